@@ -5,17 +5,24 @@ import (
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
+	"time"
 )
 
-type Config struct {
+const (
+	// statuses and notifications older than this are removed from the local cache.
+	DefaultCacheRetentionDays = 7
+)
+
+// AccountConfig is the login details for a single Mastodon account.
+type AccountConfig struct {
+	// Name is the full account name (eg. user@instance). Filled in after logging in.
+	Name string `json:"name"`
+
 	InstanceURL  string `json:"instanceURL"`
 	ClientID     string `json:"appID"`
 	ClientSecret string `json:"appSecret"`
 	Token        string `json:"token"`
 
-	// DarkMode or LightMode... only 2 options for now.
-	DarkMode bool `json:"darkMode"`
-
 	// We get the list of lists from the server, but we don't want to display all of them.
 	// Should probably be a map, but leave as slice for now
 	ListsToNotDisplay []string `json:"listsToNotDisplay"`
@@ -26,6 +33,39 @@ type Config struct {
 	Password string `json:"password"`
 }
 
+type Config struct {
+	// All accounts we're logged in with. Each gets its own set of columns.
+	Accounts []*AccountConfig `json:"accounts"`
+
+	// DarkMode or LightMode... only 2 options for now.
+	DarkMode bool `json:"darkMode"`
+
+	// Show statuses with content warnings expanded instead of hidden behind "show more".
+	ExpandContentWarnings bool `json:"expandContentWarnings"`
+
+	// Number of days statuses and notifications are kept in the local cache. 0 uses DefaultCacheRetentionDays.
+	CacheRetentionDays int `json:"cacheRetentionDays"`
+
+	// Single account details from before multiple accounts were supported.
+	// Only read so they can be migrated into Accounts.
+	InstanceURL       string   `json:"instanceURL,omitempty"`
+	ClientID          string   `json:"appID,omitempty"`
+	ClientSecret      string   `json:"appSecret,omitempty"`
+	Token             string   `json:"token,omitempty"`
+	ListsToNotDisplay []string `json:"listsToNotDisplay,omitempty"`
+	Username          string   `json:"username,omitempty"`
+	Password          string   `json:"password,omitempty"`
+}
+
+// CacheRetention is how long statuses and notifications are kept in the local cache.
+func (c *Config) CacheRetention() time.Duration {
+	days := c.CacheRetentionDays
+	if days <= 0 {
+		days = DefaultCacheRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func LoadConfig() *Config {
 
 	homeDir, err := os.UserHomeDir()
@@ -40,14 +80,8 @@ func LoadConfig() *Config {
 
 		// make a dummy...
 		c := Config{
-			InstanceURL:       "",
-			ClientID:          "",
-			ClientSecret:      "",
-			Token:             "",
-			DarkMode:          false,
-			ListsToNotDisplay: []string{},
-			Username:          "",
-			Password:          "",
+			Accounts: []*AccountConfig{},
+			DarkMode: false,
 		}
 		c.Save()
 		return &c
@@ -58,9 +92,67 @@ func LoadConfig() *Config {
 	if err != nil {
 		panic("unable to unmarshal config file")
 	}
+
+	if c.migrateSingleAccount() {
+		c.Save()
+	}
 	return &c
 }
 
+// migrateSingleAccount moves the pre multi-account login details into Accounts.
+// Returns true if anything was migrated.
+func (c *Config) migrateSingleAccount() bool {
+	if c.InstanceURL == "" {
+		return false
+	}
+
+	c.Accounts = append(c.Accounts, &AccountConfig{
+		InstanceURL:       c.InstanceURL,
+		ClientID:          c.ClientID,
+		ClientSecret:      c.ClientSecret,
+		Token:             c.Token,
+		ListsToNotDisplay: c.ListsToNotDisplay,
+		Username:          c.Username,
+		Password:          c.Password,
+	})
+
+	c.InstanceURL = ""
+	c.ClientID = ""
+	c.ClientSecret = ""
+	c.Token = ""
+	c.ListsToNotDisplay = nil
+	c.Username = ""
+	c.Password = ""
+	return true
+}
+
+// AddAccount adds a new (not yet logged in) account.
+func (c *Config) AddAccount() *AccountConfig {
+	acct := &AccountConfig{ListsToNotDisplay: []string{}}
+	c.Accounts = append(c.Accounts, acct)
+	return acct
+}
+
+// RemoveAccount removes the account with the given name.
+func (c *Config) RemoveAccount(name string) {
+	for i, a := range c.Accounts {
+		if a.Name == name {
+			c.Accounts = append(c.Accounts[:i], c.Accounts[i+1:]...)
+			return
+		}
+	}
+}
+
+// AccountByName returns the account with the given name, or nil if we don't have it.
+func (c *Config) AccountByName(name string) *AccountConfig {
+	for _, a := range c.Accounts {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
 func (c *Config) Save() error {
 	data, err := json.Marshal(c)
 	if err != nil {
@@ -9,6 +9,7 @@ const (
 	SEND_TOOT EventType = iota
 	REPLY
 	REFRESH_MESSAGES
+	TIMELINE_UPDATED
 
 	TIMELINE_REFRESH RefreshType = iota
 	USER_REFRESH
@@ -17,6 +18,13 @@ const (
 	THREAD_REFRESH
 	LIST_REFRESH
 	HOME_REFRESH
+	BOOKMARKS_REFRESH
+	FAVOURITES_REFRESH
+	CONVERSATIONS_REFRESH
+	LOCAL_REFRESH
+	FEDERATED_REFRESH
+	EXPLORE_REFRESH
+	FOLLOW_REQUESTS_REFRESH
 )
 
 // Event means something's happened in the UI that needs to go back to the main app for
@@ -47,6 +55,9 @@ func NewTootEvent(message string) TootEvent {
 type RefreshEvent struct {
 	EventBase
 
+	// Account (name) the timeline belongs to. Each account's backend only handles its own events.
+	Account string
+
 	// Refresh user, home, hashtag, notification etc.
 	RefreshType RefreshType
 
@@ -65,23 +76,36 @@ type RefreshEvent struct {
 }
 
 // Create RefreshEvent for a specific timeline and with optional SinceID/MaxIDs
-func NewRefreshEvent(timelineID string, clearExisting bool, refreshType RefreshType) RefreshEvent {
-	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, TimelineID: timelineID, RefreshType: refreshType, ClearExisting: clearExisting}
+func NewRefreshEvent(account string, timelineID string, clearExisting bool, refreshType RefreshType) RefreshEvent {
+	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, Account: account, TimelineID: timelineID, RefreshType: refreshType, ClearExisting: clearExisting}
+	return te
+}
+
+func NewRefreshAllEvent(account string, clearExisting bool, refreshType RefreshType) RefreshEvent {
+	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, Account: account, TimelineID: "home", RefreshType: refreshType, ClearExisting: clearExisting}
 	return te
 }
 
-func NewRefreshAllEvent(clearExisting bool, refreshType RefreshType) RefreshEvent {
-	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, TimelineID: "home", RefreshType: refreshType, ClearExisting: clearExisting}
+func NewHomeRefreshEvent(account string, clearExisting bool, refreshType RefreshType) RefreshEvent {
+	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, Account: account, TimelineID: "home", RefreshType: refreshType, ClearExisting: clearExisting}
 	return te
 }
 
-func NewHomeRefreshEvent(clearExisting bool, refreshType RefreshType) RefreshEvent {
-	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, TimelineID: "home", RefreshType: refreshType, ClearExisting: clearExisting}
+func NewGetOlderRefreshEvents(account string, timelineID string, refreshType RefreshType) RefreshEvent {
+	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, Account: account, TimelineID: timelineID, RefreshType: refreshType, ClearExisting: false, GetOlder: true}
 	return te
 }
 
-func NewGetOlderRefreshEvents(timelineID string, refreshType RefreshType) RefreshEvent {
-	te := RefreshEvent{EventBase: EventBase{EType: REFRESH_MESSAGES}, TimelineID: timelineID, RefreshType: refreshType, ClearExisting: false, GetOlder: true}
+// TimelineUpdatedEvent is fired by the backend when a timeline has changed without the UI asking for it
+// (eg. a status arrived via streaming), so the UI knows to redraw.
+type TimelineUpdatedEvent struct {
+	EventBase
+	Account    string
+	TimelineID string
+}
+
+func NewTimelineUpdatedEvent(account string, timelineID string) TimelineUpdatedEvent {
+	te := TimelineUpdatedEvent{EventBase: EventBase{EType: TIMELINE_UPDATED}, Account: account, TimelineID: timelineID}
 	return te
 }
 
@@ -38,6 +38,8 @@ func (el *EventListener) Listen() error {
 				el.SendEventToReceivers(ev)
 			case RefreshEvent:
 				el.SendEventToReceivers(ev)
+			case TimelineUpdatedEvent:
+				el.SendEventToReceivers(ev)
 			default:
 				log.Errorf("UNKNOWN EVENT")
 			}
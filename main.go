@@ -20,8 +20,7 @@ import (
 )
 
 var (
-	AccountID int64
-	Version   = "0.1.3"
+	Version = "0.1.3"
 )
 
 func setupLogging(debug bool) {
@@ -66,6 +65,7 @@ func main() {
 	debug := flag.Bool("debug", false, "enable debug mode")
 	enablePprof := flag.Bool("pprof", false, "enable pprof. listen on port 6060")
 	enableMemStats := flag.Bool("mem", false, "print memory stats on stdout every minute")
+	fixture := flag.String("fixture", "", "run against fixture JSON instead of a Mastodon instance")
 	flag.Parse()
 
 	if *enablePprof {
@@ -104,9 +104,27 @@ func main() {
 	// launches a go routine for listening.
 	eventListener.Listen()
 
-	backend, err := mastodon.NewMastodonBackend(eventListener, config)
-	if err != nil {
-		log.Fatalf("could not create mastodon client: %v", err)
+	// one backend per account.
+	var backends []mastodon.Backend
+	if *fixture != "" {
+		backend, err := mastodon.NewFakeBackendFromFile(eventListener, *fixture)
+		if err != nil {
+			log.Fatalf("could not create mastodon client: %v", err)
+		}
+		backends = append(backends, backend)
+	} else {
+		// first run, will need to login.
+		if len(config.Accounts) == 0 {
+			config.AddAccount()
+		}
+
+		for _, acct := range config.Accounts {
+			backend, err := mastodon.NewMastodonBackend(eventListener, config, acct)
+			if err != nil {
+				log.Fatalf("could not create mastodon client: %v", err)
+			}
+			backends = append(backends, backend)
+		}
 	}
 
 	th := ui.GenerateDarkTheme()
@@ -114,9 +132,9 @@ func main() {
 	uinterface := ui.NewUI(
 		controller,
 		w,
-		ui.NewComposeColumn(ui.NewComponentState(controller, backend), th),
+		ui.NewComposeColumn(ui.NewComponentState(controller, backends[0]), th),
 		[]*ui.MessageColumn{},
-		backend,
+		backends,
 		eventListener,
 		config,
 	)
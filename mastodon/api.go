@@ -0,0 +1,131 @@
+package mastodon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// doAPI calls Mastodon API endpoints that the go-mastodon client doesn't support (or doesn't
+// support fully). For GET requests params are sent as the query, otherwise as a form.
+// If res is not nil, the JSON response is decoded into it.
+func (c *MastodonBackend) doAPI(ctx context.Context, method string, uri string, params url.Values, res interface{}) error {
+	u, err := c.apiURL(uri)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if method == http.MethodGet {
+		u.RawQuery = params.Encode()
+	} else if params != nil {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return c.sendAPIRequest(req, res)
+}
+
+// paginationParams adds the pagination IDs (and limit) to params.
+func paginationParams(params url.Values, pg mastodon.Pagination) url.Values {
+	if pg.MaxID != "" {
+		params.Set("max_id", string(pg.MaxID))
+	}
+	if pg.SinceID != "" {
+		params.Set("since_id", string(pg.SinceID))
+	}
+	if pg.MinID != "" {
+		params.Set("min_id", string(pg.MinID))
+	}
+	if pg.Limit > 0 {
+		params.Set("limit", fmt.Sprint(pg.Limit))
+	}
+	return params
+}
+
+// doMultipartAPI uploads a file (along with any other params) as multipart form data.
+func (c *MastodonBackend) doMultipartAPI(ctx context.Context, method string, uri string, fileName string, data []byte, params url.Values, res interface{}) error {
+	u, err := c.apiURL(uri)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	for k, values := range params {
+		for _, v := range values {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return c.sendAPIRequest(req, res)
+}
+
+func (c *MastodonBackend) apiURL(uri string) (*url.URL, error) {
+	u, err := url.Parse(c.client.Config.Server)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, uri)
+	return u, nil
+}
+
+func (c *MastodonBackend) sendAPIRequest(req *http.Request, res interface{}) error {
+	_, err := c.sendAPIRequestWithHeader(req, res)
+	return err
+}
+
+// sendAPIRequestWithHeader is sendAPIRequest, also returning the response headers (eg. for the Link header).
+func (c *MastodonBackend) sendAPIRequestWithHeader(req *http.Request, res interface{}) (http.Header, error) {
+	req.Header.Set("Authorization", "Bearer "+c.client.Config.AccessToken)
+
+	resp, err := c.client.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return nil, fmt.Errorf("bad request: %s: %s", resp.Status, apiErr.Error)
+	}
+
+	if res == nil {
+		return resp.Header, nil
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(res)
+}
@@ -0,0 +1,126 @@
+package mastodon
+
+import (
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	"time"
+)
+
+// Backend is everything the UI needs from a server. MastodonBackend talks to a real
+// Mastodon instance, FakeBackend serves canned data from fixture JSON.
+type Backend interface {
+
+	// login
+	LoginWithOAuth2() error
+	GenerateOAuthLoginURL(instanceURL string) (string, error)
+	GenerateConfigWithCode(code string) error
+
+	// account this backend is logged in with.
+	AccountID() mastodon.ID
+	AccountName() string
+
+	// timeline reads
+	GetTimeline(timelineID string) ([]mastodon.Status, error)
+	GetNotifications(timelineID string) ([]*mastodon.Notification, error)
+	GetConversations() ([]*mastodon.Conversation, error)
+	GetTrends() (Trends, error)
+
+	// timelines can have gap markers (see IsGapID) where statuses haven't been fetched yet.
+	LoadGap(refreshType events.RefreshType, timelineID string, gapID mastodon.ID) error
+
+	// posting and interacting with statuses
+	Post(toot *mastodon.Toot) error
+	SetFavourite(id mastodon.ID, fav bool) error
+	SetBookmark(id mastodon.ID, bookmark bool) error
+	Boost(id mastodon.ID, boost bool) error
+	MarkConversationRead(id mastodon.ID) error
+	ClearNotifications() error
+
+	// offline outbox. Posts, favourites and boosts made while the instance is unreachable are
+	// queued, then replayed in order once it's back.
+	IsOnline() bool
+	GetOutbox() []OutboxItem
+	DiscardOutboxItem(id int64) error
+
+	// managing our own statuses.
+	GetStatusSource(id mastodon.ID) (*mastodon.Source, error)
+	EditStatus(id mastodon.ID, toot *mastodon.Toot) error
+	DeleteStatus(id mastodon.ID) error
+
+	// media attachments. Uploaded first, then referenced by MediaIDs when posting.
+	UploadMedia(fileName string, data []byte) (*mastodon.Attachment, error)
+	GetMedia(id mastodon.ID) (*mastodon.Attachment, error)
+	UpdateMedia(id mastodon.ID, description string, focusX float64, focusY float64) error
+
+	// polls. statusID is the status the poll is attached to (or a boost of it).
+	PollLimits() PollLimits
+	VotePoll(statusID mastodon.ID, choices []int) error
+	RefreshPoll(statusID mastodon.ID) error
+
+	// follows. GetUserDetails is the user being viewed in a user column, not the logged in user.
+	GetUserDetails() (*mastodon.Account, *mastodon.Relationship)
+	RefreshUserRelationship() error
+	ChangeFollowStatusForUserID(userID mastodon.ID, follow bool) error
+
+	// follow requests, for locked accounts.
+	GetFollowRequests() ([]*mastodon.Account, error)
+	AuthorizeFollowRequest(id mastodon.ID) error
+	RejectFollowRequest(id mastodon.ID) error
+
+	// mutes and blocks. Muting or blocking purges the account's (or domain's) statuses from the cache.
+	// A mute duration of 0 is indefinite.
+	MuteAccount(id mastodon.ID, notifications bool, duration time.Duration) error
+	UnmuteAccount(id mastodon.ID) error
+	BlockAccount(id mastodon.ID) error
+	UnblockAccount(id mastodon.ID) error
+	BlockDomain(domain string) error
+	UnblockDomain(domain string) error
+	GetMutes() ([]*mastodon.Account, error)
+	GetBlocks() ([]*mastodon.Account, error)
+	GetDomainBlocks() ([]string, error)
+
+	// reporting accounts (and their statuses) to moderators.
+	GetInstanceRules() ([]Rule, error)
+	GetAccountStatuses(accountID mastodon.ID) ([]mastodon.Status, error)
+	Report(report Report) error
+
+	// search
+	Search(query string) (*mastodon.Results, error)
+	ClearSearch() error
+
+	// lists
+	GetLists() ([]*List, error)
+
+	// v2 filters. Statuses matching hide filters are already left out of timelines.
+	GetFilters() []Filter
+	FilterWarnings(status mastodon.Status, context string) []string
+	SaveFilter(filter Filter, removedKeywords []mastodon.ID, expiresIn time.Duration) error
+	DeleteFilter(id mastodon.ID) error
+
+	// list management
+	CreateList(list List) (*List, error)
+	UpdateList(list List) (*List, error)
+	DeleteList(id mastodon.ID) error
+	GetListAccounts(id mastodon.ID) ([]*mastodon.Account, error)
+	GetAccountLists(accountID mastodon.ID) ([]*List, error)
+	AddToList(listID mastodon.ID, accountID mastodon.ID) error
+	RemoveFromList(listID mastodon.ID, accountID mastodon.ID) error
+
+	// read markers (MarkerHome, MarkerNotifications) shared with other clients.
+	GetMarker(timeline string) (Marker, bool)
+	SetMarker(timeline string, lastReadID mastodon.ID) error
+
+	// what's left of the instance's rate limit, so background refreshes can back off.
+	RateLimit() RateLimitBudget
+
+	// streaming. Timelines that are not streaming need to be polled.
+	StartStream(refreshType events.RefreshType, timelineID string)
+	StopStream(timelineID string)
+	IsStreaming(timelineID string) bool
+}
+
+// make sure both backends keep up with the interface.
+var (
+	_ Backend = (*MastodonBackend)(nil)
+	_ Backend = (*FakeBackend)(nil)
+)
@@ -0,0 +1,120 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kpfaulkner/shipdon/config"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+)
+
+func TestMain(m *testing.M) {
+	// nothing listens for events in tests, so stop FireEvent from blocking.
+	go func() {
+		for range events.EventChannel {
+		}
+	}()
+	os.Exit(m.Run())
+}
+
+// newTestBackend is a MastodonBackend talking to handler instead of an instance.
+func newTestBackend(t *testing.T, handler http.Handler) *MastodonBackend {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := NewMastodonBackend(events.NewEventLister(make(chan events.Event)), &config.Config{}, &config.AccountConfig{Name: "test@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.client = c.newClient(&mastodon.Config{Server: server.URL})
+	return c
+}
+
+// testStatus is a status with a numeric ID, so IDs sort the same way as on an instance.
+func testStatus(id int) mastodon.Status {
+	return mastodon.Status{
+		ID:        mastodon.ID(strconv.Itoa(id)),
+		Content:   fmt.Sprintf("<p>status %d</p>", id),
+		CreatedAt: time.Now().Add(time.Duration(id-1000) * time.Minute),
+	}
+}
+
+// statusRange is the statuses with IDs from to to, newest first.
+func statusRange(from int, to int) []mastodon.Status {
+	var statuses []mastodon.Status
+	for id := to; id >= from; id-- {
+		statuses = append(statuses, testStatus(id))
+	}
+	return statuses
+}
+
+func ids(statuses []mastodon.Status) []mastodon.ID {
+	var ids []mastodon.ID
+	for _, s := range statuses {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func idRange(from int, to int) []mastodon.ID {
+	return ids(statusRange(from, to))
+}
+
+// testInstance serves a home timeline paged with max_id/min_id/since_id, like an instance does.
+type testInstance struct {
+	lock sync.Mutex
+
+	// newest first.
+	home []mastodon.Status
+
+	// paths of the requests made, with their queries.
+	requests []string
+}
+
+func (ti *testInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	ti.requests = append(ti.requests, r.URL.RequestURI())
+	if r.URL.Path != "/api/v1/timelines/home" {
+		http.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil {
+		limit = MastodonLimit
+	}
+	maxID, minID, sinceID := mastodon.ID(q.Get("max_id")), mastodon.ID(q.Get("min_id")), mastodon.ID(q.Get("since_id"))
+
+	var page []mastodon.Status
+	for _, s := range ti.home {
+		if (maxID == "" || IDNewer(maxID, s.ID)) && (minID == "" || IDNewer(s.ID, minID)) && (sinceID == "" || IDNewer(s.ID, sinceID)) {
+			page = append(page, s)
+		}
+	}
+
+	// min_id gets the statuses just after it, everything else the newest.
+	if minID != "" && len(page) > limit {
+		page = page[len(page)-limit:]
+	}
+	page = page[:min(len(page), limit)]
+	json.NewEncoder(w).Encode(page)
+}
+
+func (ti *testInstance) requestCount() int {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+	return len(ti.requests)
+}
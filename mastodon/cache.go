@@ -25,6 +25,17 @@ type TimelineCache struct {
 	// holds ALL  status details (all timelines)
 	messageCache map[mastodon.ID]mastodon.Status
 
+	// statuses matching hide filters are left out of timelines. Each timeline's filter context
+	// is set when it's refreshed.
+	filters          []Filter
+	timelineContexts map[string]string
+
+	// filter results from the server, keyed by status ID. Statuses without any are matched locally.
+	filterResults map[mastodon.ID][]FilterResult
+
+	// changes are written through to disk. nil if the cache is only in memory.
+	store *Store
+
 	lock sync.RWMutex
 }
 
@@ -32,6 +43,33 @@ func NewTimelineCache() *TimelineCache {
 	return &TimelineCache{
 		timelineMessageCache: make(map[string]TimelineDetails),
 		messageCache:         make(map[mastodon.ID]mastodon.Status),
+		timelineContexts:     make(map[string]string),
+		filterResults:        make(map[mastodon.ID][]FilterResult),
+	}
+}
+
+// SetStore persists all changes to the cache from now on.
+func (tc *TimelineCache) SetStore(store *Store) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	tc.store = store
+}
+
+// load replaces the statuses in timelines with those loaded from disk. The timelines are topped
+// up with newer statuses (via sinceID) on the next refresh.
+func (tc *TimelineCache) load(statuses map[mastodon.ID]mastodon.Status, timelines map[string][]mastodon.ID) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	for id, s := range statuses {
+		tc.messageCache[id] = s
+	}
+	for timeline, messages := range timelines {
+		tc.timelineMessageCache[timeline] = TimelineDetails{
+			name:     timeline,
+			sinceID:  newestID(messages),
+			messages: messages,
+		}
 	}
 }
 
@@ -55,12 +93,15 @@ func (tc *TimelineCache) ClearTimeline(timeline string) error {
 	if details, ok := tc.timelineMessageCache[timeline]; ok {
 		details.messages = []mastodon.ID{}
 		tc.timelineMessageCache[timeline] = details
+		tc.store.QueueTimeline(timeline, details.messages)
 	}
 	tc.lock.Unlock()
 	return nil
 }
 
-func (tc *TimelineCache) AddToTimeline(timeline string, clearExisting bool, messages []mastodon.Status, shouldSort bool) error {
+// AddToTimeline adds the statuses to the timeline. Returns false if they weren't added because the
+// timeline was refreshed in the last 10 seconds.
+func (tc *TimelineCache) AddToTimeline(timeline string, clearExisting bool, messages []mastodon.Status, shouldSort bool) (bool, error) {
 	var details TimelineDetails
 	var ok bool
 
@@ -70,7 +111,7 @@ func (tc *TimelineCache) AddToTimeline(timeline string, clearExisting bool, mess
 		// timeline was recently refreshed (last 10 seconds)...  leave it.
 		if time.Now().Before(details.lastRefreshed.Add(time.Second * 10)) {
 			tc.lock.RUnlock()
-			return nil
+			return false, nil
 		}
 	}
 	tc.lock.RUnlock()
@@ -82,18 +123,20 @@ func (tc *TimelineCache) AddToTimeline(timeline string, clearExisting bool, mess
 
 	tc.lock.Lock()
 	for _, i := range messages {
-		details.messages = append(details.messages, i.ID)
+		if !slices.Contains(details.messages, i.ID) {
+			details.messages = append(details.messages, i.ID)
+		}
 		tc.messageCache[i.ID] = i
 	}
+	tc.store.QueueStatuses(messages)
 	tc.lock.Unlock()
 
 	if shouldSort {
-		slices.Sort(details.messages)
-		slices.Reverse(details.messages)
+		sortTimeline(details.messages)
 	}
 
 	if len(details.messages) > 0 {
-		details.sinceID = details.messages[0]
+		details.sinceID = newestID(details.messages)
 	} else {
 		details.sinceID = "0" // TODO(kpfaulkner) confirm if this is ok.
 	}
@@ -101,8 +144,9 @@ func (tc *TimelineCache) AddToTimeline(timeline string, clearExisting bool, mess
 
 	tc.lock.Lock()
 	tc.timelineMessageCache[timeline] = details
+	tc.store.QueueTimeline(timeline, details.messages)
 	tc.lock.Unlock()
-	return nil
+	return true, nil
 }
 
 func (tc *TimelineCache) AddToMessageCache(messages []mastodon.Status) error {
@@ -110,6 +154,7 @@ func (tc *TimelineCache) AddToMessageCache(messages []mastodon.Status) error {
 	for _, i := range messages {
 		tc.messageCache[i.ID] = i
 	}
+	tc.store.QueueStatuses(messages)
 	tc.lock.Unlock()
 	return nil
 }
@@ -131,14 +176,69 @@ func (tc *TimelineCache) GetAllStatusForTimeline(timelineID string) []mastodon.S
 		return nil
 	}
 
+	context, hasContext := tc.timelineContexts[timelineID]
+
 	var statuses []mastodon.Status
 	for _, id := range td.messages {
-		statuses = append(statuses, tc.messageCache[id])
+
+		// gap markers are passed on as a status with just the ID, so they can be displayed.
+		if IsGapID(id) {
+			statuses = append(statuses, mastodon.Status{ID: id})
+			continue
+		}
+
+		status := tc.messageCache[id]
+		if hasContext && FilterHides(tc.filters, tc.filterResults, status, context) {
+			continue
+		}
+		statuses = append(statuses, status)
 	}
 	return statuses
 
 }
 
+// SetFilters sets the filters used to hide statuses. The server's filter results may be out of date
+// once the filters change, so statuses are matched locally until they're fetched again.
+func (tc *TimelineCache) SetFilters(filters []Filter) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	tc.filters = filters
+	clear(tc.filterResults)
+}
+
+// AddFilterResults keeps the server's filter results for the statuses (and any statuses they boost).
+func (tc *TimelineCache) AddFilterResults(statuses ...*filteredStatus) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	for _, fs := range statuses {
+		for ; fs != nil; fs = fs.Reblog {
+			// an empty (rather than nil) slice records that nothing matched.
+			tc.filterResults[fs.ID] = append([]FilterResult{}, fs.Filtered...)
+		}
+	}
+}
+
+// FilterWarnings returns the titles of the warn filters that match the status.
+func (tc *TimelineCache) FilterWarnings(status mastodon.Status, context string) []string {
+	tc.lock.RLock()
+	defer tc.lock.RUnlock()
+	return FilterWarnings(tc.filters, tc.filterResults, status, context)
+}
+
+// SetTimelineContext sets which filter context (eg. FilterContextHome) applies to a timeline.
+func (tc *TimelineCache) SetTimelineContext(timeline string, context string) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	tc.timelineContexts[timeline] = context
+}
+
+// IsHidden returns true if a hide filter matches the status in the given context.
+func (tc *TimelineCache) IsHidden(status mastodon.Status, context string) bool {
+	tc.lock.RLock()
+	defer tc.lock.RUnlock()
+	return FilterHides(tc.filters, tc.filterResults, status, context)
+}
+
 func (tc *TimelineCache) LogCacheDetails() {
 
 	for {
@@ -154,4 +254,154 @@ func (tc *TimelineCache) UpdateTimeline(timelineID string, sinceID mastodon.ID,
 	td.sinceID = sinceID
 	td.messages = messages
 	tc.timelineMessageCache[timelineID] = td
+	tc.store.QueueTimeline(timelineID, messages)
+}
+
+// InsertIntoTimeline adds statuses that have arrived outside of a regular refresh (eg. via streaming)
+// to a timeline. Unlike AddToTimeline this ignores the recently refreshed check and will not add
+// duplicate IDs to the timeline.
+func (tc *TimelineCache) InsertIntoTimeline(timeline string, messages []mastodon.Status) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	details := tc.timelineMessageCache[timeline]
+	for _, i := range messages {
+		tc.messageCache[i.ID] = i
+		if !slices.Contains(details.messages, i.ID) {
+			details.messages = append(details.messages, i.ID)
+		}
+	}
+
+	sortTimeline(details.messages)
+
+	if len(details.messages) > 0 {
+		details.sinceID = newestID(details.messages)
+	}
+	tc.timelineMessageCache[timeline] = details
+	tc.store.QueueStatuses(messages)
+	tc.store.QueueTimeline(timeline, details.messages)
+	return nil
+}
+
+// MergeIntoTimeline puts the newest page of a timeline that isn't ordered by status ID (eg. bookmarks)
+// at the top, in the order given, keeping the older statuses we already have underneath.
+func (tc *TimelineCache) MergeIntoTimeline(timeline string, messages []mastodon.Status) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	details := tc.timelineMessageCache[timeline]
+	merged := []mastodon.ID{}
+	for _, i := range messages {
+		tc.messageCache[i.ID] = i
+		merged = append(merged, i.ID)
+	}
+	for _, id := range details.messages {
+		if !slices.Contains(merged, id) {
+			merged = append(merged, id)
+		}
+	}
+
+	// lastRefreshed is left alone, so older pages can still be added straight after a poll.
+	details.messages = merged
+	tc.timelineMessageCache[timeline] = details
+	tc.store.QueueStatuses(messages)
+	tc.store.QueueTimeline(timeline, details.messages)
+	return nil
+}
+
+// UpdateStatus replaces the cached copy of a status (eg. when it has been edited).
+// Boosts of the status are updated as well. Statuses we've never seen are ignored.
+func (tc *TimelineCache) UpdateStatus(status mastodon.Status) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	var updated []mastodon.Status
+	edited := false
+	if old, ok := tc.messageCache[status.ID]; ok {
+		edited = statusText(old) != statusText(status)
+		tc.messageCache[status.ID] = status
+		updated = append(updated, status)
+	}
+
+	for id, s := range tc.messageCache {
+		if s.Reblog != nil && s.Reblog.ID == status.ID {
+			edited = edited || statusText(*s.Reblog) != statusText(status)
+			reblogged := status
+			s.Reblog = &reblogged
+			tc.messageCache[id] = s
+			updated = append(updated, s)
+		}
+	}
+
+	// an edit may change which filters match, so match it locally until the server's results are
+	// fetched again. Favourites, boosts etc. leave the server's results alone.
+	if edited {
+		delete(tc.filterResults, status.ID)
+	}
+	tc.store.QueueStatuses(updated)
+	return nil
+}
+
+// DeleteStatus removes a status (and any boosts of it) from the message cache and every timeline.
+func (tc *TimelineCache) DeleteStatus(id mastodon.ID) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	toDelete := []mastodon.ID{id}
+	for k, s := range tc.messageCache {
+		if s.Reblog != nil && s.Reblog.ID == id {
+			toDelete = append(toDelete, k)
+		}
+	}
+
+	for _, k := range toDelete {
+		delete(tc.messageCache, k)
+		delete(tc.filterResults, k)
+	}
+
+	for timeline, details := range tc.timelineMessageCache {
+		details.messages = slices.DeleteFunc(details.messages, func(m mastodon.ID) bool {
+			return slices.Contains(toDelete, m)
+		})
+		tc.timelineMessageCache[timeline] = details
+	}
+	tc.store.QueueDeleteStatuses(toDelete)
+	return nil
+}
+
+// PurgeStatuses removes every status that matches (eg. from a blocked account) from the message
+// cache and every timeline. Returns the IDs of the statuses removed.
+func (tc *TimelineCache) PurgeStatuses(match func(mastodon.Status) bool) []mastodon.ID {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	var toDelete []mastodon.ID
+	for k, s := range tc.messageCache {
+		if match(s) {
+			toDelete = append(toDelete, k)
+		}
+	}
+
+	for _, k := range toDelete {
+		delete(tc.messageCache, k)
+		delete(tc.filterResults, k)
+	}
+
+	for timeline, details := range tc.timelineMessageCache {
+		details.messages = slices.DeleteFunc(details.messages, func(m mastodon.ID) bool {
+			return slices.Contains(toDelete, m)
+		})
+		tc.timelineMessageCache[timeline] = details
+	}
+	tc.store.QueueDeleteStatuses(toDelete)
+	return toDelete
+}
+
+// GetStatus returns the cached copy of a status.
+func (tc *TimelineCache) GetStatus(id mastodon.ID) (mastodon.Status, bool) {
+	tc.lock.RLock()
+	defer tc.lock.RUnlock()
+
+	s, ok := tc.messageCache[id]
+	return s, ok
 }
@@ -0,0 +1,117 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Conversation is a direct message conversation. Like notifications, the last status is kept in the
+// message cache so it stays up to date (favourites etc).
+type Conversation struct {
+	ID           mastodon.ID
+	Accounts     []*mastodon.Account
+	Unread       bool
+	LastStatusID mastodon.ID
+
+	// when the last status was posted, used for ordering.
+	LastUpdated time.Time
+}
+
+// GetConversations returns the direct message conversations, most recently active first.
+func (c *MastodonBackend) GetConversations() ([]*mastodon.Conversation, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	conversations := []*mastodon.Conversation{}
+	for _, conv := range c.conversations {
+		conversation := &mastodon.Conversation{
+			ID:       conv.ID,
+			Accounts: conv.Accounts,
+			Unread:   conv.Unread,
+		}
+		if conv.LastStatusID != "" {
+			if status, ok := c.timelineMessageCache.GetStatus(conv.LastStatusID); ok {
+				conversation.LastStatus = &status
+			}
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// MarkConversationRead marks the conversation as read on the server and locally.
+func (c *MastodonBackend) MarkConversationRead(id mastodon.ID) error {
+	err := c.client.MarkConversationAsRead(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to mark conversation %s as read : err %s", id, err)
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := range c.conversations {
+		if c.conversations[i].ID == id {
+			c.conversations[i].Unread = false
+		}
+	}
+	return nil
+}
+
+// refreshConversations gets the latest conversations, or the next page of older ones.
+// Conversations are paged with the IDs from the Link header, like bookmarks.
+func (c *MastodonBackend) refreshConversations(timelineID string, getOlder bool, clearExisting bool) error {
+	params := mastodon.Pagination{Limit: MastodonLimit}
+
+	if getOlder {
+		c.lock.RLock()
+		maxID := c.nextPage[timelineID]
+		c.lock.RUnlock()
+		if maxID == "" {
+			return nil
+		}
+		params.MaxID = maxID
+	}
+
+	conversations, err := c.client.GetConversations(c.ctx, &params)
+	if err != nil {
+		log.Errorf("unable to get conversations : err %s", err)
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// only the latest page knows where the next page starts.
+	if getOlder || clearExisting || len(c.conversations) == 0 {
+		c.nextPage[timelineID] = params.MaxID
+	}
+
+	if clearExisting {
+		c.conversations = []Conversation{}
+	}
+
+	for _, conv := range conversations {
+		conversation := Conversation{
+			ID:       conv.ID,
+			Accounts: conv.Accounts,
+			Unread:   conv.Unread,
+		}
+		if conv.LastStatus != nil {
+			conversation.LastStatusID = conv.LastStatus.ID
+			conversation.LastUpdated = conv.LastStatus.CreatedAt
+			c.timelineMessageCache.AddToMessageCache([]mastodon.Status{*conv.LastStatus})
+		}
+
+		// conversations we already have get replaced, they may have a newer status.
+		c.conversations = slices.DeleteFunc(c.conversations, func(existing Conversation) bool { return existing.ID == conv.ID })
+		c.conversations = append(c.conversations, conversation)
+	}
+
+	sort.SliceStable(c.conversations, func(i, j int) bool {
+		return c.conversations[i].LastUpdated.After(c.conversations[j].LastUpdated)
+	})
+	return nil
+}
@@ -0,0 +1,840 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture is the JSON document used to populate a FakeBackend.
+type Fixture struct {
+	// Account is the logged in account.
+	Account mastodon.Account `json:"account"`
+
+	// Timelines is keyed by timelineID (eg. "home", list ID or hashtag)
+	Timelines map[string][]mastodon.Status `json:"timelines"`
+
+	Notifications []*mastodon.Notification `json:"notifications"`
+	Lists         []*List                  `json:"lists"`
+	Conversations []*mastodon.Conversation `json:"conversations"`
+
+	// FollowRequests are the accounts waiting for us to accept them.
+	FollowRequests []*mastodon.Account `json:"followRequests"`
+
+	// TrendingTags and TrendingLinks are for the explore column. Trending statuses are the "explore" timeline.
+	TrendingTags  []*mastodon.Tag `json:"trendingTags"`
+	TrendingLinks []*TrendingLink `json:"trendingLinks"`
+
+	Filters []Filter `json:"filters"`
+
+	// Markers are the read markers, keyed by MarkerHome/MarkerNotifications.
+	Markers map[string]Marker `json:"markers"`
+
+	// Rules are the instance rules offered when reporting.
+	Rules []Rule `json:"rules"`
+
+	// Outbox is shown as waiting to be sent. The fake backend is always online, so it never is.
+	Outbox []OutboxItem `json:"outbox"`
+
+	// Users is keyed by account ID and is used when a user column is opened.
+	Users map[string]FixtureUser `json:"users"`
+}
+
+// FixtureUser is a user (and their statuses) that can be viewed in a user column.
+type FixtureUser struct {
+	Account      mastodon.Account      `json:"account"`
+	Relationship mastodon.Relationship `json:"relationship"`
+	Statuses     []mastodon.Status     `json:"statuses"`
+}
+
+// FakeBackend is an in-memory Backend driven by fixture JSON. Nothing leaves the process,
+// actions such as posting or favouriting just update the in-memory state.
+type FakeBackend struct {
+	fixture Fixture
+
+	timelineMessageCache *TimelineCache
+	notifications        []*mastodon.Notification
+
+	userInfo         *mastodon.Account
+	userRelationship *mastodon.Relationship
+
+	// Posted records every message sent via Post, in order.
+	Posted []mastodon.Toot
+
+	// Reported records every report sent, in order.
+	Reported []Report
+
+	// uploaded media keyed by ID.
+	media map[mastodon.ID]*mastodon.Attachment
+
+	// accounts in each list, keyed by list ID. Lists start out empty.
+	listMembers map[mastodon.ID][]*mastodon.Account
+
+	followRequests []*mastodon.Account
+	outbox         []OutboxItem
+
+	// muted and blocked accounts and domains. All start out empty.
+	mutes        []*mastodon.Account
+	blocks       []*mastodon.Account
+	domainBlocks []string
+
+	lock sync.RWMutex
+}
+
+// NewFakeBackendFromFile creates a FakeBackend populated from the fixture file at path.
+func NewFakeBackendFromFile(eventListener *events.EventListener, path string) (*FakeBackend, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFakeBackend(eventListener, data)
+}
+
+// NewFakeBackend creates a FakeBackend populated from fixture JSON.
+// eventListener can be nil if refresh events are not required.
+func NewFakeBackend(eventListener *events.EventListener, fixtureJSON []byte) (*FakeBackend, error) {
+	f := &FakeBackend{
+		timelineMessageCache: NewTimelineCache(),
+		media:                make(map[mastodon.ID]*mastodon.Attachment),
+		listMembers:          make(map[mastodon.ID][]*mastodon.Account),
+	}
+
+	if err := json.Unmarshal(fixtureJSON, &f.fixture); err != nil {
+		return nil, fmt.Errorf("unable to parse fixture : %w", err)
+	}
+
+	for timelineID, statuses := range f.fixture.Timelines {
+		f.timelineMessageCache.InsertIntoTimeline(timelineID, statuses)
+	}
+	f.notifications = slices.Clone(f.fixture.Notifications)
+	f.followRequests = slices.Clone(f.fixture.FollowRequests)
+	f.outbox = slices.Clone(f.fixture.Outbox)
+	f.timelineMessageCache.SetFilters(f.fixture.Filters)
+
+	// last statuses need to be in the cache so their threads can be opened.
+	for _, conv := range f.fixture.Conversations {
+		if conv.LastStatus != nil {
+			f.timelineMessageCache.AddToMessageCache([]mastodon.Status{*conv.LastStatus})
+		}
+	}
+
+	if eventListener != nil {
+		eventListener.RegisterReceiver(events.REFRESH_MESSAGES, f.RefreshMessagesCallback)
+	}
+	return f, nil
+}
+
+// RefreshMessagesCallback only needs to handle user and thread columns, everything else was loaded up front.
+func (f *FakeBackend) RefreshMessagesCallback(e events.Event) error {
+	re := e.(events.RefreshEvent)
+
+	if re.Account != f.AccountName() {
+		return nil
+	}
+
+	f.timelineMessageCache.SetTimelineContext(re.TimelineID, FilterContextForRefreshType(re.RefreshType))
+
+	switch re.RefreshType {
+	case events.USER_REFRESH:
+	case events.THREAD_REFRESH:
+		return f.refreshThread(mastodon.ID(re.TimelineID))
+	default:
+		return nil
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	user, ok := f.fixture.Users[re.TimelineID]
+	if !ok {
+		return fmt.Errorf("no fixture for user %s", re.TimelineID)
+	}
+	account := user.Account
+	relationship := user.Relationship
+	f.userInfo = &account
+	f.userRelationship = &relationship
+	f.timelineMessageCache.InsertIntoTimeline(re.TimelineID, user.Statuses)
+	return nil
+}
+
+// refreshThread builds the thread from every status in the fixture.
+func (f *FakeBackend) refreshThread(statusID mastodon.ID) error {
+	status, ok := f.timelineMessageCache.GetStatus(statusID)
+	if !ok {
+		return fmt.Errorf("unknown status %s", statusID)
+	}
+
+	context := &mastodon.Context{}
+	parent, ok := status.InReplyToID.(string)
+	for ok {
+		s, found := f.timelineMessageCache.GetStatus(mastodon.ID(parent))
+		if !found {
+			break
+		}
+		context.Ancestors = append([]*mastodon.Status{&s}, context.Ancestors...)
+		parent, ok = s.InReplyToID.(string)
+	}
+
+	// anything replying to the status (or a reply to it) is a descendant. Oldest first so parents are seen before replies.
+	home := f.timelineMessageCache.GetAllStatusForTimeline("home")
+	slices.Reverse(home)
+	inThread := map[mastodon.ID]bool{statusID: true}
+	for _, s := range home {
+		if parent, ok := s.InReplyToID.(string); ok && inThread[mastodon.ID(parent)] {
+			reply := s
+			context.Descendants = append(context.Descendants, &reply)
+			inThread[s.ID] = true
+		}
+	}
+
+	var statuses []mastodon.Status
+	for _, s := range threadOrder(&status, context) {
+		statuses = append(statuses, *s)
+	}
+	_, err := f.timelineMessageCache.AddToTimeline(string(statusID), true, statuses, false)
+	return err
+}
+
+func (f *FakeBackend) LoginWithOAuth2() error {
+	return nil
+}
+
+func (f *FakeBackend) GenerateOAuthLoginURL(instanceURL string) (string, error) {
+	return instanceURL, nil
+}
+
+func (f *FakeBackend) GenerateConfigWithCode(code string) error {
+	return nil
+}
+
+func (f *FakeBackend) AccountID() mastodon.ID {
+	return f.fixture.Account.ID
+}
+
+func (f *FakeBackend) AccountName() string {
+	return f.fixture.Account.Acct
+}
+
+func (f *FakeBackend) GetTimeline(timelineID string) ([]mastodon.Status, error) {
+	return f.timelineMessageCache.GetAllStatusForTimeline(timelineID), nil
+}
+
+func (f *FakeBackend) GetNotifications(timelineID string) ([]*mastodon.Notification, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	nf := ParseNotificationFilter(timelineID)
+	var notifications []*mastodon.Notification
+	for _, n := range f.notifications {
+		if nf.Accepts(n.Type) {
+			notifications = append(notifications, n)
+		}
+	}
+	return notifications, nil
+}
+
+func (f *FakeBackend) ClearNotifications() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.notifications = nil
+	return nil
+}
+
+// LoadGap just removes the gap, fixture timelines are always complete.
+func (f *FakeBackend) LoadGap(refreshType events.RefreshType, timelineID string, gapID mastodon.ID) error {
+	return f.timelineMessageCache.RemoveGap(timelineID, gapID)
+}
+
+// RateLimit is unknown, the fake backend has no limit.
+func (f *FakeBackend) RateLimit() RateLimitBudget {
+	return RateLimitBudget{}
+}
+
+// IsOnline is always true, nothing is sent anywhere.
+func (f *FakeBackend) IsOnline() bool {
+	return true
+}
+
+func (f *FakeBackend) GetOutbox() []OutboxItem {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.outbox)
+}
+
+func (f *FakeBackend) DiscardOutboxItem(id int64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.outbox = slices.DeleteFunc(f.outbox, func(item OutboxItem) bool { return item.ID == id })
+	return nil
+}
+
+func (f *FakeBackend) GetConversations() ([]*mastodon.Conversation, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.fixture.Conversations, nil
+}
+
+func (f *FakeBackend) GetFilters() []Filter {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.fixture.Filters
+}
+
+// FilterWarnings returns the titles of the warn filters that match the status.
+func (f *FakeBackend) FilterWarnings(status mastodon.Status, context string) []string {
+	return f.timelineMessageCache.FilterWarnings(status, context)
+}
+
+// SaveFilter ignores removedKeywords, the whole filter is replaced.
+func (f *FakeBackend) SaveFilter(filter Filter, removedKeywords []mastodon.ID, expiresIn time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if expiresIn > 0 {
+		expiresAt := time.Now().Add(expiresIn)
+		filter.ExpiresAt = &expiresAt
+	} else if expiresIn == FilterNoExpiry {
+		filter.ExpiresAt = nil
+	}
+
+	i := slices.IndexFunc(f.fixture.Filters, func(existing Filter) bool { return existing.ID == filter.ID })
+	if filter.ID == "" || i == -1 {
+		filter.ID = mastodon.ID(fmt.Sprintf("%d", time.Now().UnixNano()))
+		f.fixture.Filters = append(f.fixture.Filters, filter)
+	} else {
+		f.fixture.Filters[i] = filter
+	}
+	f.timelineMessageCache.SetFilters(f.fixture.Filters)
+	return nil
+}
+
+func (f *FakeBackend) DeleteFilter(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fixture.Filters = slices.DeleteFunc(f.fixture.Filters, func(existing Filter) bool { return existing.ID == id })
+	f.timelineMessageCache.SetFilters(f.fixture.Filters)
+	return nil
+}
+
+func (f *FakeBackend) GetTrends() (Trends, error) {
+	return Trends{Tags: f.fixture.TrendingTags, Links: f.fixture.TrendingLinks}, nil
+}
+
+func (f *FakeBackend) MarkConversationRead(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, conv := range f.fixture.Conversations {
+		if conv.ID == id {
+			conv.Unread = false
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown conversation %s", id)
+}
+
+// Post adds the message to the home timeline as if the server had accepted it.
+func (f *FakeBackend) Post(toot *mastodon.Toot) error {
+	if toot.Poll != nil {
+		if err := ValidatePoll(toot.Poll, f.PollLimits()); err != nil {
+			return err
+		}
+	}
+
+	f.lock.Lock()
+	f.Posted = append(f.Posted, *toot)
+	var attachments []mastodon.Attachment
+	for _, id := range toot.MediaIDs {
+		if a, ok := f.media[id]; ok {
+			attachments = append(attachments, *a)
+		}
+	}
+	f.lock.Unlock()
+
+	status := mastodon.Status{
+		ID:               mastodon.ID(fmt.Sprintf("%d", time.Now().UnixNano())),
+		Account:          f.fixture.Account,
+		Content:          "<p>" + toot.Status + "</p>",
+		CreatedAt:        time.Now(),
+		MediaAttachments: attachments,
+		Sensitive:        toot.Sensitive,
+		SpoilerText:      toot.SpoilerText,
+		Visibility:       toot.Visibility,
+		Language:         toot.Language,
+		Favourited:       false,
+		Reblogged:        false,
+	}
+	if toot.InReplyToID != "" {
+		status.InReplyToID = string(toot.InReplyToID)
+	}
+	if toot.Poll != nil {
+		status.Poll = &mastodon.Poll{
+			ID:        mastodon.ID(fmt.Sprintf("poll-%s", status.ID)),
+			ExpiresAt: time.Now().Add(time.Duration(toot.Poll.ExpiresInSeconds) * time.Second),
+			Multiple:  toot.Poll.Multiple,
+		}
+		for _, option := range toot.Poll.Options {
+			status.Poll.Options = append(status.Poll.Options, mastodon.PollOption{Title: option})
+		}
+	}
+	return f.timelineMessageCache.InsertIntoTimeline("home", []mastodon.Status{status})
+}
+
+// UploadMedia pretends the upload was processed straight away. The media is not stored anywhere.
+func (f *FakeBackend) UploadMedia(fileName string, data []byte) (*mastodon.Attachment, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	id := mastodon.ID(fmt.Sprintf("media-%d", len(f.media)+1))
+	f.media[id] = &mastodon.Attachment{
+		ID:   id,
+		Type: "image",
+		URL:  "file://" + fileName,
+	}
+	a := *f.media[id]
+	return &a, nil
+}
+
+func (f *FakeBackend) GetMedia(id mastodon.ID) (*mastodon.Attachment, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	a, ok := f.media[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown media %s", id)
+	}
+	attachment := *a
+	return &attachment, nil
+}
+
+func (f *FakeBackend) UpdateMedia(id mastodon.ID, description string, focusX float64, focusY float64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	a, ok := f.media[id]
+	if !ok {
+		return fmt.Errorf("unknown media %s", id)
+	}
+	a.Description = description
+	return nil
+}
+
+func (f *FakeBackend) PollLimits() PollLimits {
+	return DefaultPollLimits
+}
+
+// GetStatusSource strips the HTML that Post wrapped the message in.
+func (f *FakeBackend) GetStatusSource(id mastodon.ID) (*mastodon.Source, error) {
+	s, ok := f.timelineMessageCache.GetStatus(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown status %s", id)
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(s.Content, "<p>"), "</p>")
+	return &mastodon.Source{ID: id, Text: text, SpoilerText: s.SpoilerText}, nil
+}
+
+func (f *FakeBackend) EditStatus(id mastodon.ID, toot *mastodon.Toot) error {
+	s, ok := f.timelineMessageCache.GetStatus(id)
+	if !ok {
+		return fmt.Errorf("unknown status %s", id)
+	}
+	s.Content = "<p>" + toot.Status + "</p>"
+	s.SpoilerText = toot.SpoilerText
+	s.Sensitive = toot.Sensitive
+	s.Language = toot.Language
+	s.EditedAt = time.Now()
+	return f.timelineMessageCache.UpdateStatus(s)
+}
+
+func (f *FakeBackend) DeleteStatus(id mastodon.ID) error {
+	return f.timelineMessageCache.DeleteStatus(id)
+}
+
+// VotePoll records the vote against the in-memory poll.
+func (f *FakeBackend) VotePoll(statusID mastodon.ID, choices []int) error {
+	s, ok := f.timelineMessageCache.GetStatus(statusID)
+	if !ok {
+		return fmt.Errorf("unknown status %s", statusID)
+	}
+
+	s, err := pollStatus(s)
+	if err != nil {
+		return err
+	}
+
+	poll := *s.Poll
+	poll.Options = slices.Clone(poll.Options)
+	for _, choice := range choices {
+		if choice < 0 || choice >= len(poll.Options) {
+			return fmt.Errorf("invalid poll choice %d", choice)
+		}
+		poll.Options[choice].VotesCount++
+		poll.VotesCount++
+	}
+	poll.VotersCount++
+	poll.Voted = true
+	poll.OwnVotes = choices
+	s.Poll = &poll
+	return f.timelineMessageCache.UpdateStatus(s)
+}
+
+// RefreshPoll does nothing, nobody else votes in fixture polls.
+func (f *FakeBackend) RefreshPoll(statusID mastodon.ID) error {
+	return nil
+}
+
+func (f *FakeBackend) SetFavourite(id mastodon.ID, fav bool) error {
+	s, ok := f.timelineMessageCache.GetStatus(id)
+	if !ok {
+		return fmt.Errorf("unknown status %s", id)
+	}
+	s.Favourited = fav
+	return f.timelineMessageCache.UpdateStatus(s)
+}
+
+// SetBookmark also adds/removes the status from the "bookmarks" timeline.
+func (f *FakeBackend) SetBookmark(id mastodon.ID, bookmark bool) error {
+	s, ok := f.timelineMessageCache.GetStatus(id)
+	if !ok {
+		return fmt.Errorf("unknown status %s", id)
+	}
+	s.Bookmarked = bookmark
+	if err := f.timelineMessageCache.UpdateStatus(s); err != nil {
+		return err
+	}
+
+	if bookmark {
+		return f.timelineMessageCache.InsertIntoTimeline("bookmarks", []mastodon.Status{s})
+	}
+
+	details, _ := f.timelineMessageCache.GetTimelineDetails("bookmarks")
+	messages := slices.DeleteFunc(slices.Clone(details.messages), func(m mastodon.ID) bool { return m == id })
+	f.timelineMessageCache.UpdateTimeline("bookmarks", details.sinceID, messages)
+	return nil
+}
+
+func (f *FakeBackend) Boost(id mastodon.ID, boost bool) error {
+	s, ok := f.timelineMessageCache.GetStatus(id)
+	if !ok {
+		return fmt.Errorf("unknown status %s", id)
+	}
+	s.Reblogged = boost
+	return f.timelineMessageCache.UpdateStatus(s)
+}
+
+func (f *FakeBackend) GetUserDetails() (*mastodon.Account, *mastodon.Relationship) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.userInfo, f.userRelationship
+}
+
+func (f *FakeBackend) RefreshUserRelationship() error {
+	return nil
+}
+
+func (f *FakeBackend) ChangeFollowStatusForUserID(userID mastodon.ID, follow bool) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.userRelationship != nil && f.userRelationship.ID == userID {
+		f.userRelationship.Following = follow
+	}
+	return nil
+}
+
+// Search does a simple case insensitive match against the content of every status in the fixture.
+func (f *FakeBackend) Search(query string) (*mastodon.Results, error) {
+	results := &mastodon.Results{}
+	var found []mastodon.Status
+	for _, statuses := range f.fixture.Timelines {
+		for _, s := range statuses {
+			if strings.Contains(strings.ToLower(s.Content), strings.ToLower(query)) {
+				status := s
+				results.Statuses = append(results.Statuses, &status)
+				found = append(found, s)
+			}
+		}
+	}
+
+	f.timelineMessageCache.ClearTimeline("search")
+	f.timelineMessageCache.InsertIntoTimeline("search", found)
+	return results, nil
+}
+
+func (f *FakeBackend) ClearSearch() error {
+	return f.timelineMessageCache.ClearTimeline("search")
+}
+
+func (f *FakeBackend) GetLists() ([]*List, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.fixture.Lists), nil
+}
+
+func (f *FakeBackend) CreateList(list List) (*List, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	list.ID = mastodon.ID(fmt.Sprintf("%d", time.Now().UnixNano()))
+	f.fixture.Lists = append(f.fixture.Lists, &list)
+	return &list, nil
+}
+
+func (f *FakeBackend) UpdateList(list List) (*List, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i, l := range f.fixture.Lists {
+		if l.ID == list.ID {
+			f.fixture.Lists[i] = &list
+			return &list, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown list %s", list.ID)
+}
+
+func (f *FakeBackend) DeleteList(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fixture.Lists = slices.DeleteFunc(f.fixture.Lists, func(l *List) bool { return l.ID == id })
+	delete(f.listMembers, id)
+	return nil
+}
+
+func (f *FakeBackend) GetListAccounts(id mastodon.ID) ([]*mastodon.Account, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.listMembers[id]), nil
+}
+
+func (f *FakeBackend) GetAccountLists(accountID mastodon.ID) ([]*List, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	var lists []*List
+	for _, l := range f.fixture.Lists {
+		if slices.ContainsFunc(f.listMembers[l.ID], func(a *mastodon.Account) bool { return a.ID == accountID }) {
+			lists = append(lists, l)
+		}
+	}
+	return lists, nil
+}
+
+// AddToList can only add users that are in the fixture.
+func (f *FakeBackend) AddToList(listID mastodon.ID, accountID mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	user, ok := f.fixture.Users[string(accountID)]
+	if !ok {
+		return fmt.Errorf("no fixture for user %s", accountID)
+	}
+	if !slices.ContainsFunc(f.listMembers[listID], func(a *mastodon.Account) bool { return a.ID == accountID }) {
+		account := user.Account
+		f.listMembers[listID] = append(f.listMembers[listID], &account)
+	}
+	return nil
+}
+
+func (f *FakeBackend) RemoveFromList(listID mastodon.ID, accountID mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.listMembers[listID] = slices.DeleteFunc(f.listMembers[listID], func(a *mastodon.Account) bool { return a.ID == accountID })
+	return nil
+}
+
+func (f *FakeBackend) GetFollowRequests() ([]*mastodon.Account, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.followRequests), nil
+}
+
+func (f *FakeBackend) AuthorizeFollowRequest(id mastodon.ID) error {
+	f.removeFollowRequest(id)
+	return nil
+}
+
+func (f *FakeBackend) RejectFollowRequest(id mastodon.ID) error {
+	f.removeFollowRequest(id)
+	return nil
+}
+
+func (f *FakeBackend) removeFollowRequest(id mastodon.ID) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.followRequests = slices.DeleteFunc(f.followRequests, func(a *mastodon.Account) bool { return a.ID == id })
+	f.notifications = slices.DeleteFunc(f.notifications, func(n *mastodon.Notification) bool {
+		return n.Type == "follow_request" && n.Account.ID == id
+	})
+}
+
+// purge removes matching statuses, and notifications that match or are about one of the removed statuses.
+// Caller must hold the lock.
+func (f *FakeBackend) purge(statusMatch func(mastodon.Status) bool, notificationMatch func(*mastodon.Notification) bool) {
+	purged := f.timelineMessageCache.PurgeStatuses(statusMatch)
+	f.notifications = slices.DeleteFunc(f.notifications, func(n *mastodon.Notification) bool {
+		return notificationMatch(n) || (n.Status != nil && slices.Contains(purged, n.Status.ID))
+	})
+}
+
+// fixtureAccount is the account from the fixture users, or just the ID if the user isn't in the fixture.
+func (f *FakeBackend) fixtureAccount(id mastodon.ID) *mastodon.Account {
+	if user, ok := f.fixture.Users[string(id)]; ok {
+		account := user.Account
+		return &account
+	}
+	return &mastodon.Account{ID: id}
+}
+
+func (f *FakeBackend) MuteAccount(id mastodon.ID, notifications bool, duration time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.purge(statusByAccount(id), func(n *mastodon.Notification) bool { return notifications && n.Account.ID == id })
+	if !slices.ContainsFunc(f.mutes, func(a *mastodon.Account) bool { return a.ID == id }) {
+		f.mutes = append(f.mutes, f.fixtureAccount(id))
+	}
+	if f.userRelationship != nil && f.userRelationship.ID == id {
+		f.userRelationship.Muting = true
+		f.userRelationship.MutingNotifications = notifications
+	}
+	return nil
+}
+
+func (f *FakeBackend) UnmuteAccount(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.mutes = slices.DeleteFunc(f.mutes, func(a *mastodon.Account) bool { return a.ID == id })
+	if f.userRelationship != nil && f.userRelationship.ID == id {
+		f.userRelationship.Muting = false
+		f.userRelationship.MutingNotifications = false
+	}
+	return nil
+}
+
+func (f *FakeBackend) BlockAccount(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.purge(statusByAccount(id), func(n *mastodon.Notification) bool { return n.Account.ID == id })
+	if !slices.ContainsFunc(f.blocks, func(a *mastodon.Account) bool { return a.ID == id }) {
+		f.blocks = append(f.blocks, f.fixtureAccount(id))
+	}
+	if f.userRelationship != nil && f.userRelationship.ID == id {
+		f.userRelationship.Blocking = true
+		f.userRelationship.Following = false
+	}
+	return nil
+}
+
+func (f *FakeBackend) UnblockAccount(id mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.blocks = slices.DeleteFunc(f.blocks, func(a *mastodon.Account) bool { return a.ID == id })
+	if f.userRelationship != nil && f.userRelationship.ID == id {
+		f.userRelationship.Blocking = false
+	}
+	return nil
+}
+
+func (f *FakeBackend) BlockDomain(domain string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.purge(statusFromDomain(domain), func(n *mastodon.Notification) bool { return AccountDomain(n.Account) == domain })
+	if !slices.Contains(f.domainBlocks, domain) {
+		f.domainBlocks = append(f.domainBlocks, domain)
+	}
+	if f.userInfo != nil && f.userRelationship != nil && AccountDomain(*f.userInfo) == domain {
+		f.userRelationship.DomainBlocking = true
+	}
+	return nil
+}
+
+func (f *FakeBackend) UnblockDomain(domain string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.domainBlocks = slices.DeleteFunc(f.domainBlocks, func(d string) bool { return d == domain })
+	if f.userInfo != nil && f.userRelationship != nil && AccountDomain(*f.userInfo) == domain {
+		f.userRelationship.DomainBlocking = false
+	}
+	return nil
+}
+
+func (f *FakeBackend) GetMutes() ([]*mastodon.Account, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.mutes), nil
+}
+
+func (f *FakeBackend) GetBlocks() ([]*mastodon.Account, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.blocks), nil
+}
+
+func (f *FakeBackend) GetDomainBlocks() ([]string, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return slices.Clone(f.domainBlocks), nil
+}
+
+func (f *FakeBackend) GetInstanceRules() ([]Rule, error) {
+	return f.fixture.Rules, nil
+}
+
+// GetAccountStatuses uses the fixture user's statuses, or failing that any of the account's statuses in the timelines.
+func (f *FakeBackend) GetAccountStatuses(accountID mastodon.ID) ([]mastodon.Status, error) {
+	if user, ok := f.fixture.Users[string(accountID)]; ok {
+		return user.Statuses, nil
+	}
+
+	var statuses []mastodon.Status
+	for _, timeline := range f.fixture.Timelines {
+		for _, s := range timeline {
+			if s.Account.ID == accountID && !slices.ContainsFunc(statuses, func(existing mastodon.Status) bool { return existing.ID == s.ID }) {
+				statuses = append(statuses, s)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func (f *FakeBackend) Report(report Report) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Reported = append(f.Reported, report)
+	return nil
+}
+
+// GetMarker always reports the markers as loaded, there's nothing to wait for.
+func (f *FakeBackend) GetMarker(timeline string) (Marker, bool) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.fixture.Markers[timeline], true
+}
+
+func (f *FakeBackend) SetMarker(timeline string, lastReadID mastodon.ID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	marker := f.fixture.Markers[timeline]
+	if !IDNewer(lastReadID, marker.LastReadID) {
+		return nil
+	}
+	if f.fixture.Markers == nil {
+		f.fixture.Markers = make(map[string]Marker)
+	}
+	marker.LastReadID = lastReadID
+	marker.Version++
+	marker.UpdatedAt = time.Now()
+	f.fixture.Markers[timeline] = marker
+	return nil
+}
+
+// Nothing to stream, the fixture never changes.
+func (f *FakeBackend) StartStream(refreshType events.RefreshType, timelineID string) {}
+
+func (f *FakeBackend) StopStream(timelineID string) {}
+
+func (f *FakeBackend) IsStreaming(timelineID string) bool {
+	return false
+}
@@ -0,0 +1,337 @@
+package mastodon
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+)
+
+const (
+	fixtureReply  = mastodon.ID("110000000000000001")
+	fixtureStatus = mastodon.ID("110000000000000002")
+)
+
+func newTestFakeBackend(t *testing.T) *FakeBackend {
+	t.Helper()
+
+	f, err := NewFakeBackendFromFile(nil, "testdata/fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func timelineIDs(t *testing.T, b Backend, timelineID string) []mastodon.ID {
+	t.Helper()
+
+	statuses, err := b.GetTimeline(timelineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids(statuses)
+}
+
+func TestFakeBackendLoadsFixture(t *testing.T) {
+	f := newTestFakeBackend(t)
+
+	if got, want := timelineIDs(t, f, "home"), []mastodon.ID{fixtureStatus, fixtureReply}; !slices.Equal(got, want) {
+		t.Errorf("home = %v, want %v", got, want)
+	}
+	if got := timelineIDs(t, f, "42"); len(got) != 1 {
+		t.Errorf("list 42 = %v, want 1 status", got)
+	}
+	if f.AccountName() != "shipdon" || f.AccountID() != "1" {
+		t.Errorf("account %s (%s), want shipdon (1)", f.AccountName(), f.AccountID())
+	}
+
+	notifications, _ := f.GetNotifications("notifications")
+	if len(notifications) != 2 {
+		t.Errorf("%d notifications, want 2", len(notifications))
+	}
+	if outbox := f.GetOutbox(); len(outbox) != 2 || outbox[1].Error == "" {
+		t.Errorf("outbox = %+v, want 2 items with the second failed", outbox)
+	}
+}
+
+func TestFakeBackendBadFixture(t *testing.T) {
+	if _, err := NewFakeBackend(nil, []byte(`{"timelines": [`)); err == nil {
+		t.Error("no error for bad fixture JSON")
+	}
+	if _, err := NewFakeBackendFromFile(nil, "testdata/missing.json"); err == nil {
+		t.Error("no error for a missing fixture file")
+	}
+}
+
+func TestFakeBackendActions(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		action  func(f *FakeBackend) error
+		wantErr bool
+		check   func(t *testing.T, f *FakeBackend)
+	}{
+		{
+			name:   "favourite",
+			action: func(f *FakeBackend) error { return f.SetFavourite(fixtureStatus, true) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if s, _ := f.timelineMessageCache.GetStatus(fixtureStatus); s.Favourited != true {
+					t.Error("not favourited")
+				}
+			},
+		},
+		{
+			name:   "unfavourite",
+			action: func(f *FakeBackend) error { return f.SetFavourite(fixtureReply, false) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if s, _ := f.timelineMessageCache.GetStatus(fixtureReply); s.Favourited != false {
+					t.Error("still favourited")
+				}
+			},
+		},
+		{
+			name:   "boost",
+			action: func(f *FakeBackend) error { return f.Boost(fixtureStatus, true) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if s, _ := f.timelineMessageCache.GetStatus(fixtureStatus); s.Reblogged != true {
+					t.Error("not boosted")
+				}
+			},
+		},
+		{
+			name:   "bookmark",
+			action: func(f *FakeBackend) error { return f.SetBookmark(fixtureStatus, true) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if got := timelineIDs(t, f, "bookmarks"); !slices.Equal(got, []mastodon.ID{fixtureStatus}) {
+					t.Errorf("bookmarks = %v", got)
+				}
+			},
+		},
+		{
+			name: "unbookmark",
+			action: func(f *FakeBackend) error {
+				f.SetBookmark(fixtureStatus, true)
+				return f.SetBookmark(fixtureStatus, false)
+			},
+			check: func(t *testing.T, f *FakeBackend) {
+				if got := timelineIDs(t, f, "bookmarks"); len(got) != 0 {
+					t.Errorf("bookmarks = %v, want none", got)
+				}
+			},
+		},
+		{
+			name:   "edit",
+			action: func(f *FakeBackend) error { return f.EditStatus(fixtureReply, &mastodon.Toot{Status: "edited"}) },
+			check: func(t *testing.T, f *FakeBackend) {
+				s, _ := f.timelineMessageCache.GetStatus(fixtureReply)
+				if s.Content != "<p>edited</p>" || s.EditedAt.IsZero() {
+					t.Errorf("status after edit %+v", s)
+				}
+				if source, _ := f.GetStatusSource(fixtureReply); source.Text != "edited" {
+					t.Errorf("source after edit %q", source.Text)
+				}
+			},
+		},
+		{
+			name:   "delete",
+			action: func(f *FakeBackend) error { return f.DeleteStatus(fixtureReply) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if got := timelineIDs(t, f, "home"); !slices.Equal(got, []mastodon.ID{fixtureStatus}) {
+					t.Errorf("home = %v", got)
+				}
+			},
+		},
+		{
+			name:   "post",
+			action: func(f *FakeBackend) error { return f.Post(&mastodon.Toot{Status: "new post"}) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if len(f.Posted) != 1 || f.Posted[0].Status != "new post" {
+					t.Errorf("posted %+v", f.Posted)
+				}
+				home, _ := f.GetTimeline("home")
+				if len(home) != 3 || home[0].Content != "<p>new post</p>" {
+					t.Errorf("home = %v, want the new post on top", ids(home))
+				}
+			},
+		},
+		{
+			name: "poll too big",
+			action: func(f *FakeBackend) error {
+				options := make([]string, DefaultPollLimits.MaxOptions+1)
+				for i := range options {
+					options[i] = "option"
+				}
+				return f.Post(&mastodon.Toot{Status: "poll", Poll: &mastodon.TootPoll{Options: options, ExpiresInSeconds: 3600}})
+			},
+			wantErr: true,
+			check: func(t *testing.T, f *FakeBackend) {
+				if len(f.Posted) != 0 {
+					t.Errorf("posted %+v", f.Posted)
+				}
+			},
+		},
+		{
+			name:   "clear notifications",
+			action: func(f *FakeBackend) error { return f.ClearNotifications() },
+			check: func(t *testing.T, f *FakeBackend) {
+				if n, _ := f.GetNotifications("notifications"); len(n) != 0 {
+					t.Errorf("%d notifications left", len(n))
+				}
+			},
+		},
+		{
+			name:   "discard outbox item",
+			action: func(f *FakeBackend) error { return f.DiscardOutboxItem(2) },
+			check: func(t *testing.T, f *FakeBackend) {
+				if outbox := f.GetOutbox(); len(outbox) != 1 || outbox[0].ID != 1 {
+					t.Errorf("outbox = %+v", outbox)
+				}
+			},
+		},
+		{
+			name:    "unknown status",
+			action:  func(f *FakeBackend) error { return f.SetFavourite("123", true) },
+			wantErr: true,
+			check:   func(t *testing.T, f *FakeBackend) {},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTestFakeBackend(t)
+			if err := tc.action(f); (err != nil) != tc.wantErr {
+				t.Fatalf("err %v, want error %v", err, tc.wantErr)
+			}
+			tc.check(t, f)
+		})
+	}
+}
+
+func TestFakeBackendRefresh(t *testing.T) {
+	f := newTestFakeBackend(t)
+
+	// a reply, so the thread has more than just the status.
+	if err := f.Post(&mastodon.Toot{Status: "replying", InReplyToID: fixtureStatus}); err != nil {
+		t.Fatal(err)
+	}
+	home, _ := f.GetTimeline("home")
+	reply := home[0].ID
+
+	for _, tc := range []struct {
+		name    string
+		event   events.RefreshEvent
+		wantErr bool
+		want    []mastodon.ID
+	}{
+		{
+			name:  "thread",
+			event: events.NewRefreshEvent(f.AccountName(), string(fixtureStatus), false, events.THREAD_REFRESH),
+			want:  []mastodon.ID{fixtureStatus, reply},
+		},
+		{
+			name:  "thread from the reply",
+			event: events.NewRefreshEvent(f.AccountName(), string(reply), false, events.THREAD_REFRESH),
+			want:  []mastodon.ID{fixtureStatus, reply},
+		},
+		{
+			name:  "thread with missing parent",
+			event: events.NewRefreshEvent(f.AccountName(), string(fixtureReply), false, events.THREAD_REFRESH),
+			want:  []mastodon.ID{fixtureReply},
+		},
+		{
+			name:    "thread of unknown status",
+			event:   events.NewRefreshEvent(f.AccountName(), "123", false, events.THREAD_REFRESH),
+			wantErr: true,
+		},
+		{
+			name:  "user",
+			event: events.NewRefreshEvent(f.AccountName(), "2", false, events.USER_REFRESH),
+		},
+		{
+			name:    "unknown user",
+			event:   events.NewRefreshEvent(f.AccountName(), "3", false, events.USER_REFRESH),
+			wantErr: true,
+		},
+		{
+			name:  "another account",
+			event: events.NewRefreshEvent("someone@else", "123", false, events.THREAD_REFRESH),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := f.RefreshMessagesCallback(tc.event)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err %v, want error %v", err, tc.wantErr)
+			}
+			if tc.want != nil {
+				if got := timelineIDs(t, f, tc.event.TimelineID); !slices.Equal(got, tc.want) {
+					t.Errorf("timeline = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+
+	account, relationship := f.GetUserDetails()
+	if account == nil || account.ID != "2" || relationship == nil || !relationship.Following {
+		t.Errorf("user details %+v %+v", account, relationship)
+	}
+}
+
+func TestFakeBackendFilters(t *testing.T) {
+	f := newTestFakeBackend(t)
+	reply, _ := f.timelineMessageCache.GetStatus(fixtureReply)
+	status, _ := f.timelineMessageCache.GetStatus(fixtureStatus)
+
+	if got := f.FilterWarnings(reply, FilterContextHome); !slices.Equal(got, []string{"Old news"}) {
+		t.Errorf("warnings = %v, want [Old news]", got)
+	}
+	if got := f.FilterWarnings(reply, FilterContextPublic); len(got) != 0 {
+		t.Errorf("warnings in public = %v, want none", got)
+	}
+	if got := f.FilterWarnings(status, FilterContextHome); len(got) != 0 {
+		t.Errorf("warnings for unmatched status = %v", got)
+	}
+
+	// hiding instead of warning.
+	filter := f.GetFilters()[0]
+	filter.FilterAction = FilterActionHide
+	if err := f.SaveFilter(filter, nil, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !f.timelineMessageCache.IsHidden(reply, FilterContextHome) {
+		t.Error("not hidden by hide filter")
+	}
+	if f.GetFilters()[0].ExpiresAt == nil {
+		t.Error("expiry not set")
+	}
+
+	if err := f.SaveFilter(f.GetFilters()[0], nil, FilterNoExpiry); err != nil {
+		t.Fatal(err)
+	}
+	if f.GetFilters()[0].ExpiresAt != nil {
+		t.Error("expiry not cleared")
+	}
+
+	if err := f.DeleteFilter(filter.ID); err != nil {
+		t.Fatal(err)
+	}
+	if f.timelineMessageCache.IsHidden(reply, FilterContextHome) || len(f.GetFilters()) != 0 {
+		t.Error("filter still applied after delete")
+	}
+}
+
+func TestFakeBackendReceivesRefreshEvents(t *testing.T) {
+	listener := events.NewEventLister(make(chan events.Event))
+	f, err := NewFakeBackendFromFile(listener, "testdata/fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener.SendEventToReceivers(events.NewRefreshEvent(f.AccountName(), string(fixtureStatus), false, events.THREAD_REFRESH))
+	if got := timelineIDs(t, f, string(fixtureStatus)); !slices.Equal(got, []mastodon.ID{fixtureStatus}) {
+		t.Errorf("thread = %v, want the status", got)
+	}
+
+	listener.SendEventToReceivers(events.NewRefreshEvent(f.AccountName(), "2", false, events.USER_REFRESH))
+	if account, _ := f.GetUserDetails(); account == nil || account.ID != "2" {
+		t.Errorf("user details %+v, want user 2", account)
+	}
+}
@@ -0,0 +1,302 @@
+package mastodon
+
+import (
+	"fmt"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// where a filter applies.
+const (
+	FilterContextHome          = "home"
+	FilterContextNotifications = "notifications"
+	FilterContextPublic        = "public"
+	FilterContextThread        = "thread"
+	FilterContextAccount       = "account"
+)
+
+// what happens to statuses that match a filter.
+const (
+	FilterActionWarn = "warn"
+	FilterActionHide = "hide"
+)
+
+// FilterNoExpiry passed as the expiry to SaveFilter removes any expiry the filter has.
+const FilterNoExpiry time.Duration = -1
+
+// FilterContexts are all the contexts a filter can apply to.
+var FilterContexts = []string{FilterContextHome, FilterContextNotifications, FilterContextPublic, FilterContextThread, FilterContextAccount}
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// compiled whole word keyword regexes, keyed by keyword. Filters are matched every frame.
+var wholeWordRegexes sync.Map
+
+// FilterKeyword is a keyword (or phrase) that a filter matches.
+type FilterKeyword struct {
+	ID        mastodon.ID `json:"id,omitempty"`
+	Keyword   string      `json:"keyword"`
+	WholeWord bool        `json:"whole_word"`
+}
+
+// Filter is a v2 filter. go-mastodon only supports v1 filters.
+type Filter struct {
+	ID           mastodon.ID     `json:"id"`
+	Title        string          `json:"title"`
+	Context      []string        `json:"context"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	FilterAction string          `json:"filter_action"`
+	Keywords     []FilterKeyword `json:"keywords"`
+}
+
+// FilterResult is a filter the server matched against a status.
+type FilterResult struct {
+	Filter         Filter   `json:"filter"`
+	KeywordMatches []string `json:"keyword_matches"`
+}
+
+// filteredStatus is a status along with the server's filter results, which go-mastodon doesn't decode.
+type filteredStatus struct {
+	mastodon.Status
+	Filtered []FilterResult  `json:"filtered"`
+	Reblog   *filteredStatus `json:"reblog"`
+}
+
+// status is the plain status, including the boosted status (if any).
+func (fs *filteredStatus) status() *mastodon.Status {
+	s := fs.Status
+	if fs.Reblog != nil {
+		s.Reblog = fs.Reblog.status()
+	}
+	return &s
+}
+
+// Expired returns true if the filter has an expiry that has passed.
+func (f Filter) Expired() bool {
+	return f.ExpiresAt != nil && time.Now().After(*f.ExpiresAt)
+}
+
+// Matches returns true if the filter applies to the context, and one of its keywords is in the status.
+func (f Filter) Matches(status mastodon.Status, context string) bool {
+	if f.Expired() || !slices.Contains(f.Context, context) {
+		return false
+	}
+
+	text := statusText(status)
+	for _, k := range f.Keywords {
+		if keywordMatches(k, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingFilters are the filters that apply to the status in the context. The server's filter results
+// (keyed by status ID) are used if we have them for the status, otherwise (eg. for streamed statuses)
+// the filters are matched locally. Boosts are matched on the boosted status, which is where the server puts them.
+func matchingFilters(filters []Filter, results map[mastodon.ID][]FilterResult, status mastodon.Status, context string) []Filter {
+	id := status.ID
+	if status.Reblog != nil {
+		id = status.Reblog.ID
+	}
+	serverResults, ok := results[id]
+
+	var matching []Filter
+	if ok {
+		for _, r := range serverResults {
+			if !r.Filter.Expired() && slices.Contains(r.Filter.Context, context) {
+				matching = append(matching, r.Filter)
+			}
+		}
+		return matching
+	}
+
+	for _, f := range filters {
+		if f.Matches(status, context) {
+			matching = append(matching, f)
+		}
+	}
+	return matching
+}
+
+// FilterWarnings returns the titles of the warn filters that match the status.
+func FilterWarnings(filters []Filter, results map[mastodon.ID][]FilterResult, status mastodon.Status, context string) []string {
+	var titles []string
+	for _, f := range matchingFilters(filters, results, status, context) {
+		if f.FilterAction != FilterActionHide {
+			titles = append(titles, f.Title)
+		}
+	}
+	return titles
+}
+
+// FilterHides returns true if a hide filter matches the status.
+func FilterHides(filters []Filter, results map[mastodon.ID][]FilterResult, status mastodon.Status, context string) bool {
+	for _, f := range matchingFilters(filters, results, status, context) {
+		if f.FilterAction == FilterActionHide {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterContextForRefreshType is the filter context that applies to a type of timeline.
+func FilterContextForRefreshType(refreshType events.RefreshType) string {
+	switch refreshType {
+	case events.HOME_REFRESH, events.LIST_REFRESH:
+		return FilterContextHome
+	case events.NOTIFICATION_REFRESH:
+		return FilterContextNotifications
+	case events.THREAD_REFRESH, events.CONVERSATIONS_REFRESH:
+		return FilterContextThread
+	case events.USER_REFRESH:
+		return FilterContextAccount
+	}
+	return FilterContextPublic
+}
+
+// statusText is the text filters are matched against: content, content warning, poll options and media descriptions.
+func statusText(status mastodon.Status) string {
+	if status.Reblog != nil {
+		status = *status.Reblog
+	}
+
+	parts := []string{htmlTagRegex.ReplaceAllString(status.Content, " "), status.SpoilerText}
+	if status.Poll != nil {
+		for _, o := range status.Poll.Options {
+			parts = append(parts, o.Title)
+		}
+	}
+	for _, a := range status.MediaAttachments {
+		parts = append(parts, a.Description)
+	}
+	return strings.ToLower(html.UnescapeString(strings.Join(parts, "\n")))
+}
+
+// keywordMatches checks for the keyword in (lowercase) text. Whole word keywords can't be part of a longer word.
+func keywordMatches(k FilterKeyword, text string) bool {
+	keyword := strings.ToLower(strings.TrimSpace(k.Keyword))
+	if keyword == "" {
+		return false
+	}
+	if !k.WholeWord {
+		return strings.Contains(text, keyword)
+	}
+
+	re, ok := wholeWordRegexes.Load(keyword)
+	if !ok {
+		re, _ = wholeWordRegexes.LoadOrStore(keyword, regexp.MustCompile(`(^|[^\pL\pN_])`+regexp.QuoteMeta(keyword)+`($|[^\pL\pN_])`))
+	}
+	return re.(*regexp.Regexp).MatchString(text)
+}
+
+// getStatuses gets a list of statuses directly (rather than with go-mastodon), so the server's
+// filter results for them are kept.
+func (c *MastodonBackend) getStatuses(uri string, params url.Values) ([]*mastodon.Status, error) {
+	var filtered []*filteredStatus
+	err := c.doAPI(c.ctx, http.MethodGet, uri, params, &filtered)
+	if err != nil {
+		return nil, err
+	}
+	c.timelineMessageCache.AddFilterResults(filtered...)
+
+	statuses := make([]*mastodon.Status, 0, len(filtered))
+	for _, fs := range filtered {
+		statuses = append(statuses, fs.status())
+	}
+	return statuses, nil
+}
+
+// FilterWarnings returns the titles of the warn filters that match the status.
+func (c *MastodonBackend) FilterWarnings(status mastodon.Status, context string) []string {
+	return c.timelineMessageCache.FilterWarnings(status, context)
+}
+
+// GetFilters returns the filters from the last refresh.
+func (c *MastodonBackend) GetFilters() []Filter {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.filters
+}
+
+// RefreshFilters gets the latest filters from the server.
+func (c *MastodonBackend) RefreshFilters() error {
+	var filters []Filter
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v2/filters", nil, &filters)
+	if err != nil {
+		log.Errorf("unable to get filters : err %s", err)
+		return err
+	}
+
+	c.lock.Lock()
+	c.filters = filters
+	c.lock.Unlock()
+	c.timelineMessageCache.SetFilters(filters)
+	return nil
+}
+
+// SaveFilter creates the filter if it has no ID, otherwise updates it. Keywords that were removed from
+// an existing filter need to be passed in removedKeywords so they get deleted.
+// expiresIn of 0 means the expiry is left as is (or never expires for new filters), FilterNoExpiry removes it.
+func (c *MastodonBackend) SaveFilter(filter Filter, removedKeywords []mastodon.ID, expiresIn time.Duration) error {
+	params := url.Values{}
+	params.Set("title", filter.Title)
+	params.Set("filter_action", filter.FilterAction)
+	for _, ctx := range filter.Context {
+		params.Add("context[]", ctx)
+	}
+	if expiresIn > 0 {
+		params.Set("expires_in", strconv.Itoa(int(expiresIn.Seconds())))
+	} else if expiresIn == FilterNoExpiry {
+		// an empty expiry clears it.
+		params.Set("expires_in", "")
+	}
+
+	for i, k := range filter.Keywords {
+		prefix := fmt.Sprintf("keywords_attributes[%d]", i)
+		if k.ID != "" {
+			params.Set(prefix+"[id]", string(k.ID))
+		}
+		params.Set(prefix+"[keyword]", k.Keyword)
+		params.Set(prefix+"[whole_word]", strconv.FormatBool(k.WholeWord))
+	}
+	for i, id := range removedKeywords {
+		prefix := fmt.Sprintf("keywords_attributes[%d]", len(filter.Keywords)+i)
+		params.Set(prefix+"[id]", string(id))
+		params.Set(prefix+"[_destroy]", "true")
+	}
+
+	method := http.MethodPost
+	uri := "/api/v2/filters"
+	if filter.ID != "" {
+		method = http.MethodPut
+		uri = fmt.Sprintf("/api/v2/filters/%s", url.PathEscape(string(filter.ID)))
+	}
+
+	err := c.doAPI(c.ctx, method, uri, params, nil)
+	if err != nil {
+		log.Errorf("unable to save filter %s : err %s", filter.Title, err)
+		return err
+	}
+	return c.RefreshFilters()
+}
+
+// DeleteFilter removes the filter (and its keywords) from the server.
+func (c *MastodonBackend) DeleteFilter(id mastodon.ID) error {
+	err := c.doAPI(c.ctx, http.MethodDelete, fmt.Sprintf("/api/v2/filters/%s", url.PathEscape(string(id))), nil, nil)
+	if err != nil {
+		log.Errorf("unable to delete filter %s : err %s", id, err)
+		return err
+	}
+	return c.RefreshFilters()
+}
@@ -0,0 +1,310 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-mastodon"
+)
+
+func TestKeywordMatches(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		keyword FilterKeyword
+		content string
+		want    bool
+	}{
+		{"substring", FilterKeyword{Keyword: "cat"}, "<p>concatenate</p>", true},
+		{"whole word", FilterKeyword{Keyword: "cat", WholeWord: true}, "<p>a cat sat</p>", true},
+		{"whole word inside another", FilterKeyword{Keyword: "cat", WholeWord: true}, "<p>concatenate</p>", false},
+		{"whole word at punctuation", FilterKeyword{Keyword: "cat", WholeWord: true}, "<p>my cat!</p>", true},
+		{"whole word between tags", FilterKeyword{Keyword: "cat", WholeWord: true}, "<p>dog</p><p>cat</p>", true},
+		{"ignores case", FilterKeyword{Keyword: "Cat"}, "<p>CAT</p>", true},
+		{"phrase", FilterKeyword{Keyword: "black cat", WholeWord: true}, "<p>a black cat</p>", true},
+		{"escaped html", FilterKeyword{Keyword: "r&d"}, "<p>r&amp;d news</p>", true},
+		{"not in tags", FilterKeyword{Keyword: "href"}, `<p><a href="x">link</a></p>`, false},
+		{"blank keyword", FilterKeyword{Keyword: " "}, "<p>anything</p>", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := keywordMatches(tc.keyword, statusText(mastodon.Status{Content: tc.content})); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	filter := Filter{
+		Title:    "cats",
+		Context:  []string{FilterContextHome},
+		Keywords: []FilterKeyword{{Keyword: "cat", WholeWord: true}},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		filter  func(f *Filter)
+		status  mastodon.Status
+		context string
+		want    bool
+	}{
+		{
+			name:    "content",
+			status:  mastodon.Status{Content: "<p>cat</p>"},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "other context",
+			status:  mastodon.Status{Content: "<p>cat</p>"},
+			context: FilterContextPublic,
+		},
+		{
+			name:    "expired",
+			filter:  func(f *Filter) { f.ExpiresAt = &past },
+			status:  mastodon.Status{Content: "<p>cat</p>"},
+			context: FilterContextHome,
+		},
+		{
+			name:    "not expired yet",
+			filter:  func(f *Filter) { f.ExpiresAt = &future },
+			status:  mastodon.Status{Content: "<p>cat</p>"},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "boosted status",
+			status:  mastodon.Status{Reblog: &mastodon.Status{Content: "<p>cat</p>"}},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "content warning",
+			status:  mastodon.Status{SpoilerText: "cat pics", Content: "<p>look</p>"},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "media description",
+			status:  mastodon.Status{MediaAttachments: []mastodon.Attachment{{Description: "a cat"}}},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "poll option",
+			status:  mastodon.Status{Poll: &mastodon.Poll{Options: []mastodon.PollOption{{Title: "cat"}, {Title: "dog"}}}},
+			context: FilterContextHome,
+			want:    true,
+		},
+		{
+			name:    "no keyword",
+			status:  mastodon.Status{Content: "<p>dog</p>"},
+			context: FilterContextHome,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := filter
+			if tc.filter != nil {
+				tc.filter(&f)
+			}
+			if got := f.Matches(tc.status, tc.context); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerFilterResults(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	warn := Filter{ID: "1", Title: "cats", Context: []string{FilterContextHome}, FilterAction: FilterActionWarn, Keywords: []FilterKeyword{{Keyword: "cat"}}}
+	hide := Filter{ID: "2", Title: "dogs", Context: []string{FilterContextHome}, FilterAction: FilterActionHide, Keywords: []FilterKeyword{{Keyword: "dog"}}}
+	filters := []Filter{warn, hide}
+
+	expiredHide := hide
+	expiredHide.ExpiresAt = &past
+
+	for _, tc := range []struct {
+		name         string
+		results      map[mastodon.ID][]FilterResult
+		status       mastodon.Status
+		context      string
+		wantWarnings []string
+		wantHidden   bool
+	}{
+		{
+			name:         "matched locally without results",
+			status:       mastodon.Status{ID: "10", Content: "<p>cat</p>"},
+			context:      FilterContextHome,
+			wantWarnings: []string{"cats"},
+		},
+		{
+			name:       "hidden locally without results",
+			status:     mastodon.Status{ID: "10", Content: "<p>dog</p>"},
+			context:    FilterContextHome,
+			wantHidden: true,
+		},
+		{
+			name:    "server didn't match",
+			results: map[mastodon.ID][]FilterResult{"10": {}},
+			status:  mastodon.Status{ID: "10", Content: "<p>cat</p>"},
+			context: FilterContextHome,
+		},
+		{
+			name:       "server matched",
+			results:    map[mastodon.ID][]FilterResult{"10": {{Filter: hide}}},
+			status:     mastodon.Status{ID: "10", Content: "<p>nothing local</p>"},
+			context:    FilterContextHome,
+			wantHidden: true,
+		},
+		{
+			name:    "server matched in another context",
+			results: map[mastodon.ID][]FilterResult{"10": {{Filter: hide}}},
+			status:  mastodon.Status{ID: "10"},
+			context: FilterContextPublic,
+		},
+		{
+			name:    "server matched but since expired",
+			results: map[mastodon.ID][]FilterResult{"10": {{Filter: expiredHide}}},
+			status:  mastodon.Status{ID: "10"},
+			context: FilterContextHome,
+		},
+		{
+			name:         "server matched the boosted status",
+			results:      map[mastodon.ID][]FilterResult{"5": {{Filter: warn}}},
+			status:       mastodon.Status{ID: "10", Reblog: &mastodon.Status{ID: "5"}},
+			context:      FilterContextHome,
+			wantWarnings: []string{"cats"},
+		},
+		{
+			name:    "results for another status",
+			results: map[mastodon.ID][]FilterResult{"11": {{Filter: hide}}},
+			status:  mastodon.Status{ID: "10", Content: "<p>cat</p>"},
+			context: FilterContextHome,
+
+			wantWarnings: []string{"cats"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := FilterWarnings(filters, tc.results, tc.status, tc.context); !slices.Equal(got, tc.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tc.wantWarnings)
+			}
+			if got := FilterHides(filters, tc.results, tc.status, tc.context); got != tc.wantHidden {
+				t.Errorf("hidden = %v, want %v", got, tc.wantHidden)
+			}
+		})
+	}
+}
+
+func TestGetStatusesKeepsFilterResults(t *testing.T) {
+	c := newTestBackend(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": "3", "content": "<p>cat</p>", "filtered": []},
+			{"id": "2", "content": "<p>fine</p>", "filtered": [{"filter": {"id": "1", "title": "hidden", "context": ["home"], "filter_action": "hide"}, "keyword_matches": ["fine"]}]},
+			{"id": "1", "reblog": {"id": "0", "content": "<p>boosted</p>", "filtered": [{"filter": {"id": "2", "title": "boosts", "context": ["home"], "filter_action": "warn"}}]}}
+		]`))
+	}))
+	c.timelineMessageCache.SetFilters([]Filter{{Title: "cats", Context: []string{FilterContextHome}, Keywords: []FilterKeyword{{Keyword: "cat"}}}})
+
+	statuses, err := c.getStatuses("/api/v1/timelines/home", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || statuses[2].Reblog == nil || statuses[2].Reblog.ID != "0" {
+		t.Fatalf("statuses not decoded: %+v", statuses)
+	}
+
+	if got := c.FilterWarnings(*statuses[0], FilterContextHome); len(got) != 0 {
+		t.Errorf("status the server didn't filter got warnings %v", got)
+	}
+	if !c.timelineMessageCache.IsHidden(*statuses[1], FilterContextHome) {
+		t.Errorf("status the server hid isn't hidden")
+	}
+	if got := c.FilterWarnings(*statuses[2], FilterContextHome); !slices.Equal(got, []string{"boosts"}) {
+		t.Errorf("boost warnings = %v, want [boosts]", got)
+	}
+}
+
+func TestSaveFilterExpiry(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		expiresIn time.Duration
+		wantSet   bool
+		want      string
+	}{
+		{"left as is", 0, false, ""},
+		{"an hour", time.Hour, true, "3600"},
+		{"cleared", FilterNoExpiry, true, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var form url.Values
+			c := newTestBackend(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					r.ParseForm()
+					lock.Lock()
+					form = r.PostForm
+					lock.Unlock()
+				}
+				json.NewEncoder(w).Encode([]Filter{})
+			}))
+
+			err := c.SaveFilter(Filter{ID: "1", Title: "cats", FilterAction: FilterActionWarn}, nil, tc.expiresIn)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if form == nil {
+				t.Fatal("filter wasn't saved")
+			}
+			got, ok := form["expires_in"]
+			if ok != tc.wantSet || (ok && got[0] != tc.want) {
+				t.Errorf("expires_in = %q (set %v), want %q (set %v)", got, ok, tc.want, tc.wantSet)
+			}
+		})
+	}
+}
+
+func TestUpdateStatusKeepsFilterResults(t *testing.T) {
+	hide := Filter{ID: "2", Title: "dogs", Context: []string{FilterContextHome}, FilterAction: FilterActionHide, Keywords: []FilterKeyword{{Keyword: "dog"}}}
+
+	for _, tc := range []struct {
+		name       string
+		update     func(s mastodon.Status) mastodon.Status
+		wantHidden bool
+	}{
+		{"favourited", func(s mastodon.Status) mastodon.Status { s.Favourited = true; return s }, true},
+		{"boosted", func(s mastodon.Status) mastodon.Status { s.Reblogged = true; s.ReblogsCount++; return s }, true},
+		{"edited", func(s mastodon.Status) mastodon.Status { s.Content = "<p>edited</p>"; return s }, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := NewTimelineCache()
+			cache.SetFilters([]Filter{hide})
+
+			// the server hides it, though nothing matches locally.
+			status := mastodon.Status{ID: "10", Content: "<p>nothing local</p>"}
+			boost := mastodon.Status{ID: "11", Reblog: &status}
+			cache.InsertIntoTimeline("home", []mastodon.Status{status, boost})
+			cache.AddFilterResults(&filteredStatus{Status: status, Filtered: []FilterResult{{Filter: hide}}})
+
+			if err := cache.UpdateStatus(tc.update(status)); err != nil {
+				t.Fatal(err)
+			}
+			updated, _ := cache.GetStatus("10")
+			if got := cache.IsHidden(updated, FilterContextHome); got != tc.wantHidden {
+				t.Errorf("hidden %v, want %v", got, tc.wantHidden)
+			}
+			updatedBoost, _ := cache.GetStatus("11")
+			if got := cache.IsHidden(updatedBoost, FilterContextHome); got != tc.wantHidden {
+				t.Errorf("boost hidden %v, want %v", got, tc.wantHidden)
+			}
+		})
+	}
+}
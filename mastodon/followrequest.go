@@ -0,0 +1,104 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"slices"
+)
+
+// GetFollowRequests returns the accounts waiting for us to accept their follow request.
+func (c *MastodonBackend) GetFollowRequests() ([]*mastodon.Account, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return slices.Clone(c.followRequests), nil
+}
+
+// AuthorizeFollowRequest accepts the account's follow request.
+func (c *MastodonBackend) AuthorizeFollowRequest(id mastodon.ID) error {
+	err := c.client.FollowRequestAuthorize(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to authorize follow request from %s : err %s", id, err)
+		return err
+	}
+	c.removeFollowRequest(id)
+	return nil
+}
+
+// RejectFollowRequest rejects the account's follow request.
+func (c *MastodonBackend) RejectFollowRequest(id mastodon.ID) error {
+	err := c.client.FollowRequestReject(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to reject follow request from %s : err %s", id, err)
+		return err
+	}
+	c.removeFollowRequest(id)
+	return nil
+}
+
+// removeFollowRequest removes a handled request from the follow requests column and from notifications.
+func (c *MastodonBackend) removeFollowRequest(id mastodon.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.followRequests = slices.DeleteFunc(c.followRequests, func(a *mastodon.Account) bool { return a.ID == id })
+	c.deleteNotifications(func(n Notification) bool {
+		return n.Type == "follow_request" && n.Account.ID == id
+	})
+}
+
+// refreshFollowRequests gets the latest follow requests, or the next page of older ones.
+// Like bookmarks, follow requests are paged with the IDs from the Link header, and the latest page
+// is merged in above the older ones we have so polling doesn't lose our place.
+func (c *MastodonBackend) refreshFollowRequests(timelineID string, getOlder bool, clearExisting bool) error {
+	params := mastodon.Pagination{Limit: MastodonLimit}
+
+	if getOlder {
+		c.lock.RLock()
+		maxID := c.nextPage[timelineID]
+		c.lock.RUnlock()
+		if maxID == "" {
+			return nil
+		}
+		params.MaxID = maxID
+	}
+
+	accounts, err := c.client.GetFollowRequests(c.ctx, &params)
+	if err != nil {
+		log.Errorf("unable to get follow requests : err %s", err)
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// The Link header is missing once there are no more. A short latest page is the whole list,
+	// so there's nothing older to keep.
+	_, havePage := c.nextPage[timelineID]
+	wholeList := !getOlder && len(accounts) < MastodonLimit
+	if getOlder || clearExisting || wholeList || !havePage {
+		c.nextPage[timelineID] = params.MaxID
+		if len(accounts) == 0 || wholeList {
+			c.nextPage[timelineID] = ""
+		}
+	}
+
+	if getOlder {
+		for _, a := range accounts {
+			if !slices.ContainsFunc(c.followRequests, func(existing *mastodon.Account) bool { return existing.ID == a.ID }) {
+				c.followRequests = append(c.followRequests, a)
+			}
+		}
+		return nil
+	}
+
+	// latest page goes on top, followed by the older requests we already have.
+	merged := slices.Clone(accounts)
+	if !clearExisting && !wholeList {
+		for _, existing := range c.followRequests {
+			if !slices.ContainsFunc(merged, func(a *mastodon.Account) bool { return a.ID == existing.ID }) {
+				merged = append(merged, existing)
+			}
+		}
+	}
+	c.followRequests = merged
+	return nil
+}
@@ -0,0 +1,202 @@
+package mastodon
+
+import (
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+// GapIDPrefix starts the IDs of gap markers in a timeline. A gap marker sits directly above the
+// status it was found above, and means there are statuses between it and the status above it that
+// haven't been fetched yet.
+const GapIDPrefix = "gap:"
+
+// GapID is the ID of the gap marker above the status belowID.
+func GapID(belowID mastodon.ID) mastodon.ID {
+	return mastodon.ID(GapIDPrefix + string(belowID))
+}
+
+// IsGapID returns true if the ID is a gap marker rather than a status.
+func IsGapID(id mastodon.ID) bool {
+	return strings.HasPrefix(string(id), GapIDPrefix)
+}
+
+// gapBelowID is the status the gap marker sits above.
+func gapBelowID(gapID mastodon.ID) mastodon.ID {
+	return mastodon.ID(strings.TrimPrefix(string(gapID), GapIDPrefix))
+}
+
+// sortTimeline sorts the IDs newest first, with gap markers directly above the status they belong to.
+func sortTimeline(messages []mastodon.ID) {
+	slices.SortStableFunc(messages, func(a, b mastodon.ID) int {
+		keyA, keyB := gapBelowID(a), gapBelowID(b)
+		switch {
+		case IDNewer(keyA, keyB):
+			return -1
+		case IDNewer(keyB, keyA):
+			return 1
+		case IsGapID(a) && !IsGapID(b):
+			return -1
+		case IsGapID(b) && !IsGapID(a):
+			return 1
+		}
+		return 0
+	})
+}
+
+// newestID is the newest status in the timeline, ignoring gap markers. Empty if there are no statuses.
+func newestID(messages []mastodon.ID) mastodon.ID {
+	for _, id := range messages {
+		if !IsGapID(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// oldestID is the oldest status in the timeline, ignoring gap markers. Empty if there are no statuses.
+func oldestID(messages []mastodon.ID) mastodon.ID {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if !IsGapID(messages[i]) {
+			return messages[i]
+		}
+	}
+	return ""
+}
+
+// newestStatusID is the newest of the statuses. Empty if there are none.
+func newestStatusID(statuses []*mastodon.Status) mastodon.ID {
+	var newest mastodon.ID
+	for _, s := range statuses {
+		if newest == "" || IDNewer(s.ID, newest) {
+			newest = s.ID
+		}
+	}
+	return newest
+}
+
+// oldestStatusID is the oldest of the statuses. Empty if there are none.
+func oldestStatusID(statuses []*mastodon.Status) mastodon.ID {
+	var oldest mastodon.ID
+	for _, s := range statuses {
+		if oldest == "" || IDNewer(oldest, s.ID) {
+			oldest = s.ID
+		}
+	}
+	return oldest
+}
+
+// AddGap adds a gap marker above the status belowID.
+func (tc *TimelineCache) AddGap(timeline string, belowID mastodon.ID) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	details := tc.timelineMessageCache[timeline]
+	gapID := GapID(belowID)
+	if slices.Contains(details.messages, gapID) {
+		return nil
+	}
+
+	details.messages = append(details.messages, gapID)
+	sortTimeline(details.messages)
+	tc.timelineMessageCache[timeline] = details
+	tc.store.QueueTimeline(timeline, details.messages)
+	return nil
+}
+
+// RemoveGap removes a gap marker.
+func (tc *TimelineCache) RemoveGap(timeline string, gapID mastodon.ID) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+
+	details := tc.timelineMessageCache[timeline]
+	details.messages = slices.DeleteFunc(details.messages, func(id mastodon.ID) bool { return id == gapID })
+	tc.timelineMessageCache[timeline] = details
+	tc.store.QueueTimeline(timeline, details.messages)
+	return nil
+}
+
+// newestStatusAbove is the closest status above position i, skipping any other gap markers.
+func newestStatusAbove(messages []mastodon.ID, i int) mastodon.ID {
+	for j := i - 1; j >= 0; j-- {
+		if !IsGapID(messages[j]) {
+			return messages[j]
+		}
+	}
+	return ""
+}
+
+// detectGap is called after getting newer statuses with min_id paging. If the page came back full,
+// there are more statuses than we got. The latest page is fetched as well, and if it doesn't meet up
+// with the statuses we got, a gap marker is added between them.
+// Returns all the statuses to add to the timeline, and the status the gap is above (empty if no gap).
+func (c *MastodonBackend) detectGap(refreshType events.RefreshType, timelineID string, statuses []*mastodon.Status) ([]*mastodon.Status, mastodon.ID) {
+	if len(statuses) < MastodonLimit {
+		return statuses, ""
+	}
+
+	latest, err := c.getTimelinePage(refreshType, timelineID, mastodon.Pagination{Limit: MastodonLimit})
+	if err != nil {
+		return statuses, ""
+	}
+
+	belowGap := newestStatusID(statuses)
+	for _, s := range latest {
+		if !slices.ContainsFunc(statuses, func(existing *mastodon.Status) bool { return existing.ID == s.ID }) {
+			statuses = append(statuses, s)
+		}
+	}
+
+	if len(latest) == 0 || !IDNewer(oldestStatusID(latest), belowGap) {
+		return statuses, ""
+	}
+	return statuses, belowGap
+}
+
+// latestPageGap is used when a timeline that can have gaps is refreshed with ClearExisting. Rather than
+// clearing, what we already have (and its gap markers) is kept and the latest page added above it. If a
+// full page doesn't meet up with what we have, returns the status a gap marker goes above (else empty).
+func latestPageGap(existing []mastodon.ID, statuses []*mastodon.Status) mastodon.ID {
+	newest := newestID(existing)
+	if newest == "" || len(statuses) < MastodonLimit || !IDNewer(oldestStatusID(statuses), newest) {
+		return ""
+	}
+	return newest
+}
+
+// LoadGap gets the statuses missing from a gap, starting with the oldest. If there are more than
+// fit in a page, the gap is moved up above the statuses that were added.
+func (c *MastodonBackend) LoadGap(refreshType events.RefreshType, timelineID string, gapID mastodon.ID) error {
+	details, _ := c.timelineMessageCache.GetTimelineDetails(timelineID)
+	i := slices.Index(details.messages, gapID)
+	if i == -1 {
+		return nil
+	}
+
+	params := mastodon.Pagination{
+		MinID: gapBelowID(gapID),
+		MaxID: newestStatusAbove(details.messages, i),
+		Limit: MastodonLimit,
+	}
+	statuses, err := c.getTimelinePage(refreshType, timelineID, params)
+	if err != nil {
+		log.Errorf("unable to load gap %s in timelineID %s : err %s", gapID, timelineID, err)
+		return err
+	}
+
+	var nonPtrStatus []mastodon.Status
+	for _, s := range statuses {
+		nonPtrStatus = append(nonPtrStatus, *s)
+	}
+
+	c.timelineMessageCache.RemoveGap(timelineID, gapID)
+	c.timelineMessageCache.InsertIntoTimeline(timelineID, nonPtrStatus)
+	if len(statuses) == MastodonLimit {
+		c.timelineMessageCache.AddGap(timelineID, newestStatusID(statuses))
+	}
+
+	events.FireEvent(events.NewTimelineUpdatedEvent(c.AccountName(), timelineID))
+	return nil
+}
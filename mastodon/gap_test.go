@@ -0,0 +1,237 @@
+package mastodon
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+)
+
+func TestSortTimeline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		messages []mastodon.ID
+		want     []mastodon.ID
+	}{
+		{"newest first", []mastodon.ID{"9", "10", "8"}, []mastodon.ID{"10", "9", "8"}},
+		{"longer IDs are newer", []mastodon.ID{"99", "100"}, []mastodon.ID{"100", "99"}},
+		{"gap above its status", []mastodon.ID{"5", GapID("5"), "10"}, []mastodon.ID{"10", GapID("5"), "5"}},
+		{"gap before status", []mastodon.ID{GapID("5"), "5", "4"}, []mastodon.ID{GapID("5"), "5", "4"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			messages := slices.Clone(tc.messages)
+			sortTimeline(messages)
+			if !slices.Equal(messages, tc.want) {
+				t.Errorf("got %v, want %v", messages, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewestAndOldestIDSkipGaps(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		messages   []mastodon.ID
+		wantNewest mastodon.ID
+		wantOldest mastodon.ID
+	}{
+		{"empty", nil, "", ""},
+		{"no gaps", []mastodon.ID{"3", "2", "1"}, "3", "1"},
+		{"gap at top", []mastodon.ID{GapID("3"), "3", "2"}, "3", "2"},
+		{"only gaps", []mastodon.ID{GapID("3")}, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := newestID(tc.messages); got != tc.wantNewest {
+				t.Errorf("newestID = %q, want %q", got, tc.wantNewest)
+			}
+			if got := oldestID(tc.messages); got != tc.wantOldest {
+				t.Errorf("oldestID = %q, want %q", got, tc.wantOldest)
+			}
+		})
+	}
+}
+
+func TestDetectGap(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+
+		// statuses on the instance, and the newest we already have.
+		home   []mastodon.Status
+		minID  mastodon.ID
+		wantID []mastodon.ID
+
+		// empty if there shouldn't be a gap.
+		wantBelowGap mastodon.ID
+	}{
+		{
+			name:   "page not full",
+			home:   statusRange(1, 25),
+			minID:  "10",
+			wantID: idRange(11, 25),
+		},
+		{
+			name:   "full page meets the latest",
+			home:   statusRange(1, 40),
+			minID:  "10",
+			wantID: idRange(11, 40),
+		},
+		{
+			name:   "full page ends exactly at the latest",
+			home:   statusRange(1, 30),
+			minID:  "10",
+			wantID: idRange(11, 30),
+		},
+		{
+			name:         "statuses missing between the pages",
+			home:         statusRange(1, 60),
+			minID:        "10",
+			wantID:       append(idRange(41, 60), idRange(11, 30)...),
+			wantBelowGap: "30",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestBackend(t, &testInstance{home: tc.home})
+
+			page, err := c.getTimelinePage(events.HOME_REFRESH, "home", mastodon.Pagination{MinID: tc.minID, Limit: MastodonLimit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			statuses, belowGap := c.detectGap(events.HOME_REFRESH, "home", page)
+
+			var got []mastodon.ID
+			for _, s := range statuses {
+				got = append(got, s.ID)
+			}
+			sortTimeline(got)
+			if !slices.Equal(got, tc.wantID) {
+				t.Errorf("statuses = %v, want %v", got, tc.wantID)
+			}
+			if belowGap != tc.wantBelowGap {
+				t.Errorf("gap below %q, want %q", belowGap, tc.wantBelowGap)
+			}
+		})
+	}
+}
+
+func TestLoadGap(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+
+		// statuses on the instance, and those we have either side of the gap.
+		home  []mastodon.Status
+		have  []mastodon.Status
+		below mastodon.ID
+		want  []mastodon.ID
+	}{
+		{
+			name:  "gap fits in a page",
+			home:  statusRange(1, 60),
+			have:  append(statusRange(41, 60), statusRange(11, 30)...),
+			below: "30",
+			want:  idRange(11, 60),
+		},
+		{
+			name:  "gap bigger than a page moves up",
+			home:  statusRange(1, 100),
+			have:  append(statusRange(91, 100), statusRange(1, 30)...),
+			below: "30",
+			want:  append(append(idRange(91, 100), GapID("50")), idRange(1, 50)...),
+		},
+		{
+			name:  "nothing missing",
+			home:  statusRange(1, 40),
+			have:  statusRange(1, 40),
+			below: "30",
+			want:  idRange(1, 40),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestBackend(t, &testInstance{home: tc.home})
+			c.timelineMessageCache.InsertIntoTimeline("home", tc.have)
+			c.timelineMessageCache.AddGap("home", tc.below)
+
+			if err := c.LoadGap(events.HOME_REFRESH, "home", GapID(tc.below)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := ids(c.timelineMessageCache.GetAllStatusForTimeline("home"))
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("timeline = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadGapUntilClosed(t *testing.T) {
+	ti := &testInstance{home: statusRange(1, 110)}
+	c := newTestBackend(t, ti)
+	c.timelineMessageCache.InsertIntoTimeline("home", append(statusRange(101, 110), statusRange(1, 10)...))
+	c.timelineMessageCache.AddGap("home", "10")
+
+	for i := 0; i < 10; i++ {
+		details, _ := c.timelineMessageCache.GetTimelineDetails("home")
+		gap := slices.IndexFunc(details.messages, IsGapID)
+		if gap == -1 {
+			break
+		}
+		c.LoadGap(events.HOME_REFRESH, "home", details.messages[gap])
+	}
+
+	got := ids(c.timelineMessageCache.GetAllStatusForTimeline("home"))
+	if !slices.Equal(got, idRange(1, 110)) {
+		t.Errorf("timeline = %v, want all 110 statuses", got)
+	}
+	if n := ti.requestCount(); n != 5 {
+		t.Errorf("took %d requests, want 5", n)
+	}
+}
+
+func TestRefreshKeepsGapsWhenClearing(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		home []mastodon.Status
+		want []mastodon.ID
+	}{
+		{
+			name: "latest page meets what we have",
+			home: statusRange(1, 20),
+			want: append(append(idRange(6, 20), GapID("5")), idRange(1, 5)...),
+		},
+		{
+			name: "gap below the latest page",
+			home: statusRange(1, 60),
+			want: append(append(append(append(idRange(41, 60), GapID("10")), idRange(6, 10)...), GapID("5")), idRange(1, 5)...),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestBackend(t, &testInstance{home: tc.home})
+			c.timelineMessageCache.InsertIntoTimeline("home", statusRange(1, 10))
+			c.timelineMessageCache.AddGap("home", "5")
+
+			if err := c.RefreshMessagesCallback(events.NewRefreshEvent(c.AccountName(), "home", true, events.HOME_REFRESH)); err != nil {
+				t.Fatal(err)
+			}
+
+			got := ids(c.timelineMessageCache.GetAllStatusForTimeline("home"))
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("timeline = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRefreshNoGapWhenNothingAdded(t *testing.T) {
+	c := newTestBackend(t, &testInstance{home: statusRange(1, 60)})
+
+	// adding marks the timeline as just refreshed, so the next page is skipped.
+	c.timelineMessageCache.AddToTimeline("home", false, statusRange(1, 10), true)
+	if err := c.RefreshMessagesCallback(events.NewRefreshEvent(c.AccountName(), "home", false, events.HOME_REFRESH)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := ids(c.timelineMessageCache.GetAllStatusForTimeline("home"))
+	if !slices.Equal(got, idRange(1, 10)) {
+		t.Errorf("timeline = %v, want a gap only with the statuses it's between", got)
+	}
+}
@@ -0,0 +1,60 @@
+package mastodon
+
+import (
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+// lookupInstanceDetails queries the instance for where the streaming API lives and
+// what limits it has.
+func (c *MastodonBackend) lookupInstanceDetails() {
+	instance, err := c.client.GetInstance(c.ctx)
+	if err != nil {
+		log.Errorf("unable to get instance details : err %s", err)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if instance.Configuration != nil && instance.Configuration.Polls != nil {
+		c.pollLimits = pollLimitsFromConfig(*instance.Configuration.Polls)
+	}
+
+	streamingAPI, ok := instance.URLs["streaming_api"]
+	if !ok || streamingAPI == "" {
+		return
+	}
+
+	// we use the server sent events version of the API, so need http(s) instead of ws(s)
+	streamingAPI = strings.Replace(streamingAPI, "wss://", "https://", 1)
+	streamingAPI = strings.Replace(streamingAPI, "ws://", "http://", 1)
+	c.streamingBaseURL = streamingAPI
+}
+
+// PollLimits returns the limits the instance puts on polls.
+func (c *MastodonBackend) PollLimits() PollLimits {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.pollLimits
+}
+
+// pollLimitsFromConfig reads the poll section of the instance configuration. Anything
+// missing falls back to the Mastodon defaults.
+func pollLimitsFromConfig(polls map[string]int) PollLimits {
+	limits := DefaultPollLimits
+	if v, ok := polls["max_options"]; ok && v > 0 {
+		limits.MaxOptions = v
+	}
+	if v, ok := polls["max_characters_per_option"]; ok && v > 0 {
+		limits.MaxCharactersPerOption = v
+	}
+	if v, ok := polls["min_expiration"]; ok && v > 0 {
+		limits.MinExpiration = time.Duration(v) * time.Second
+	}
+	if v, ok := polls["max_expiration"]; ok && v > 0 {
+		limits.MaxExpiration = time.Duration(v) * time.Second
+	}
+	return limits
+}
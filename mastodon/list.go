@@ -1,10 +1,110 @@
 package mastodon
 
+import (
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// who sees replies in a list.
+const (
+	RepliesPolicyFollowed = "followed"
+	RepliesPolicyList     = "list"
+	RepliesPolicyNone     = "none"
+)
+
+// List is a Mastodon list. go-mastodon's List is missing the replies policy and exclusive flag.
 type List struct {
-	Name string
+	ID    mastodon.ID `json:"id"`
+	Title string      `json:"title"`
+
+	// which replies are shown. One of the RepliesPolicy constants.
+	RepliesPolicy string `json:"replies_policy"`
+
+	// exclusive lists hide their members' statuses from the home timeline.
+	Exclusive bool `json:"exclusive"`
+}
+
+func (l List) params() url.Values {
+	params := url.Values{}
+	params.Set("title", l.Title)
+	if l.RepliesPolicy != "" {
+		params.Set("replies_policy", l.RepliesPolicy)
+	}
+	params.Set("exclusive", strconv.FormatBool(l.Exclusive))
+	return params
+}
+
+// CreateList creates a new list. Only the title, replies policy and exclusive flag are used.
+func (c *MastodonBackend) CreateList(list List) (*List, error) {
+	var created List
+	err := c.doAPI(c.ctx, http.MethodPost, "/api/v1/lists", list.params(), &created)
+	if err != nil {
+		log.Errorf("unable to create list %s : err %s", list.Title, err)
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateList renames the list and/or changes its replies policy and exclusive flag.
+func (c *MastodonBackend) UpdateList(list List) (*List, error) {
+	var updated List
+	err := c.doAPI(c.ctx, http.MethodPut, fmt.Sprintf("/api/v1/lists/%s", url.PathEscape(string(list.ID))), list.params(), &updated)
+	if err != nil {
+		log.Errorf("unable to update list %s : err %s", list.ID, err)
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *MastodonBackend) DeleteList(id mastodon.ID) error {
+	err := c.client.DeleteList(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to delete list %s : err %s", id, err)
+		return err
+	}
+	return nil
+}
+
+// GetListAccounts gets the members of a list.
+func (c *MastodonBackend) GetListAccounts(id mastodon.ID) ([]*mastodon.Account, error) {
+	accounts, err := c.client.GetListAccounts(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to get accounts for list %s : err %s", id, err)
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetAccountLists gets the lists (of ours) that an account is a member of.
+func (c *MastodonBackend) GetAccountLists(accountID mastodon.ID) ([]*List, error) {
+	var lists []*List
+	err := c.doAPI(c.ctx, http.MethodGet, fmt.Sprintf("/api/v1/accounts/%s/lists", url.PathEscape(string(accountID))), nil, &lists)
+	if err != nil {
+		log.Errorf("unable to get lists for account %s : err %s", accountID, err)
+		return nil, err
+	}
+	return lists, nil
+}
+
+// AddToList adds an account to a list. We have to be following the account.
+func (c *MastodonBackend) AddToList(listID mastodon.ID, accountID mastodon.ID) error {
+	err := c.client.AddToList(c.ctx, listID, accountID)
+	if err != nil {
+		log.Errorf("unable to add account %s to list %s : err %s", accountID, listID, err)
+		return err
+	}
+	return nil
 }
 
-func NewList() *List {
-	l := List{}
-	return &l
+func (c *MastodonBackend) RemoveFromList(listID mastodon.ID, accountID mastodon.ID) error {
+	err := c.client.RemoveFromList(c.ctx, listID, accountID)
+	if err != nil {
+		log.Errorf("unable to remove account %s from list %s : err %s", accountID, listID, err)
+		return err
+	}
+	return nil
 }
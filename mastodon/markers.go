@@ -0,0 +1,137 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// timelines that have read markers.
+const (
+	MarkerHome          = "home"
+	MarkerNotifications = "notifications"
+)
+
+const (
+	// markers are saved at most this often, so scrolling doesn't send a request per status.
+	MarkerSaveDelay = 10 * time.Second
+
+	// how often to get the markers from the server, to pick up reading done on other clients.
+	MarkerRefreshInterval = time.Minute
+)
+
+// Marker is the last status (or notification) read in a timeline. Shared with other clients via the server.
+type Marker struct {
+	LastReadID mastodon.ID `json:"last_read_id"`
+	Version    int64       `json:"version"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// IDNewer returns true if ID a is newer than ID b. IDs are numeric, so longer IDs are newer.
+func IDNewer(a mastodon.ID, b mastodon.ID) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// GetMarker returns the read marker for the timeline (MarkerHome or MarkerNotifications).
+// ok is false until we've tried to get the markers from the server. If there is no marker for the
+// timeline (or the server doesn't support markers), LastReadID is empty.
+func (c *MastodonBackend) GetMarker(timeline string) (Marker, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.markers[timeline], c.markersLoaded
+}
+
+// RefreshMarkers gets the markers from the server. Markers only ever move forward, so a marker
+// older than the one we have (eg. one we haven't saved yet) is ignored.
+func (c *MastodonBackend) RefreshMarkers() error {
+	params := url.Values{}
+	params.Add("timeline[]", MarkerHome)
+	params.Add("timeline[]", MarkerNotifications)
+
+	var markers map[string]Marker
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/markers", params, &markers)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// columns wait for the markers before they start reading, so still count as loaded on error.
+	c.markersLoaded = true
+	if err != nil {
+		log.Errorf("unable to get markers : err %s", err)
+		return err
+	}
+	c.mergeMarkers(markers)
+	return nil
+}
+
+// pollMarkers keeps the markers in step with other clients. Never returns.
+func (c *MastodonBackend) pollMarkers() {
+	for {
+		time.Sleep(MarkerRefreshInterval)
+		c.RefreshMarkers()
+	}
+}
+
+// SetMarker moves the timeline's marker forward to lastReadID. Saving to the server is delayed by
+// MarkerSaveDelay, so only the last of several quick changes is sent.
+func (c *MastodonBackend) SetMarker(timeline string, lastReadID mastodon.ID) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	marker := c.markers[timeline]
+	if !IDNewer(lastReadID, marker.LastReadID) {
+		return nil
+	}
+	marker.LastReadID = lastReadID
+	c.markers[timeline] = marker
+	c.pendingMarkers[timeline] = lastReadID
+
+	if !c.markerSaveScheduled {
+		c.markerSaveScheduled = true
+		time.AfterFunc(MarkerSaveDelay, c.saveMarkers)
+	}
+	return nil
+}
+
+// saveMarkers sends the markers changed since the last save.
+func (c *MastodonBackend) saveMarkers() {
+	c.lock.Lock()
+	params := url.Values{}
+	for timeline, id := range c.pendingMarkers {
+		params.Set(timeline+"[last_read_id]", string(id))
+	}
+	c.pendingMarkers = make(map[string]mastodon.ID)
+	c.markerSaveScheduled = false
+	c.lock.Unlock()
+
+	if len(params) == 0 {
+		return
+	}
+
+	var markers map[string]Marker
+	err := c.doAPI(BackgroundContext(c.ctx), http.MethodPost, "/api/v1/markers", params, &markers)
+	if err != nil {
+		// most likely a conflict with another client saving at the same time. The next refresh will sort it out.
+		log.Errorf("unable to save markers : err %s", err)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.mergeMarkers(markers)
+}
+
+// mergeMarkers keeps whichever of our marker and the server's is newest. Caller must hold the lock.
+func (c *MastodonBackend) mergeMarkers(markers map[string]Marker) {
+	for timeline, m := range markers {
+		existing, ok := c.markers[timeline]
+		if !ok || !IDNewer(existing.LastReadID, m.LastReadID) {
+			c.markers[timeline] = m
+		}
+	}
+}
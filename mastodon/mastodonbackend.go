@@ -9,9 +9,9 @@ import (
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"math/rand"
+	"net/http"
 	"net/url"
-	"slices"
-	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -33,7 +33,6 @@ var (
 
 	scopes      = []string{"read", "write", "follow"}
 	instanceURL = "hachyderm.io"
-	AccountID   mastodon.ID
 )
 
 func StringWithCharset(length int, charset string) string {
@@ -57,16 +56,51 @@ type Notification struct {
 }
 
 type MastodonBackend struct {
-	app    *mastodon.Application
 	client *mastodon.Client
 
+	// every request goes through here, so the instance's rate limit is respected.
+	transport *RateLimitTransport
+
+	// account we're logged in with.
+	accountID mastodon.ID
+
 	// cache of messages.
 	// key is timeline name
 	timelineMessageCache *TimelineCache
 
 	// keep local copy of notifications. These are specialised enough that they
 	// wont need to be keeped in the cache (I hope)
-	notifications []Notification
+	// Keyed by timelineID, since notification columns can be filtered by type.
+	notifications map[string][]Notification
+
+	// direct message conversations, most recently active first.
+	conversations []Conversation
+
+	// trending tags and links. Trending statuses live in the timeline cache.
+	trends Trends
+
+	// v2 filters for the account.
+	filters []Filter
+
+	// accounts waiting for us to accept their follow request (if our account is locked).
+	followRequests []*mastodon.Account
+
+	// timelines and notifications are persisted here, so they can be displayed straight away on
+	// the next startup. nil until logged in.
+	store *Store
+
+	// posts, favourites and boosts waiting to be sent, oldest first. online is false if the
+	// instance was unreachable the last time we tried it.
+	outbox       []OutboxItem
+	nextOutboxID int64
+	online       bool
+
+	// read markers for home and notifications, keyed by MarkerHome/MarkerNotifications.
+	// Changes waiting to be saved are in pendingMarkers.
+	markers             map[string]Marker
+	markersLoaded       bool
+	pendingMarkers      map[string]mastodon.ID
+	markerSaveScheduled bool
 
 	eventListener *events.EventListener
 	lock          sync.RWMutex
@@ -78,33 +112,45 @@ type MastodonBackend struct {
 	// lastRefreshed (was stored in cache... but try local copy)
 	lastRefreshed map[string]time.Time
 
+	// for timelines paged via the Link header (bookmarks, favourites, conversations), the max ID of the next (older) page.
+	nextPage map[string]mastodon.ID
+
 	config *config.Config
 
+	// login details for this backend's account. Lives inside config.
+	account *config.AccountConfig
+
 	// used when we're investigating a specific user.
 	userInfo         *mastodon.Account
 	userRelationship *mastodon.Relationship
 
+	// streaming connections, keyed by timelineID
+	streams map[string]*timelineStream
+
+	// base URL of the streaming API if the instance hosts it separately.
+	streamingBaseURL string
+
+	// limits for creating polls on this instance.
+	pollLimits PollLimits
+
 	ctx context.Context
 }
 
-func NewMastodonBackend(eventListener *events.EventListener, config *config.Config) (*MastodonBackend, error) {
+// NewMastodonBackend creates a backend for a single account. Multiple accounts means multiple backends.
+func NewMastodonBackend(eventListener *events.EventListener, config *config.Config, account *config.AccountConfig) (*MastodonBackend, error) {
 	c := MastodonBackend{}
-	appConfig := &mastodon.AppConfig{
-		Server:       "https://hachyderm.io",
-		ClientName:   "shipdon",
-		Scopes:       "read write follow",
-		Website:      "https://github.com/kpfaulkner/shipdon",
-		RedirectURIs: "urn:ietf:wg:oauth:2.0:oob",
-	}
-	app, err := mastodon.RegisterApp(context.Background(), appConfig)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	c.app = app
 
 	c.listDetails = make(map[string]mastodon.List)
 	c.lastRefreshed = make(map[string]time.Time)
+	c.nextPage = make(map[string]mastodon.ID)
+	c.notifications = make(map[string][]Notification)
+	c.markers = make(map[string]Marker)
+	c.pendingMarkers = make(map[string]mastodon.ID)
+	c.streams = make(map[string]*timelineStream)
+	c.pollLimits = DefaultPollLimits
+	c.online = true
+	c.transport = NewRateLimitTransport(nil)
+	c.ctx = context.Background()
 
 	c.timelineMessageCache = NewTimelineCache()
 	c.eventListener = eventListener
@@ -112,6 +158,7 @@ func NewMastodonBackend(eventListener *events.EventListener, config *config.Conf
 	go c.timelineMessageCache.LogCacheDetails()
 	c.eventListener.RegisterReceiver(events.REFRESH_MESSAGES, c.RefreshMessagesCallback)
 	c.config = config
+	c.account = account
 	return &c, nil
 }
 
@@ -120,29 +167,31 @@ func NewMastodonBackend(eventListener *events.EventListener, config *config.Conf
 // TODO(kpfaulkner) secure password in SOME fashion.
 func (c *MastodonBackend) LoginWithPassword(username string, password string) error {
 
-	if c.config.InstanceURL == "" {
+	if c.account.InstanceURL == "" {
 		return errors.New("missing config data")
 	}
 
 	app, err := mastodon.RegisterApp(context.Background(), &mastodon.AppConfig{
-		Server:     c.config.InstanceURL,
+		Server:     c.account.InstanceURL,
 		ClientName: "shipdon",
 		Scopes:     "read write follow",
 		Website:    "https://github.com/kpfaulkner/shipdon",
 	})
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to register app with %s : err %s", c.account.InstanceURL, err)
+		return err
 	}
 
-	client := mastodon.NewClient(&mastodon.Config{
-		Server:       c.config.InstanceURL,
+	client := c.newClient(&mastodon.Config{
+		Server:       c.account.InstanceURL,
 		ClientID:     app.ClientID,
 		ClientSecret: app.ClientSecret,
 	})
 
 	err = client.Authenticate(context.Background(), username, password)
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to authenticate %s : err %s", username, err)
+		return err
 	}
 
 	c.client = client
@@ -150,33 +199,77 @@ func (c *MastodonBackend) LoginWithPassword(username string, password string) er
 	return nil
 }
 
+// newClient creates a Mastodon client that sends its requests through the rate limited transport.
+func (c *MastodonBackend) newClient(cfg *mastodon.Config) *mastodon.Client {
+	client := mastodon.NewClient(cfg)
+	client.Client.Transport = idempotencyTransport{next: c.transport}
+	return client
+}
+
+// RateLimit is what's left of the instance's rate limit.
+func (c *MastodonBackend) RateLimit() RateLimitBudget {
+	return c.transport.Budget()
+}
+
 // LoginWithOAuth2 login to Mastodon using OAuth2
 // Can only log in if the config file has appID, appSecret, instance and Token info
 func (c *MastodonBackend) LoginWithOAuth2() error {
-	if c.config.ClientID == "" || c.config.ClientSecret == "" || c.config.InstanceURL == "" || c.config.Token == "" {
+	if c.account.ClientID == "" || c.account.ClientSecret == "" || c.account.InstanceURL == "" || c.account.Token == "" {
 		return errors.New("missing config data")
 	}
 
 	cfg := &mastodon.Config{
-		Server:       c.config.InstanceURL,
-		ClientID:     c.config.ClientID,
-		ClientSecret: c.config.ClientSecret,
-		AccessToken:  c.config.Token,
+		Server:       c.account.InstanceURL,
+		ClientID:     c.account.ClientID,
+		ClientSecret: c.account.ClientSecret,
+		AccessToken:  c.account.Token,
 	}
 
-	c.client = mastodon.NewClient(cfg)
+	c.client = c.newClient(cfg)
 	c.ctx = context.Background()
 
 	acct, err := c.client.GetAccountCurrentUser(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to get current user : err %s", err)
+		return err
 	}
 
-	// keep track of account we're logged in with. Yes, global, yucky, but will do for now.
-	AccountID = acct.ID
+	c.setAccount(acct)
+	c.openStore()
+	c.lookupInstanceDetails()
+	c.RefreshFilters()
+	c.RefreshMarkers()
+	go c.pollMarkers()
+	go c.runOutbox()
 	return nil
 }
 
+// setAccount records which account we've logged in as. The account name is saved in the config
+// so columns etc can be tied back to the account.
+func (c *MastodonBackend) setAccount(acct *mastodon.Account) {
+	c.accountID = acct.ID
+
+	name := acct.Acct
+	if u, err := url.Parse(c.account.InstanceURL); err == nil && u.Host != "" && !strings.Contains(name, "@") {
+		name = name + "@" + u.Host
+	}
+
+	if c.account.Name != name {
+		c.account.Name = name
+		c.writeConfigToFile()
+	}
+}
+
+// AccountID is the ID of the account this backend is logged in with.
+func (c *MastodonBackend) AccountID() mastodon.ID {
+	return c.accountID
+}
+
+// AccountName is the full name (user@instance) of the account this backend is logged in with.
+func (c *MastodonBackend) AccountName() string {
+	return c.account.Name
+}
+
 // Logoff from Mastodon
 func (c *MastodonBackend) Logoff() error {
 	return nil
@@ -212,7 +305,7 @@ func (c *MastodonBackend) Search(query string) (*mastodon.Results, error) {
 	for _, s := range results.Statuses {
 		nonPtrStatuses = append(nonPtrStatuses, *s)
 	}
-	err = c.timelineMessageCache.AddToTimeline("search", true, nonPtrStatuses, true)
+	_, err = c.timelineMessageCache.AddToTimeline("search", true, nonPtrStatuses, true)
 	if err != nil {
 		log.Errorf("unable to add statuses to timelineID %s : err %s", "search", err)
 		return nil, err
@@ -245,58 +338,157 @@ func (c *MastodonBackend) ChangeFollowStatusForUserID(userID mastodon.ID, follow
 	return nil
 }
 
-// just grab notifications
-func (c *MastodonBackend) GetNotifications() ([]*mastodon.Notification, error) {
-	notifications := []*mastodon.Notification{}
+// Favourite a toot. If the instance is unreachable, the favourite is queued in the outbox.
+func (c *MastodonBackend) SetFavourite(id mastodon.ID, fav bool) error {
+	err := c.sendOrQueue(OutboxItem{Kind: OutboxFavourite, StatusID: id, On: fav})
+	if err != nil {
+		return err
+	}
+
+	// set local cache?
+	if s, ok := c.timelineMessageCache.GetStatus(id); ok {
+		s.Favourited = fav
+		c.timelineMessageCache.UpdateStatus(s)
+	}
+
+	return nil
+}
 
-	for _, n := range c.notifications {
-		notification := &mastodon.Notification{
-			ID:        n.ID,
-			Type:      n.Type,
-			CreatedAt: n.CreatedAt,
-			Account:   n.Account,
+func (c *MastodonBackend) setFavourite(ctx context.Context, id mastodon.ID, fav bool) error {
+	if fav {
+		_, err := c.client.Favourite(ctx, id)
+		if err != nil {
+			log.Errorf("unable to favourite toot %s : err %s", id, err)
+			return err
 		}
-		if n.StatusID != "" {
-			status := c.timelineMessageCache.messageCache[n.StatusID]
-			notification.Status = &status
+	} else {
+		_, err := c.client.Unfavourite(ctx, id)
+		if err != nil {
+			log.Errorf("unable to unfavourite toot %s : err %s", id, err)
+			return err
 		}
-		notifications = append(notifications, notification)
 	}
-	return notifications, nil
+	return nil
 }
 
-// Favourite a toot
-func (c *MastodonBackend) SetFavourite(id mastodon.ID, fav bool) error {
-
-	if fav {
-		_, err := c.client.Favourite(c.ctx, id)
+// SetBookmark bookmarks or unbookmarks a toot
+func (c *MastodonBackend) SetBookmark(id mastodon.ID, bookmark bool) error {
+	var status *mastodon.Status
+	var err error
+	if bookmark {
+		status, err = c.client.Bookmark(c.ctx, id)
 		if err != nil {
-			log.Errorf("unable to favourite toot %d : err %s", id, err)
+			log.Errorf("unable to bookmark toot %s : err %s", id, err)
 			return err
 		}
 	} else {
-		_, err := c.client.Unfavourite(c.ctx, id)
+		status, err = c.client.Unbookmark(c.ctx, id)
 		if err != nil {
-			log.Errorf("unable to unfavourite toot %d : err %s", id, err)
+			log.Errorf("unable to unbookmark toot %s : err %s", id, err)
 			return err
 		}
 	}
 
-	// set local cache?
-	if s, ok := c.timelineMessageCache.messageCache[id]; ok {
-		s.Favourited = fav
-		c.timelineMessageCache.messageCache[id] = s
+	return c.timelineMessageCache.UpdateStatus(*status)
+}
+
+// GetStatusSource gets the plain text of one of our statuses, so it can be edited.
+func (c *MastodonBackend) GetStatusSource(id mastodon.ID) (*mastodon.Source, error) {
+	source, err := c.client.GetStatusSource(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to get source for status %s : err %s", id, err)
+		return nil, err
 	}
+	return source, nil
+}
 
-	return nil
+// EditStatus replaces the content of one of our statuses. The cached copy is updated in place.
+func (c *MastodonBackend) EditStatus(id mastodon.ID, toot *mastodon.Toot) error {
+	if toot.Poll != nil {
+		if err := ValidatePoll(toot.Poll, c.PollLimits()); err != nil {
+			log.Errorf("invalid poll %v", err)
+			return err
+		}
+	}
+
+	status, err := c.client.UpdateStatus(c.ctx, toot, id)
+	if err != nil {
+		log.Errorf("unable to edit status %s : err %s", id, err)
+		return err
+	}
+
+	return c.timelineMessageCache.UpdateStatus(*status)
 }
 
-// Post new message to Mastodon
-func (c *MastodonBackend) Post(msg string, replyStatusID mastodon.ID) error {
-	status, err := c.client.PostStatus(c.ctx, &mastodon.Toot{
-		Status:      msg,
-		InReplyToID: replyStatusID,
-	})
+// DeleteStatus deletes one of our statuses, removing it (and any boosts of it) from every timeline.
+func (c *MastodonBackend) DeleteStatus(id mastodon.ID) error {
+	err := c.client.DeleteStatus(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to delete status %s : err %s", id, err)
+		return err
+	}
+
+	return c.timelineMessageCache.DeleteStatus(id)
+}
+
+// VotePoll votes in the poll attached to the status. choices are indexes into the poll options.
+func (c *MastodonBackend) VotePoll(statusID mastodon.ID, choices []int) error {
+	status, ok := c.timelineMessageCache.GetStatus(statusID)
+	if !ok {
+		return fmt.Errorf("unknown status %s", statusID)
+	}
+
+	status, err := pollStatus(status)
+	if err != nil {
+		return err
+	}
+
+	poll, err := c.client.PollVote(c.ctx, status.Poll.ID, choices...)
+	if err != nil {
+		log.Errorf("unable to vote in poll %s : err %s", status.Poll.ID, err)
+		return err
+	}
+
+	status.Poll = poll
+	return c.timelineMessageCache.UpdateStatus(status)
+}
+
+// RefreshPoll gets the latest results for the poll attached to the status.
+func (c *MastodonBackend) RefreshPoll(statusID mastodon.ID) error {
+	status, ok := c.timelineMessageCache.GetStatus(statusID)
+	if !ok {
+		return fmt.Errorf("unknown status %s", statusID)
+	}
+
+	status, err := pollStatus(status)
+	if err != nil {
+		return err
+	}
+
+	poll, err := c.client.GetPoll(c.ctx, status.Poll.ID)
+	if err != nil {
+		log.Errorf("unable to get poll %s : err %s", status.Poll.ID, err)
+		return err
+	}
+
+	status.Poll = poll
+	return c.timelineMessageCache.UpdateStatus(status)
+}
+
+// Post new message to Mastodon. If the instance is unreachable, the toot is queued in the outbox.
+func (c *MastodonBackend) Post(toot *mastodon.Toot) error {
+	if toot.Poll != nil {
+		if err := ValidatePoll(toot.Poll, c.PollLimits()); err != nil {
+			log.Errorf("invalid poll %v", err)
+			return err
+		}
+	}
+
+	return c.sendOrQueue(OutboxItem{Kind: OutboxPost, Toot: toot})
+}
+
+func (c *MastodonBackend) post(ctx context.Context, toot *mastodon.Toot) error {
+	status, err := c.client.PostStatus(ctx, toot)
 	if err != nil {
 		log.Errorf("unable to post toot %v", err)
 		return err
@@ -306,10 +498,49 @@ func (c *MastodonBackend) Post(msg string, replyStatusID mastodon.ID) error {
 	return nil
 }
 
+// UploadMedia uploads an attachment to be used in a later Post. The server may still be
+// processing the media when this returns (URL will be empty), GetMedia can be used to check on it.
+// Uploads happen in the background and large files can take a while, so there's no timeout.
+func (c *MastodonBackend) UploadMedia(fileName string, data []byte) (*mastodon.Attachment, error) {
+	var attachment mastodon.Attachment
+	err := c.doMultipartAPI(BackgroundContext(c.ctx), http.MethodPost, "/api/v2/media", fileName, data, nil, &attachment)
+	if err != nil {
+		log.Errorf("unable to upload media %s : err %s", fileName, err)
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// GetMedia gets the current state of uploaded media. URL is empty while the server is still processing it.
+func (c *MastodonBackend) GetMedia(id mastodon.ID) (*mastodon.Attachment, error) {
+	var attachment mastodon.Attachment
+	err := c.doAPI(c.ctx, http.MethodGet, fmt.Sprintf("/api/v1/media/%s", url.PathEscape(string(id))), nil, &attachment)
+	if err != nil {
+		log.Errorf("unable to get media %s : err %s", id, err)
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// UpdateMedia sets the alt text and focal point of uploaded media. Focal point x and y are between -1.0 and 1.0
+func (c *MastodonBackend) UpdateMedia(id mastodon.ID, description string, focusX float64, focusY float64) error {
+	params := url.Values{}
+	params.Set("description", description)
+	params.Set("focus", fmt.Sprintf("%.2f,%.2f", focusX, focusY))
+	err := c.doAPI(c.ctx, http.MethodPut, fmt.Sprintf("/api/v1/media/%s", url.PathEscape(string(id))), params, nil)
+	if err != nil {
+		log.Errorf("unable to update media %s : err %s", id, err)
+		return err
+	}
+	return nil
+}
+
 // GetLists get all the lists that we're subscribed to.
-func (c *MastodonBackend) GetLists() ([]*mastodon.List, error) {
+func (c *MastodonBackend) GetLists() ([]*List, error) {
 
-	lists, err := c.client.GetLists(c.ctx)
+	// go-mastodon's GetLists doesn't give us the replies policy or exclusive flag.
+	var lists []*List
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/lists", nil, &lists)
 	if err != nil {
 		log.Errorf("unable to get lists for accounterr %s", err)
 		return nil, err
@@ -326,6 +557,11 @@ func (c *MastodonBackend) GetLists() ([]*mastodon.List, error) {
 func (c *MastodonBackend) RefreshMessagesCallback(e events.Event) error {
 	re := e.(events.RefreshEvent)
 
+	// for one of the other accounts.
+	if re.Account != c.AccountName() {
+		return nil
+	}
+
 	timelineID := re.TimelineID
 
 	var statuses []*mastodon.Status
@@ -343,6 +579,7 @@ func (c *MastodonBackend) RefreshMessagesCallback(e events.Event) error {
 	}
 
 	c.lastRefreshed[timelineID] = time.Now()
+	c.timelineMessageCache.SetTimelineContext(timelineID, FilterContextForRefreshType(re.RefreshType))
 
 	details, _ = c.timelineMessageCache.GetTimelineDetails(timelineID)
 
@@ -350,72 +587,62 @@ func (c *MastodonBackend) RefreshMessagesCallback(e events.Event) error {
 
 	// if we're getting older statuses, then we need to get the statuses before the oldest one we have.
 	if len(details.messages) > 0 && re.GetOlder {
-		params.MaxID = details.messages[len(details.messages)-1]
+		params.MaxID = oldestID(details.messages)
 	} else {
-		// regular get newer then prepend to existing statuses
-		if !re.ClearExisting && len(details.messages) > 0 {
-			params.SinceID = details.messages[0]
+		// regular get newer then prepend to existing statuses. Paged up from the newest we have
+		// so nothing is skipped, if there are more than a page a gap is left to load later.
+		if !re.ClearExisting {
+			params.MinID = newestID(details.messages)
 		}
 	}
 
 	// when added to cache, should it be sorted.
 	shouldSort := true
 
+	// status the gap (if any) is above.
+	var belowGap mastodon.ID
+
 	switch re.RefreshType {
-	case events.HASHTAG_REFRESH:
-		statuses, err = c.client.GetTimelineHashtag(context.Background(), timelineID, false, &params)
-		if err != nil {
-			log.Errorf("unable to get timelineID %s : err %s", timelineID, err)
-			return nil
-		}
-	case events.LIST_REFRESH:
-		statuses, err = c.client.GetTimelineList(context.Background(), mastodon.ID(timelineID), &params)
+	case events.HASHTAG_REFRESH, events.LIST_REFRESH, events.HOME_REFRESH, events.LOCAL_REFRESH, events.FEDERATED_REFRESH:
+		statuses, err = c.getTimelinePage(re.RefreshType, timelineID, params)
 		if err != nil {
-			log.Errorf("unable to get timelineID %s : err %s", timelineID, err)
 			return nil
 		}
-	case events.HOME_REFRESH:
-		statuses, err = c.client.GetTimelineHome(context.Background(), &params)
-		if err != nil {
-			log.Errorf("unable to get timelineID %s : err %s", timelineID, err)
-			return nil
+		if params.MinID != "" {
+			statuses, belowGap = c.detectGap(re.RefreshType, timelineID, statuses)
+		} else if re.ClearExisting {
+			belowGap = latestPageGap(details.messages, statuses)
+			re.ClearExisting = false
 		}
 	case events.NOTIFICATION_REFRESH:
-		notifications, err := c.client.GetNotifications(context.Background(), &params)
-		if err != nil {
-			log.Errorf("unable to get notifications : err %s", err)
-			return nil
-		}
+		c.refreshNotifications(timelineID, re.GetOlder, re.ClearExisting)
+		return nil
 
-		if re.ClearExisting {
-			c.notifications = []Notification{}
+	case events.EXPLORE_REFRESH:
+		// trends aren't paged, so there's nothing older.
+		if !re.GetOlder {
+			c.refreshTrends(timelineID)
 		}
-		for _, n := range notifications {
-			notification := Notification{
-				ID:        n.ID,
-				Type:      n.Type,
-				CreatedAt: n.CreatedAt,
-				Account:   n.Account,
-			}
+		return nil
 
-			if n.Status != nil {
-				notification.StatusID = n.Status.ID
-				c.timelineMessageCache.AddToMessageCache([]mastodon.Status{*n.Status})
-			} else {
-				notification.StatusID = ""
-			}
+	case events.CONVERSATIONS_REFRESH:
+		c.refreshConversations(timelineID, re.GetOlder, re.ClearExisting)
+		return nil
 
-			c.notifications = append(c.notifications, notification)
-		}
-		sort.Slice(c.notifications, func(i, j int) bool {
-			return c.notifications[i].CreatedAt.After(c.notifications[j].CreatedAt)
-		})
+	case events.FOLLOW_REQUESTS_REFRESH:
+		c.refreshFollowRequests(timelineID, re.GetOlder, re.ClearExisting)
 		return nil
 
 	case events.USER_REFRESH:
 		c.userInfo = nil
 		c.userRelationship = nil
-		statuses, err = c.client.GetAccountStatuses(context.Background(), mastodon.ID(re.TimelineID), &params)
+		statuses, _ = c.getTimelinePage(re.RefreshType, timelineID, params)
+		if params.MinID != "" {
+			statuses, belowGap = c.detectGap(re.RefreshType, timelineID, statuses)
+		} else if re.ClearExisting {
+			belowGap = latestPageGap(details.messages, statuses)
+			re.ClearExisting = false
+		}
 		account, err := c.client.GetAccount(context.Background(), mastodon.ID(re.TimelineID))
 		if err != nil {
 			log.Errorf("unable to get accountID %s : err %s", re.TimelineID, err)
@@ -424,43 +651,91 @@ func (c *MastodonBackend) RefreshMessagesCallback(e events.Event) error {
 		}
 		err = c.RefreshUserRelationship()
 		if err != nil {
-			log.Errorf("unable to get relationship for userid %s :  %s : err %s", re.TimelineID, err)
+			log.Errorf("unable to get relationship for userid %s : err %s", re.TimelineID, err)
 		}
 
-	case events.THREAD_REFRESH:
-		done := false
-		statusID := mastodon.ID(re.TimelineID)
-		for !done {
-			status, err := c.client.GetStatus(context.Background(), statusID)
-			if err != nil {
-				log.Errorf("unable to get statusID %s : err %s", timelineID, err)
-				continue
+	case events.BOOKMARKS_REFRESH, events.FAVOURITES_REFRESH:
+		statuses, err = c.getPagedTimeline(re.RefreshType, timelineID, re.GetOlder, re.ClearExisting)
+		if err != nil {
+			return nil
+		}
+
+		// kept in the order the server gives us. The newest page is merged in above what we
+		// already have, so polling keeps the older pages.
+		shouldSort = false
+		if !re.GetOlder && !re.ClearExisting {
+			var nonPtrStatus []mastodon.Status
+			for _, s := range statuses {
+				nonPtrStatus = append(nonPtrStatus, *s)
 			}
-			statuses = append(statuses, status)
-			if status.InReplyToID != nil {
-				s := status.InReplyToID.(string)
-				statusID = mastodon.ID(s)
-			} else {
-				done = true
+			if err := c.timelineMessageCache.MergeIntoTimeline(timelineID, nonPtrStatus); err != nil {
+				log.Errorf("unable to merge statuses into timelineID %s : err %s", timelineID, err)
+				return err
 			}
+			return nil
+		}
+
+	case events.THREAD_REFRESH:
+		// get the whole thread in one go, so there is never anything older to get.
+		if re.GetOlder {
+			return nil
+		}
+
+		statuses, err = c.getThread(mastodon.ID(timelineID))
+		if err != nil {
+			return nil
 		}
-		slices.Reverse(statuses)
+
+		// thread is in tree order, and always replaces what we had.
 		shouldSort = false
+		re.ClearExisting = true
 	}
 
 	var nonPtrStatus []mastodon.Status
 	for _, s := range statuses {
 		nonPtrStatus = append(nonPtrStatus, *s)
 	}
-	err = c.timelineMessageCache.AddToTimeline(timelineID, re.ClearExisting, nonPtrStatus, shouldSort)
+	added, err := c.timelineMessageCache.AddToTimeline(timelineID, re.ClearExisting, nonPtrStatus, shouldSort)
 	if err != nil {
 		log.Errorf("unable to add statuses to timelineID %s : err %s", timelineID, err)
 		return err
 	}
 
+	if added && belowGap != "" {
+		c.timelineMessageCache.AddGap(timelineID, belowGap)
+	}
 	return nil
 }
 
+// getTimelinePage gets a page of statuses from a timeline that can be paged with max_id/min_id
+// (home, lists, hashtags, local/federated and users). The API is called directly so the server's
+// filter results are kept.
+func (c *MastodonBackend) getTimelinePage(refreshType events.RefreshType, timelineID string, params mastodon.Pagination) ([]*mastodon.Status, error) {
+	var statuses []*mastodon.Status
+	var err error
+
+	switch refreshType {
+	case events.HASHTAG_REFRESH:
+		statuses, err = c.getStatuses("/api/v1/timelines/tag/"+timelineID, paginationParams(url.Values{}, params))
+	case events.LIST_REFRESH:
+		statuses, err = c.getStatuses("/api/v1/timelines/list/"+timelineID, paginationParams(url.Values{}, params))
+	case events.HOME_REFRESH:
+		statuses, err = c.getStatuses("/api/v1/timelines/home", paginationParams(url.Values{}, params))
+	case events.LOCAL_REFRESH, events.FEDERATED_REFRESH:
+		return c.getPublicTimeline(ParsePublicTimeline(timelineID), params)
+	case events.USER_REFRESH:
+		statuses, err = c.getStatuses("/api/v1/accounts/"+timelineID+"/statuses", paginationParams(url.Values{}, params))
+	default:
+		err = fmt.Errorf("timelineID %s can't be paged", timelineID)
+	}
+
+	if err != nil {
+		log.Errorf("unable to get timelineID %s : err %s", timelineID, err)
+		return nil, err
+	}
+	return statuses, nil
+}
+
 // GetUserDetails is NOT the current user, but the user we've investigating (ie getting profile of).
 func (c *MastodonBackend) GetUserDetails() (*mastodon.Account, *mastodon.Relationship) {
 	return c.userInfo, c.userRelationship
@@ -470,34 +745,42 @@ func (c *MastodonBackend) RefreshUserRelationship() error {
 	// based off c.userInfo, refresh the userdetails/relationship\
 	relationship, err := c.client.GetAccountRelationships(context.Background(), []string{string(c.userInfo.ID)})
 	if err != nil {
-		log.Errorf("unable to get relationship for userid %s :  %s : err %s", c.userInfo.ID, err)
+		log.Errorf("unable to get relationship for userid %s : err %s", c.userInfo.ID, err)
 	}
 	c.userRelationship = relationship[0]
 	return nil
 }
 
-// Boost or unboost a toot
+// Boost or unboost a toot. If the instance is unreachable, the boost is queued in the outbox.
 func (c *MastodonBackend) Boost(id mastodon.ID, boost bool) error {
+	err := c.sendOrQueue(OutboxItem{Kind: OutboxBoost, StatusID: id, On: boost})
+	if err != nil {
+		return err
+	}
+
+	// set local cache?
+	if s, ok := c.timelineMessageCache.GetStatus(id); ok {
+		s.Reblogged = boost
+		c.timelineMessageCache.UpdateStatus(s)
+	}
+
+	return nil
+}
+
+func (c *MastodonBackend) boost(ctx context.Context, id mastodon.ID, boost bool) error {
 	if boost {
-		_, err := c.client.Reblog(context.Background(), id)
+		_, err := c.client.Reblog(ctx, id)
 		if err != nil {
-			log.Errorf("unable to boost toot %d : err %s", id, err)
+			log.Errorf("unable to boost toot %s : err %s", id, err)
 			return err
 		}
 	} else {
-		_, err := c.client.Unreblog(context.Background(), id)
+		_, err := c.client.Unreblog(ctx, id)
 		if err != nil {
-			log.Errorf("unable to boost toot %d : err %s", id, err)
+			log.Errorf("unable to boost toot %s : err %s", id, err)
 			return err
 		}
 	}
-
-	// set local cache?
-	if s, ok := c.timelineMessageCache.messageCache[id]; ok {
-		s.Reblogged = boost
-		c.timelineMessageCache.messageCache[id] = s
-	}
-
 	return nil
 }
 
@@ -518,18 +801,20 @@ func (c *MastodonBackend) GenerateOAuthLoginURL(instanceURL string) (string, err
 	}
 	app, err := mastodon.RegisterApp(context.Background(), appConfig)
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to register app with %s : err %s", instanceURL, err)
+		return "", err
 	}
 
-	fmt.Printf("clientID %+v\n", app.ClientID)
-	c.config.ClientID = app.ClientID
-	c.config.ClientSecret = app.ClientSecret
-	c.config.InstanceURL = instanceURL
+	log.Debugf("clientID %+v", app.ClientID)
+	c.account.ClientID = app.ClientID
+	c.account.ClientSecret = app.ClientSecret
+	c.account.InstanceURL = instanceURL
 
 	// Have the user manually get the token and send it back to us
 	u, err := url.Parse(app.AuthURI)
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to parse auth URI %s : err %s", app.AuthURI, err)
+		return "", err
 	}
 
 	return u.String(), nil
@@ -539,30 +824,39 @@ func (c *MastodonBackend) GenerateOAuthLoginURL(instanceURL string) (string, err
 func (c *MastodonBackend) GenerateConfigWithCode(code string) error {
 
 	cfg := &mastodon.Config{
-		Server:       c.config.InstanceURL,
-		ClientID:     c.config.ClientID,
-		ClientSecret: c.config.ClientSecret,
+		Server:       c.account.InstanceURL,
+		ClientID:     c.account.ClientID,
+		ClientSecret: c.account.ClientSecret,
 		AccessToken:  code,
 	}
 
-	c.client = mastodon.NewClient(cfg)
+	c.client = c.newClient(cfg)
 	err := c.client.AuthenticateToken(context.Background(), code, "urn:ietf:wg:oauth:2.0:oob")
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to authenticate with code : err %s", err)
+		return err
 	}
 
-	// save to disk.
-	c.config.Token = cfg.AccessToken
-	c.writeConfigToFile()
-
+	c.account.Token = cfg.AccessToken
 	c.ctx = context.Background()
 
 	acct, err := c.client.GetAccountCurrentUser(context.Background())
 	if err != nil {
-		log.Fatal(err)
+		log.Errorf("unable to get current user : err %s", err)
+		return err
 	}
 
-	fmt.Printf("Account is %v\n", acct)
+	log.Debugf("account is %v", acct.Acct)
+
+	// save to disk (setAccount fills in the account name).
+	c.setAccount(acct)
+	c.openStore()
+
+	c.lookupInstanceDetails()
+	c.RefreshFilters()
+	c.RefreshMarkers()
+	go c.pollMarkers()
+	go c.runOutbox()
 
 	return nil
 }
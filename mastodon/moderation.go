@@ -0,0 +1,182 @@
+package mastodon
+
+import (
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// moderationPageLimit is the page size used when getting mutes and blocks. The server max is 80.
+const moderationPageLimit = 80
+
+// AccountDomain is the instance a remote account is on. Empty for accounts on our own instance.
+func AccountDomain(account mastodon.Account) string {
+	if _, domain, ok := strings.Cut(account.Acct, "@"); ok {
+		return domain
+	}
+	return ""
+}
+
+// statusByAccount returns a matcher for statuses written or boosted by the account.
+func statusByAccount(accountID mastodon.ID) func(mastodon.Status) bool {
+	return func(s mastodon.Status) bool {
+		return s.Account.ID == accountID || (s.Reblog != nil && s.Reblog.Account.ID == accountID)
+	}
+}
+
+// statusFromDomain returns a matcher for statuses written or boosted by accounts on the domain.
+func statusFromDomain(domain string) func(mastodon.Status) bool {
+	return func(s mastodon.Status) bool {
+		return AccountDomain(s.Account) == domain || (s.Reblog != nil && AccountDomain(s.Reblog.Account) == domain)
+	}
+}
+
+// MuteAccount hides the account's statuses (and optionally notifications). A duration of 0 mutes indefinitely.
+func (c *MastodonBackend) MuteAccount(id mastodon.ID, notifications bool, duration time.Duration) error {
+	params := url.Values{}
+	params.Set("notifications", strconv.FormatBool(notifications))
+	params.Set("duration", strconv.Itoa(int(duration.Seconds())))
+
+	err := c.doAPI(c.ctx, http.MethodPost, fmt.Sprintf("/api/v1/accounts/%s/mute", url.PathEscape(string(id))), params, nil)
+	if err != nil {
+		log.Errorf("unable to mute account %s : err %s", id, err)
+		return err
+	}
+	c.purge(statusByAccount(id), func(n Notification) bool { return notifications && n.Account.ID == id })
+	return nil
+}
+
+func (c *MastodonBackend) UnmuteAccount(id mastodon.ID) error {
+	_, err := c.client.AccountUnmute(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to unmute account %s : err %s", id, err)
+		return err
+	}
+	return nil
+}
+
+// BlockAccount blocks the account, which also unfollows it.
+func (c *MastodonBackend) BlockAccount(id mastodon.ID) error {
+	_, err := c.client.AccountBlock(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to block account %s : err %s", id, err)
+		return err
+	}
+	c.purge(statusByAccount(id), func(n Notification) bool { return n.Account.ID == id })
+	return nil
+}
+
+func (c *MastodonBackend) UnblockAccount(id mastodon.ID) error {
+	_, err := c.client.AccountUnblock(c.ctx, id)
+	if err != nil {
+		log.Errorf("unable to unblock account %s : err %s", id, err)
+		return err
+	}
+	return nil
+}
+
+// BlockDomain hides everything from the domain and removes any followers we have there.
+func (c *MastodonBackend) BlockDomain(domain string) error {
+	params := url.Values{}
+	params.Set("domain", domain)
+	err := c.doAPI(c.ctx, http.MethodPost, "/api/v1/domain_blocks", params, nil)
+	if err != nil {
+		log.Errorf("unable to block domain %s : err %s", domain, err)
+		return err
+	}
+	c.purge(statusFromDomain(domain), func(n Notification) bool { return AccountDomain(n.Account) == domain })
+	return nil
+}
+
+func (c *MastodonBackend) UnblockDomain(domain string) error {
+	params := url.Values{}
+	params.Set("domain", domain)
+	err := c.doAPI(c.ctx, http.MethodDelete, "/api/v1/domain_blocks", params, nil)
+	if err != nil {
+		log.Errorf("unable to unblock domain %s : err %s", domain, err)
+		return err
+	}
+	return nil
+}
+
+// GetMutes returns every account we've muted.
+func (c *MastodonBackend) GetMutes() ([]*mastodon.Account, error) {
+	accounts, err := allAccountPages(func(pg *mastodon.Pagination) ([]*mastodon.Account, error) {
+		return c.client.GetMutes(c.ctx, pg)
+	})
+	if err != nil {
+		log.Errorf("unable to get mutes : err %s", err)
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// GetBlocks returns every account we've blocked.
+func (c *MastodonBackend) GetBlocks() ([]*mastodon.Account, error) {
+	accounts, err := allAccountPages(func(pg *mastodon.Pagination) ([]*mastodon.Account, error) {
+		return c.client.GetBlocks(c.ctx, pg)
+	})
+	if err != nil {
+		log.Errorf("unable to get blocks : err %s", err)
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// allAccountPages keeps calling get, following the Link header, until there are no older pages.
+func allAccountPages(get func(pg *mastodon.Pagination) ([]*mastodon.Account, error)) ([]*mastodon.Account, error) {
+	var accounts []*mastodon.Account
+	var maxID mastodon.ID
+	for {
+		pg := &mastodon.Pagination{MaxID: maxID, Limit: moderationPageLimit}
+		page, err := get(pg)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, page...)
+
+		// pg is only updated if there was a Link header.
+		if len(page) == 0 || pg.MaxID == "" || pg.MaxID == maxID {
+			return accounts, nil
+		}
+		maxID = pg.MaxID
+	}
+}
+
+// GetDomainBlocks returns every domain we've blocked.
+func (c *MastodonBackend) GetDomainBlocks() ([]string, error) {
+	var domains []string
+	var maxID mastodon.ID
+	for {
+		var page []string
+		next, err := c.getLinkPage(c.ctx, "/api/v1/domain_blocks", mastodon.Pagination{MaxID: maxID, Limit: moderationPageLimit * 2}, &page)
+		if err != nil {
+			log.Errorf("unable to get domain blocks : err %s", err)
+			return nil, err
+		}
+		domains = append(domains, page...)
+
+		if len(page) == 0 || next == "" || next == maxID {
+			return domains, nil
+		}
+		maxID = next
+	}
+}
+
+// purge removes matching statuses from the cache, and notifications that match or are about one of
+// the removed statuses (they'd have nothing left to show).
+func (c *MastodonBackend) purge(statusMatch func(mastodon.Status) bool, notificationMatch func(Notification) bool) {
+	purged := c.timelineMessageCache.PurgeStatuses(statusMatch)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.deleteNotifications(func(n Notification) bool {
+		return notificationMatch(n) || (n.StatusID != "" && slices.Contains(purged, n.StatusID))
+	})
+}
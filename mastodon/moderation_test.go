@@ -0,0 +1,128 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-mastodon"
+)
+
+func TestModerationPurges(t *testing.T) {
+	muted := mastodon.Account{ID: "7", Acct: "muted@example.com"}
+	other := mastodon.Account{ID: "8", Acct: "other@example.org"}
+
+	for _, tc := range []struct {
+		name              string
+		action            func(c *MastodonBackend) error
+		wantHome          []mastodon.ID
+		wantNotifications []mastodon.ID
+	}{
+		{
+			name:              "mute keeping notifications",
+			action:            func(c *MastodonBackend) error { return c.MuteAccount(muted.ID, false, 0) },
+			wantHome:          []mastodon.ID{"2"},
+			wantNotifications: []mastodon.ID{"103", "101"},
+		},
+		{
+			name:              "mute with notifications",
+			action:            func(c *MastodonBackend) error { return c.MuteAccount(muted.ID, true, 0) },
+			wantHome:          []mastodon.ID{"2"},
+			wantNotifications: []mastodon.ID{"103"},
+		},
+		{
+			name:              "block",
+			action:            func(c *MastodonBackend) error { return c.BlockAccount(muted.ID) },
+			wantHome:          []mastodon.ID{"2"},
+			wantNotifications: []mastodon.ID{"103"},
+		},
+		{
+			name:              "block domain",
+			action:            func(c *MastodonBackend) error { return c.BlockDomain("example.org") },
+			wantHome:          []mastodon.ID{"3", "1"},
+			wantNotifications: []mastodon.ID{"102", "101"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestBackend(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{}`))
+			}))
+
+			mention := mastodon.Status{ID: "4", Account: muted, Content: "<p>hello</p>"}
+			ours := mastodon.Status{ID: "5", Account: mastodon.Account{ID: "1"}}
+			c.timelineMessageCache.InsertIntoTimeline("home", []mastodon.Status{
+				{ID: "3", Account: muted},
+				{ID: "2", Account: other},
+				{ID: "1", Reblog: &mastodon.Status{ID: "0", Account: muted}},
+			})
+			c.timelineMessageCache.AddFilterResults(&filteredStatus{Status: mention, Filtered: []FilterResult{}})
+
+			now := time.Now()
+			c.addNotifications("notifications", []*mastodon.Notification{
+				{ID: "103", Type: "follow", CreatedAt: now, Account: other},
+				{ID: "102", Type: "mention", CreatedAt: now.Add(-time.Minute), Account: muted, Status: &mention},
+				{ID: "101", Type: "favourite", CreatedAt: now.Add(-2 * time.Minute), Account: muted, Status: &ours},
+			}, false)
+
+			if err := tc.action(c); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := ids(c.timelineMessageCache.GetAllStatusForTimeline("home")); !slices.Equal(got, tc.wantHome) {
+				t.Errorf("home = %v, want %v", got, tc.wantHome)
+			}
+
+			notifications, _ := c.GetNotifications("notifications")
+			var got []mastodon.ID
+			for _, n := range notifications {
+				got = append(got, n.ID)
+				if n.Status != nil && n.Status.ID == "" {
+					t.Errorf("notification %s left without its status", n.ID)
+				}
+			}
+			if !slices.Equal(got, tc.wantNotifications) {
+				t.Errorf("notifications = %v, want %v", got, tc.wantNotifications)
+			}
+
+			if _, ok := c.timelineMessageCache.GetStatus(mention.ID); !ok {
+				c.timelineMessageCache.lock.RLock()
+				_, ok := c.timelineMessageCache.filterResults[mention.ID]
+				c.timelineMessageCache.lock.RUnlock()
+				if ok {
+					t.Error("filter results kept for a purged status")
+				}
+			}
+		})
+	}
+}
+
+func TestGetDomainBlocksPaging(t *testing.T) {
+	var domains []string
+	for i := 0; i < 2*moderationPageLimit+50; i++ {
+		domains = append(domains, fmt.Sprintf("domain%d.example", i))
+	}
+
+	// domain blocks are paged with the Link header, the IDs being the block's position here.
+	c := newTestBackend(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("max_id"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(start+limit, len(domains))
+		if end < len(domains) {
+			w.Header().Add("Link", fmt.Sprintf(`<http://example.com/api/v1/domain_blocks?max_id=%d>; rel="next"`, end))
+		}
+		w.Header().Add("Link", `<http://example.com/api/v1/domain_blocks?min_id=0>; rel="prev"`)
+		json.NewEncoder(w).Encode(domains[start:end])
+	}))
+
+	got, err := c.GetDomainBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, domains) {
+		t.Errorf("got %d domains, want all %d", len(got), len(domains))
+	}
+}
@@ -0,0 +1,246 @@
+package mastodon
+
+import (
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	// NotificationsTimelineID is the unfiltered notifications timeline.
+	NotificationsTimelineID = "notifications"
+
+	// most notifications kept per notifications timeline. Older ones are dropped.
+	MaxNotifications = 400
+)
+
+// NotificationTypes are the notification types that can be filtered on.
+var NotificationTypes = []string{"mention", "status", "reblog", "follow", "follow_request", "favourite", "poll", "update"}
+
+// NotificationFilter restricts a notifications timeline to some types of notification.
+// If Exclude is set, Types are the types to leave out instead.
+type NotificationFilter struct {
+	Types   []string
+	Exclude bool
+}
+
+// NewNotificationFilter filters to the selected types. If most types are selected, the rest are
+// excluded instead, so types added to Mastodon later will still turn up.
+func NewNotificationFilter(selected []string) NotificationFilter {
+	var unselected []string
+	for _, t := range NotificationTypes {
+		if !slices.Contains(selected, t) {
+			unselected = append(unselected, t)
+		}
+	}
+
+	if len(unselected) < len(selected) {
+		return NotificationFilter{Types: unselected, Exclude: true}
+	}
+	return NotificationFilter{Types: selected}
+}
+
+// TimelineID identifies the filtered timeline, eg. "notifications:mention" or "notifications:-mention"
+func (nf NotificationFilter) TimelineID() string {
+	if len(nf.Types) == 0 {
+		return NotificationsTimelineID
+	}
+	prefix := ""
+	if nf.Exclude {
+		prefix = "-"
+	}
+	return NotificationsTimelineID + ":" + prefix + strings.Join(nf.Types, ",")
+}
+
+// Name is used for the column title, eg. "notifications (mention)" or "notifications (not mention)"
+func (nf NotificationFilter) Name() string {
+	if len(nf.Types) == 0 {
+		return NotificationsTimelineID
+	}
+	if nf.Exclude {
+		return fmt.Sprintf("%s (not %s)", NotificationsTimelineID, strings.Join(nf.Types, ", "))
+	}
+	return fmt.Sprintf("%s (%s)", NotificationsTimelineID, strings.Join(nf.Types, ", "))
+}
+
+// ParseNotificationFilter is the reverse of NotificationFilter.TimelineID
+func ParseNotificationFilter(timelineID string) NotificationFilter {
+	_, types, ok := strings.Cut(timelineID, ":")
+	if !ok || types == "" {
+		return NotificationFilter{}
+	}
+	nf := NotificationFilter{}
+	if strings.HasPrefix(types, "-") {
+		nf.Exclude = true
+		types = types[1:]
+	}
+	nf.Types = strings.Split(types, ",")
+	return nf
+}
+
+// Accepts returns true if notifications of the type belong in the filtered timeline.
+func (nf NotificationFilter) Accepts(notificationType string) bool {
+	if len(nf.Types) == 0 {
+		return true
+	}
+	return slices.Contains(nf.Types, notificationType) != nf.Exclude
+}
+
+// getNotifications gets notifications, filtered server side. go-mastodon doesn't support
+// types[] and exclude_types[], so call the API directly.
+func (c *MastodonBackend) getNotifications(nf NotificationFilter, pg mastodon.Pagination) ([]*mastodon.Notification, error) {
+	params := url.Values{}
+	key := "types[]"
+	if nf.Exclude {
+		key = "exclude_types[]"
+	}
+	for _, t := range nf.Types {
+		params.Add(key, t)
+	}
+	if pg.MaxID != "" {
+		params.Set("max_id", string(pg.MaxID))
+	}
+	if pg.SinceID != "" {
+		params.Set("since_id", string(pg.SinceID))
+	}
+	if pg.Limit > 0 {
+		params.Set("limit", fmt.Sprint(pg.Limit))
+	}
+
+	var notifications []*mastodon.Notification
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/notifications", params, &notifications)
+	if err != nil {
+		log.Errorf("unable to get notifications %s : err %s", nf.TimelineID(), err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// refreshNotifications gets newer notifications for the timeline, or older ones if getOlder is set.
+// Older pages stop at MaxNotifications, as anything past that would be dropped straight away.
+func (c *MastodonBackend) refreshNotifications(timelineID string, getOlder bool, clearExisting bool) error {
+	params := mastodon.Pagination{Limit: MastodonLimit}
+
+	c.lock.RLock()
+	existing := c.notifications[timelineID]
+	if len(existing) > 0 {
+		if getOlder {
+			params.MaxID = existing[len(existing)-1].ID
+			params.Limit = min(params.Limit, int64(MaxNotifications-len(existing)))
+		} else if !clearExisting {
+			params.SinceID = existing[0].ID
+		}
+	}
+	c.lock.RUnlock()
+
+	if params.Limit <= 0 {
+		return nil
+	}
+
+	notifications, err := c.getNotifications(ParseNotificationFilter(timelineID), params)
+	if err != nil {
+		return err
+	}
+
+	c.addNotifications(timelineID, notifications, clearExisting)
+	return nil
+}
+
+// GetNotifications returns the notifications for a (possibly filtered) notifications timeline, newest first.
+func (c *MastodonBackend) GetNotifications(timelineID string) ([]*mastodon.Notification, error) {
+	notifications := []*mastodon.Notification{}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	for _, n := range c.notifications[timelineID] {
+		notification := &mastodon.Notification{
+			ID:        n.ID,
+			Type:      n.Type,
+			CreatedAt: n.CreatedAt,
+			Account:   n.Account,
+		}
+		if n.StatusID != "" {
+			status, _ := c.timelineMessageCache.GetStatus(n.StatusID)
+			if c.timelineMessageCache.IsHidden(status, FilterContextNotifications) {
+				continue
+			}
+			notification.Status = &status
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// addNotifications stores notifications (and their statuses) locally, newest first.
+// Notifications we already have are not duplicated, and only the newest MaxNotifications are kept.
+func (c *MastodonBackend) addNotifications(timelineID string, notifications []*mastodon.Notification, clearExisting bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	existing := c.notifications[timelineID]
+	if clearExisting {
+		existing = []Notification{}
+	}
+
+	for _, n := range notifications {
+		if slices.ContainsFunc(existing, func(e Notification) bool { return e.ID == n.ID }) {
+			continue
+		}
+
+		notification := Notification{
+			ID:        n.ID,
+			Type:      n.Type,
+			CreatedAt: n.CreatedAt,
+			Account:   n.Account,
+		}
+
+		if n.Status != nil {
+			notification.StatusID = n.Status.ID
+			c.timelineMessageCache.AddToMessageCache([]mastodon.Status{*n.Status})
+		}
+
+		existing = append(existing, notification)
+	}
+	sort.Slice(existing, func(i, j int) bool {
+		return existing[i].CreatedAt.After(existing[j].CreatedAt)
+	})
+
+	if len(existing) > MaxNotifications {
+		existing = existing[:MaxNotifications]
+	}
+	c.notifications[timelineID] = existing
+	c.store.QueueNotifications(timelineID, existing)
+}
+
+// deleteNotifications removes matching notifications from every notifications timeline.
+// Caller must hold the lock.
+func (c *MastodonBackend) deleteNotifications(match func(Notification) bool) {
+	for timelineID, notifications := range c.notifications {
+		remaining := slices.DeleteFunc(notifications, match)
+		if len(remaining) != len(notifications) {
+			c.store.QueueNotifications(timelineID, remaining)
+		}
+		c.notifications[timelineID] = remaining
+	}
+}
+
+// ClearNotifications dismisses every notification on the server, and locally.
+func (c *MastodonBackend) ClearNotifications() error {
+	err := c.client.ClearNotifications(c.ctx)
+	if err != nil {
+		log.Errorf("unable to clear notifications : err %s", err)
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.notifications = make(map[string][]Notification)
+	c.store.QueueClearNotifications()
+	return nil
+}
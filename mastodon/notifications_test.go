@@ -0,0 +1,54 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-mastodon"
+)
+
+func TestRefreshOlderNotificationsStopsAtCap(t *testing.T) {
+	now := time.Now()
+	notification := func(id int) *mastodon.Notification {
+		return &mastodon.Notification{ID: mastodon.ID(strconv.Itoa(id)), Type: "follow", CreatedAt: now.Add(time.Duration(id) * time.Second)}
+	}
+
+	// the instance has notifications 1 to 1000, newest first.
+	var requests []string
+	c := newTestBackend(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+		maxID, _ := strconv.Atoi(r.URL.Query().Get("max_id"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var page []*mastodon.Notification
+		for id := maxID - 1; id > 0 && len(page) < limit; id-- {
+			page = append(page, notification(id))
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+
+	var newest []*mastodon.Notification
+	for id := 1000; id > 1000-MaxNotifications+10; id-- {
+		newest = append(newest, notification(id))
+	}
+	c.addNotifications("notifications", newest, false)
+
+	for i := 0; i < 3; i++ {
+		if err := c.refreshNotifications("notifications", true, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications, _ := c.GetNotifications("notifications")
+	if len(notifications) != MaxNotifications {
+		t.Fatalf("%d notifications, want %d", len(notifications), MaxNotifications)
+	}
+	if oldest := notifications[len(notifications)-1].ID; oldest != mastodon.ID(strconv.Itoa(1000-MaxNotifications+1)) {
+		t.Errorf("oldest notification %s, want the older page kept", oldest)
+	}
+	if len(requests) != 1 {
+		t.Errorf("%d requests, want paging to stop at the cap: %v", len(requests), requests)
+	}
+}
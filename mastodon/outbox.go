@@ -0,0 +1,281 @@
+package mastodon
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kinds of request that can wait in the outbox.
+const (
+	OutboxPost      = "post"
+	OutboxFavourite = "favourite"
+	OutboxBoost     = "boost"
+)
+
+const (
+	// how often to check if the instance is reachable, and replay the outbox once it is.
+	OutboxRetryInterval = 30 * time.Second
+
+	// timelineID used when telling the UI the outbox (or connection state) has changed.
+	OutboxTimelineID = "outbox"
+)
+
+// OutboxItem is a post, favourite or boost made while the instance was unreachable.
+// Items are replayed in the order they were made.
+type OutboxItem struct {
+	ID       int64     `json:"id"`
+	Kind     string    `json:"kind"`
+	QueuedAt time.Time `json:"queuedAt"`
+
+	// OutboxPost only.
+	Toot *mastodon.Toot `json:"toot,omitempty"`
+
+	// OutboxFavourite and OutboxBoost only. On is false to unfavourite/unboost.
+	StatusID mastodon.ID `json:"statusID,omitempty"`
+	On       bool        `json:"on"`
+
+	// sent with every attempt, so the server can spot a post that got through even though
+	// we thought the instance was unreachable, and not create it again.
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	// set if the server rejected the item when it was replayed. Failed items are not retried,
+	// they're left for the user to edit or discard.
+	Error string `json:"error,omitempty"`
+}
+
+// Description is a short summary of the item for displaying in the outbox.
+func (o OutboxItem) Description() string {
+	switch o.Kind {
+	case OutboxPost:
+		text := []rune(strings.TrimSpace(o.Toot.Status))
+		if len(text) > 40 {
+			text = append(text[:40], '…')
+		}
+		return fmt.Sprintf("post: %s", string(text))
+	default:
+		action := o.Kind
+		if !o.On {
+			action = "un" + action
+		}
+		return fmt.Sprintf("%s status %s", action, o.StatusID)
+	}
+}
+
+// unreachable returns true if the request failed because we couldn't get to the instance (or it's
+// down), rather than the instance rejecting the request.
+func unreachable(err error) bool {
+	if errors.Is(err, ErrRateLimited) {
+		return false
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return true
+	}
+
+	var apiErr *mastodon.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 500
+}
+
+type idempotencyKey struct{}
+
+// withIdempotencyKey sends requests made with ctx with the Idempotency-Key header.
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// idempotencyTransport adds the Idempotency-Key header to requests whose context has one. go-mastodon
+// doesn't let us set headers, so this sits under the client.
+type idempotencyTransport struct {
+	next http.RoundTripper
+}
+
+func (t idempotencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if key, ok := req.Context().Value(idempotencyKey{}).(string); ok {
+		req = req.Clone(req.Context())
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// IsOnline returns false if the instance was unreachable the last time we tried it.
+func (c *MastodonBackend) IsOnline() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.online
+}
+
+// GetOutbox returns the items waiting to be sent, oldest first.
+func (c *MastodonBackend) GetOutbox() []OutboxItem {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]OutboxItem{}, c.outbox...)
+}
+
+// DiscardOutboxItem removes an item from the outbox without sending it.
+func (c *MastodonBackend) DiscardOutboxItem(id int64) error {
+	c.lock.Lock()
+	err := c.removeOutboxItem(id)
+	c.lock.Unlock()
+
+	c.outboxChanged()
+	return err
+}
+
+// outboxChanged lets the UI know the outbox or connection state has changed.
+func (c *MastodonBackend) outboxChanged() {
+	events.FireEvent(events.NewTimelineUpdatedEvent(c.AccountName(), OutboxTimelineID))
+}
+
+// removeOutboxItem removes the item from the outbox, and from disk.
+// Caller must hold the lock.
+func (c *MastodonBackend) removeOutboxItem(id int64) error {
+	for i, item := range c.outbox {
+		if item.ID == id {
+			c.outbox = append(c.outbox[:i], c.outbox[i+1:]...)
+			return c.store.DeleteOutboxItem(id)
+		}
+	}
+	return nil
+}
+
+// setOnline records whether the instance is reachable, letting the UI know if it has changed.
+func (c *MastodonBackend) setOnline(online bool) {
+	c.lock.Lock()
+	changed := c.online != online
+	c.online = online
+	c.lock.Unlock()
+
+	if changed {
+		c.outboxChanged()
+	}
+}
+
+// sendOrQueue sends the item, or queues it if the instance is unreachable. Anything already waiting
+// in the outbox is sent first, so the item is also queued if we're offline or have pending items.
+func (c *MastodonBackend) sendOrQueue(item OutboxItem) error {
+	item.IdempotencyKey = newIdempotencyKey()
+
+	c.lock.RLock()
+	queue := !c.online || c.hasPendingOutbox()
+	c.lock.RUnlock()
+
+	if !queue {
+		err := c.sendOutboxItem(c.ctx, item)
+		if err == nil || !unreachable(err) {
+			return err
+		}
+		c.setOnline(false)
+	}
+
+	c.lock.Lock()
+	c.nextOutboxID++
+	item.ID = c.nextOutboxID
+	item.QueuedAt = time.Now()
+	c.outbox = append(c.outbox, item)
+	err := c.store.SaveOutboxItem(item)
+	c.lock.Unlock()
+
+	c.outboxChanged()
+	return err
+}
+
+// hasPendingOutbox returns true if there are items waiting to be sent (not counting failed items).
+// Caller must hold the lock.
+func (c *MastodonBackend) hasPendingOutbox() bool {
+	for _, item := range c.outbox {
+		if item.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MastodonBackend) sendOutboxItem(ctx context.Context, item OutboxItem) error {
+	ctx = withIdempotencyKey(ctx, item.IdempotencyKey)
+	switch item.Kind {
+	case OutboxPost:
+		return c.post(ctx, item.Toot)
+	case OutboxFavourite:
+		return c.setFavourite(ctx, item.StatusID, item.On)
+	case OutboxBoost:
+		return c.boost(ctx, item.StatusID, item.On)
+	}
+	return fmt.Errorf("unknown outbox item %s", item.Kind)
+}
+
+// runOutbox regularly checks the instance is reachable and replays the outbox.
+func (c *MastodonBackend) runOutbox() {
+	for {
+		time.Sleep(OutboxRetryInterval)
+		c.replayOutbox()
+	}
+}
+
+// replayOutbox sends the pending items in order. Stops at the first item that fails because the
+// instance is still unreachable (or we're rate limited). Items the instance rejects are marked as failed.
+func (c *MastodonBackend) replayOutbox() {
+	ctx := BackgroundContext(c.ctx)
+	for _, item := range c.GetOutbox() {
+		if item.Error != "" {
+			continue
+		}
+
+		err := c.sendOutboxItem(ctx, item)
+		if errors.Is(err, ErrRateLimited) {
+			return
+		}
+		if err != nil && unreachable(err) {
+			c.setOnline(false)
+			return
+		}
+		c.setOnline(true)
+
+		c.lock.Lock()
+		if err != nil {
+			c.markOutboxItemFailed(item.ID, err)
+		} else {
+			c.removeOutboxItem(item.ID)
+		}
+		c.lock.Unlock()
+		c.outboxChanged()
+	}
+
+	// nothing left to send, so just check if the instance is there.
+	_, err := c.client.GetInstance(ctx)
+	c.setOnline(err == nil || !unreachable(err))
+}
+
+// markOutboxItemFailed records why the instance rejected the item.
+// Caller must hold the lock.
+func (c *MastodonBackend) markOutboxItemFailed(id int64, err error) {
+	log.Errorf("outbox item %d rejected : err %s", id, err)
+	for i := range c.outbox {
+		if c.outbox[i].ID == id {
+			c.outbox[i].Error = err.Error()
+			c.store.SaveOutboxItem(c.outbox[i])
+			return
+		}
+	}
+}
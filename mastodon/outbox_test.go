@@ -0,0 +1,202 @@
+package mastodon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mattn/go-mastodon"
+	"github.com/pkg/errors"
+)
+
+func TestUnreachable(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "dial"}}, true},
+		{"timeout", &net.DNSError{IsTimeout: true}, true},
+		{"server error", &mastodon.APIError{StatusCode: http.StatusBadGateway}, true},
+		{"wrapped server error", fmt.Errorf("posting: %w", &mastodon.APIError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"rejected", &mastodon.APIError{StatusCode: http.StatusUnprocessableEntity}, false},
+		{"rate limited", errors.Wrap(ErrRateLimited, "posting"), false},
+		{"other", errors.New("something else"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := unreachable(tc.err); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// outboxInstance accepts posts (or not, depending on status), recording the idempotency keys they were sent with.
+type outboxInstance struct {
+	lock   sync.Mutex
+	status int
+	keys   []string
+}
+
+func (oi *outboxInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	oi.lock.Lock()
+	defer oi.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v1/statuses":
+		oi.keys = append(oi.keys, r.Header.Get("Idempotency-Key"))
+		if oi.status != http.StatusOK {
+			http.Error(w, `{"error": "nope"}`, oi.status)
+			return
+		}
+		fmt.Fprintf(w, `{"id": "%d", "content": "<p>posted</p>"}`, len(oi.keys))
+	case "/api/v1/instance":
+		w.Write([]byte(`{"uri": "example.com"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (oi *outboxInstance) setStatus(status int) {
+	oi.lock.Lock()
+	defer oi.lock.Unlock()
+	oi.status = status
+}
+
+func (oi *outboxInstance) sentKeys() []string {
+	oi.lock.Lock()
+	defer oi.lock.Unlock()
+	return append([]string{}, oi.keys...)
+}
+
+func TestPostSentStraightAway(t *testing.T) {
+	oi := &outboxInstance{status: http.StatusOK}
+	c := newTestBackend(t, oi)
+
+	if err := c.Post(&mastodon.Toot{Status: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.GetOutbox()); n != 0 {
+		t.Errorf("%d items queued, want none", n)
+	}
+	if keys := oi.sentKeys(); len(keys) != 1 || keys[0] == "" {
+		t.Errorf("sent with keys %q, want one key", keys)
+	}
+}
+
+func TestPostRejectedIsNotQueued(t *testing.T) {
+	oi := &outboxInstance{status: http.StatusUnprocessableEntity}
+	c := newTestBackend(t, oi)
+
+	if err := c.Post(&mastodon.Toot{Status: "hello"}); err == nil {
+		t.Error("rejected post didn't return an error")
+	}
+	if n := len(c.GetOutbox()); n != 0 {
+		t.Errorf("%d items queued, want none", n)
+	}
+	if !c.IsOnline() {
+		t.Error("offline after the instance rejected a post")
+	}
+}
+
+func TestOutboxReplay(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+
+		// how the instance responds when the outbox is replayed.
+		status int
+
+		wantQueued bool
+		wantFailed bool
+		wantOnline bool
+	}{
+		{name: "instance is back", status: http.StatusOK, wantOnline: true},
+		{name: "rejected", status: http.StatusUnprocessableEntity, wantQueued: true, wantFailed: true, wantOnline: true},
+		{name: "still down", status: http.StatusBadGateway, wantQueued: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oi := &outboxInstance{status: http.StatusBadGateway}
+			c := newTestBackend(t, oi)
+
+			if err := c.Post(&mastodon.Toot{Status: "hello"}); err != nil {
+				t.Fatal(err)
+			}
+			if c.IsOnline() {
+				t.Fatal("still online after the instance went down")
+			}
+			if n := len(c.GetOutbox()); n != 1 {
+				t.Fatalf("%d items queued, want 1", n)
+			}
+
+			oi.setStatus(tc.status)
+			c.replayOutbox()
+
+			outbox := c.GetOutbox()
+			if queued := len(outbox) == 1; queued != tc.wantQueued {
+				t.Errorf("queued %v, want %v", queued, tc.wantQueued)
+			}
+			if len(outbox) == 1 && (outbox[0].Error != "") != tc.wantFailed {
+				t.Errorf("error %q, want failed %v", outbox[0].Error, tc.wantFailed)
+			}
+			if c.IsOnline() != tc.wantOnline {
+				t.Errorf("online %v, want %v", c.IsOnline(), tc.wantOnline)
+			}
+
+			// the replay must be recognisable as the post that (maybe) got through the first time.
+			keys := oi.sentKeys()
+			if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+				t.Errorf("sent with keys %q, want the same key twice", keys)
+			}
+		})
+	}
+}
+
+func TestFailedItemsAreNotReplayed(t *testing.T) {
+	oi := &outboxInstance{status: http.StatusOK}
+	c := newTestBackend(t, oi)
+	c.outbox = []OutboxItem{
+		{ID: 1, Kind: OutboxPost, Toot: &mastodon.Toot{Status: "rejected"}, IdempotencyKey: "a", Error: "nope"},
+		{ID: 2, Kind: OutboxPost, Toot: &mastodon.Toot{Status: "pending"}, IdempotencyKey: "b"},
+	}
+
+	c.replayOutbox()
+
+	outbox := c.GetOutbox()
+	if len(outbox) != 1 || outbox[0].ID != 1 {
+		t.Errorf("outbox %+v, want only the failed item", outbox)
+	}
+	if keys := oi.sentKeys(); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("sent with keys %q, want [b]", keys)
+	}
+}
+
+func TestIdempotencyTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Idempotency-Key"))
+	}))
+	defer server.Close()
+	transport := idempotencyTransport{next: http.DefaultTransport}
+
+	for _, ctx := range []context.Context{context.Background(), withIdempotencyKey(context.Background(), ""), withIdempotencyKey(context.Background(), "abc")} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if req.Header.Get("Idempotency-Key") != "" {
+			t.Error("original request was changed")
+		}
+	}
+
+	if want := []string{"", "", "abc"}; !slices.Equal(got, want) {
+		t.Errorf("keys %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,93 @@
+package mastodon
+
+import (
+	"context"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"github.com/tomnomnom/linkheader"
+	"net/http"
+	"net/url"
+)
+
+// getPagedTimeline gets bookmarks or favourites. These are ordered by when we bookmarked/favourited
+// the status (not by status ID), so the only way to page through them is with the IDs the server
+// gives us in the Link header.
+// Returns nil if getting older statuses and there are no more. Getting the newest page leaves the
+// cursor alone once we have one (unless clearing), so polling doesn't lose our place in the older pages.
+func (c *MastodonBackend) getPagedTimeline(refreshType events.RefreshType, timelineID string, getOlder bool, clearExisting bool) ([]*mastodon.Status, error) {
+	params := mastodon.Pagination{Limit: MastodonLimit}
+
+	if getOlder {
+		c.lock.RLock()
+		maxID, ok := c.nextPage[timelineID]
+		c.lock.RUnlock()
+		if !ok || maxID == "" {
+			return nil, nil
+		}
+		params.MaxID = maxID
+	}
+
+	var statuses []*mastodon.Status
+	var err error
+	switch refreshType {
+	case events.BOOKMARKS_REFRESH:
+		statuses, err = c.client.GetBookmarks(c.ctx, &params)
+	case events.FAVOURITES_REFRESH:
+		statuses, err = c.client.GetFavourites(c.ctx, &params)
+	}
+	if err != nil {
+		log.Errorf("unable to get timelineID %s : err %s", timelineID, err)
+		return nil, err
+	}
+
+	// params now holds the Link header IDs. No MaxID means we've reached the end. There's no Link
+	// header at all for an empty page, which leaves params as they were.
+	nextPage := params.MaxID
+	if len(statuses) == 0 {
+		nextPage = ""
+	}
+
+	c.lock.Lock()
+	if _, ok := c.nextPage[timelineID]; getOlder || clearExisting || !ok {
+		c.nextPage[timelineID] = nextPage
+	}
+	c.lock.Unlock()
+
+	return statuses, nil
+}
+
+// getLinkPage gets one page of a list that go-mastodon doesn't support (eg. domain blocks), paged
+// with the Link header. Returns the max ID of the next (older) page, or "" if there are no more.
+func (c *MastodonBackend) getLinkPage(ctx context.Context, uri string, pg mastodon.Pagination, res interface{}) (mastodon.ID, error) {
+	u, err := c.apiURL(uri)
+	if err != nil {
+		return "", err
+	}
+	u.RawQuery = paginationParams(url.Values{}, pg).Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	header, err := c.sendAPIRequestWithHeader(req, res)
+	if err != nil {
+		return "", err
+	}
+	return nextPageID(header.Get("Link")), nil
+}
+
+// nextPageID is the max ID from the "next" link in a Link header, or "" if there isn't one.
+func nextPageID(link string) mastodon.ID {
+	for _, l := range linkheader.Parse(link) {
+		if l.Rel != "next" {
+			continue
+		}
+		u, err := url.Parse(l.URL)
+		if err != nil {
+			return ""
+		}
+		return mastodon.ID(u.Query().Get("max_id"))
+	}
+	return ""
+}
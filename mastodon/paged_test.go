@@ -0,0 +1,288 @@
+package mastodon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+)
+
+// bookmarksInstance serves bookmarks paged with the Link header, like an instance does. Bookmarks
+// are paged by when they were bookmarked, so the paging IDs aren't the status IDs.
+type bookmarksInstance struct {
+	lock sync.Mutex
+
+	// most recently bookmarked first.
+	bookmarks []mastodon.Status
+
+	requests int
+}
+
+// bookmarkID is the paging ID of the bookmark at index i. Older bookmarks have smaller IDs.
+func (bi *bookmarksInstance) bookmarkID(i int) int {
+	id, _ := strconv.Atoi(string(bi.bookmarks[i].ID))
+	return id * 7
+}
+
+func (bi *bookmarksInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+
+	bi.requests++
+	if r.URL.Path != "/api/v1/bookmarks" {
+		http.NotFound(w, r)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		limit = MastodonLimit
+	}
+	maxID, err := strconv.Atoi(r.URL.Query().Get("max_id"))
+	if err != nil {
+		maxID = -1
+	}
+
+	page := []mastodon.Status{}
+	var last int
+	for i, s := range bi.bookmarks {
+		if (maxID == -1 || bi.bookmarkID(i) < maxID) && len(page) < limit {
+			page = append(page, s)
+			last = i
+		}
+	}
+
+	// the next link is only there while there may be more.
+	if len(page) == limit {
+		w.Header().Add("Link", fmt.Sprintf(`<http://example.com/api/v1/bookmarks?max_id=%d>; rel="next"`, bi.bookmarkID(last)))
+	}
+	if len(page) > 0 {
+		w.Header().Add("Link", fmt.Sprintf(`<http://example.com/api/v1/bookmarks?min_id=%d>; rel="prev"`, bi.bookmarkID(0)))
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func (bi *bookmarksInstance) bookmark(status mastodon.Status) {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+	bi.bookmarks = append([]mastodon.Status{status}, bi.bookmarks...)
+}
+
+func (bi *bookmarksInstance) requestCount() int {
+	bi.lock.Lock()
+	defer bi.lock.Unlock()
+	return bi.requests
+}
+
+// refresh sends a refresh event for the timeline, skipping the check for recent refreshes.
+func refresh(t *testing.T, c *MastodonBackend, re events.RefreshEvent) {
+	t.Helper()
+
+	delete(c.lastRefreshed, re.TimelineID)
+	c.timelineMessageCache.lock.Lock()
+	details := c.timelineMessageCache.timelineMessageCache[re.TimelineID]
+	details.lastRefreshed = time.Time{}
+	c.timelineMessageCache.timelineMessageCache[re.TimelineID] = details
+	c.timelineMessageCache.lock.Unlock()
+
+	if err := c.RefreshMessagesCallback(re); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBookmarksPaging(t *testing.T) {
+	bi := &bookmarksInstance{bookmarks: statusRange(1, 45)}
+	c := newTestBackend(t, bi)
+
+	load := events.NewRefreshEvent(c.AccountName(), "bookmarks", true, events.BOOKMARKS_REFRESH)
+	poll := events.NewRefreshEvent(c.AccountName(), "bookmarks", false, events.BOOKMARKS_REFRESH)
+	older := poll
+	older.GetOlder = true
+
+	for _, tc := range []struct {
+		name        string
+		bookmark    int
+		event       events.RefreshEvent
+		want        []mastodon.ID
+		wantCursor  mastodon.ID
+		wantRequest bool
+	}{
+		{name: "load", event: load, want: idRange(26, 45), wantCursor: "182", wantRequest: true},
+		{name: "older", event: older, want: idRange(6, 45), wantCursor: "42", wantRequest: true},
+		{name: "poll with nothing new", event: poll, want: idRange(6, 45), wantCursor: "42", wantRequest: true},
+		{name: "poll with a new bookmark", bookmark: 46, event: poll, want: idRange(6, 46), wantCursor: "42", wantRequest: true},
+		{name: "older after poll", event: older, want: idRange(1, 46), wantCursor: "", wantRequest: true},
+		{name: "nothing older", event: older, want: idRange(1, 46), wantCursor: "", wantRequest: false},
+		{name: "reload", event: load, want: idRange(27, 46), wantCursor: "189", wantRequest: true},
+	} {
+		if tc.bookmark != 0 {
+			bi.bookmark(testStatus(tc.bookmark))
+		}
+		requests := bi.requestCount()
+
+		refresh(t, c, tc.event)
+
+		got := ids(c.timelineMessageCache.GetAllStatusForTimeline("bookmarks"))
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: timeline = %v, want %v", tc.name, got, tc.want)
+		}
+		if cursor := c.nextPage["bookmarks"]; cursor != tc.wantCursor {
+			t.Errorf("%s: cursor = %q, want %q", tc.name, cursor, tc.wantCursor)
+		}
+		if requested := bi.requestCount() > requests; requested != tc.wantRequest {
+			t.Errorf("%s: requested %v, want %v", tc.name, requested, tc.wantRequest)
+		}
+	}
+}
+
+func TestBookmarksPagingEndsOnEmptyPage(t *testing.T) {
+	bi := &bookmarksInstance{bookmarks: statusRange(1, 40)}
+	c := newTestBackend(t, bi)
+
+	older := events.NewRefreshEvent(c.AccountName(), "bookmarks", false, events.BOOKMARKS_REFRESH)
+	older.GetOlder = true
+
+	refresh(t, c, events.NewRefreshEvent(c.AccountName(), "bookmarks", true, events.BOOKMARKS_REFRESH))
+	refresh(t, c, older)
+	if cursor := c.nextPage["bookmarks"]; cursor != "7" {
+		t.Fatalf("cursor = %q after two full pages, want 7", cursor)
+	}
+
+	// the last full page had a next link, but there's nothing after it.
+	refresh(t, c, older)
+	if cursor := c.nextPage["bookmarks"]; cursor != "" {
+		t.Errorf("cursor = %q after an empty page, want none", cursor)
+	}
+
+	requests := bi.requestCount()
+	refresh(t, c, older)
+	if bi.requestCount() != requests {
+		t.Error("asked for older bookmarks after reaching the end")
+	}
+	if got := ids(c.timelineMessageCache.GetAllStatusForTimeline("bookmarks")); !slices.Equal(got, idRange(1, 40)) {
+		t.Errorf("timeline = %v, want all 40", got)
+	}
+}
+
+func TestMergeIntoTimeline(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		existing []mastodon.Status
+		page     []mastodon.Status
+		want     []mastodon.ID
+	}{
+		{"empty timeline", nil, statusRange(1, 3), idRange(1, 3)},
+		{"new on top", statusRange(1, 3), statusRange(2, 5), idRange(1, 5)},
+		{"unchanged", statusRange(1, 3), statusRange(1, 3), idRange(1, 3)},
+		{"empty page", statusRange(1, 3), nil, idRange(1, 3)},
+		{"re-bookmarked moves to the top", statusRange(1, 3), []mastodon.Status{testStatus(1)}, []mastodon.ID{"1", "3", "2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := NewTimelineCache()
+			cache.AddToTimeline("bookmarks", true, tc.existing, false)
+			before, _ := cache.GetTimelineDetails("bookmarks")
+
+			if err := cache.MergeIntoTimeline("bookmarks", tc.page); err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(cache.GetAllStatusForTimeline("bookmarks")); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			// merging is a poll, so it mustn't stop the next page being added.
+			if after, _ := cache.GetTimelineDetails("bookmarks"); !after.lastRefreshed.Equal(before.lastRefreshed) {
+				t.Errorf("merge changed lastRefreshed")
+			}
+		})
+	}
+}
+
+// followRequestsInstance serves follow requests paged with the Link header.
+type followRequestsInstance struct {
+	lock     sync.Mutex
+	accounts []mastodon.Account
+}
+
+func (fi *followRequestsInstance) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+
+	maxID, err := strconv.Atoi(r.URL.Query().Get("max_id"))
+	if err != nil {
+		maxID = len(fi.accounts)
+	}
+
+	page := []mastodon.Account{}
+	next := 0
+	for i := maxID - 1; i >= 0 && len(page) < MastodonLimit; i-- {
+		page = append(page, fi.accounts[i])
+		next = i
+	}
+	if len(page) == MastodonLimit {
+		w.Header().Add("Link", fmt.Sprintf(`<http://example.com/api/v1/follow_requests?max_id=%d>; rel="next"`, next))
+	}
+	if len(page) > 0 {
+		w.Header().Add("Link", fmt.Sprintf(`<http://example.com/api/v1/follow_requests?min_id=%d>; rel="prev"`, len(fi.accounts)))
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+func (fi *followRequestsInstance) setAccounts(from int, to int) {
+	fi.lock.Lock()
+	defer fi.lock.Unlock()
+	fi.accounts = nil
+	for i := from; i <= to; i++ {
+		fi.accounts = append(fi.accounts, mastodon.Account{ID: mastodon.ID(strconv.Itoa(i))})
+	}
+}
+
+func accountIDs(accounts []*mastodon.Account) []mastodon.ID {
+	var ids []mastodon.ID
+	for _, a := range accounts {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func TestFollowRequestsPaging(t *testing.T) {
+	fi := &followRequestsInstance{}
+	c := newTestBackend(t, fi)
+
+	for _, tc := range []struct {
+		name       string
+		from, to   int
+		getOlder   bool
+		clear      bool
+		want       []mastodon.ID
+		wantCursor mastodon.ID
+	}{
+		{name: "short list is everything", from: 1, to: 5, clear: true, want: idRange(1, 5)},
+		{name: "accepted elsewhere", from: 2, to: 5, want: idRange(2, 5)},
+		{name: "load a full page", from: 1, to: 30, clear: true, want: idRange(11, 30), wantCursor: "10"},
+		{name: "older", from: 1, to: 30, getOlder: true, want: idRange(1, 30)},
+		{name: "poll keeps older pages", from: 1, to: 31, want: idRange(1, 31)},
+		{name: "nothing left", from: 1, to: 31, getOlder: true, want: idRange(1, 31)},
+		{name: "clear", from: 1, to: 31, clear: true, want: idRange(12, 31), wantCursor: "11"},
+		{name: "poll when it's shrunk to one page", from: 20, to: 31, want: idRange(20, 31)},
+	} {
+		fi.setAccounts(tc.from, tc.to)
+		if err := c.refreshFollowRequests("followrequests", tc.getOlder, tc.clear); err != nil {
+			t.Fatal(err)
+		}
+
+		requests, _ := c.GetFollowRequests()
+		if got := accountIDs(requests); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: requests = %v, want %v", tc.name, got, tc.want)
+		}
+		if cursor := c.nextPage["followrequests"]; cursor != tc.wantCursor {
+			t.Errorf("%s: cursor = %q, want %q", tc.name, cursor, tc.wantCursor)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package mastodon
+
+import (
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	"time"
+	"unicode/utf8"
+)
+
+// PollLimits are the restrictions an instance places on creating polls.
+type PollLimits struct {
+	MaxOptions             int
+	MaxCharactersPerOption int
+	MinExpiration          time.Duration
+	MaxExpiration          time.Duration
+}
+
+// DefaultPollLimits are the Mastodon defaults, used until we've heard otherwise from the instance.
+var DefaultPollLimits = PollLimits{
+	MaxOptions:             4,
+	MaxCharactersPerOption: 50,
+	MinExpiration:          5 * time.Minute,
+	MaxExpiration:          2629746 * time.Second,
+}
+
+// ValidatePoll checks the poll against the instance limits.
+func ValidatePoll(poll *mastodon.TootPoll, limits PollLimits) error {
+	if len(poll.Options) < 2 {
+		return fmt.Errorf("poll needs at least 2 options")
+	}
+	if len(poll.Options) > limits.MaxOptions {
+		return fmt.Errorf("poll can have at most %d options", limits.MaxOptions)
+	}
+
+	for i, option := range poll.Options {
+		if option == "" {
+			return fmt.Errorf("poll option %d is empty", i+1)
+		}
+		if utf8.RuneCountInString(option) > limits.MaxCharactersPerOption {
+			return fmt.Errorf("poll option %d is longer than %d characters", i+1, limits.MaxCharactersPerOption)
+		}
+	}
+
+	expiresIn := time.Duration(poll.ExpiresInSeconds) * time.Second
+	if expiresIn < limits.MinExpiration || expiresIn > limits.MaxExpiration {
+		return fmt.Errorf("poll duration must be between %s and %s", limits.MinExpiration, limits.MaxExpiration)
+	}
+	return nil
+}
+
+// pollStatus returns the status that owns the poll. For boosts that's the boosted status.
+func pollStatus(status mastodon.Status) (mastodon.Status, error) {
+	if status.Reblog != nil {
+		status = *status.Reblog
+	}
+	if status.Poll == nil {
+		return status, fmt.Errorf("status %s has no poll", status.ID)
+	}
+	return status, nil
+}
@@ -0,0 +1,91 @@
+package mastodon
+
+import (
+	"fmt"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"strings"
+)
+
+// PublicTimeline is the local or federated timeline, optionally filtered.
+type PublicTimeline struct {
+	Local bool
+
+	// only statuses with media attached.
+	OnlyMedia bool
+
+	// only statuses from other instances. Only makes sense for the federated timeline.
+	RemoteOnly bool
+}
+
+// TimelineID identifies the timeline (with its filters) eg. "local" or "federated:remote:media"
+func (pt PublicTimeline) TimelineID() string {
+	parts := []string{"federated"}
+	if pt.Local {
+		parts[0] = "local"
+	}
+	if pt.RemoteOnly && !pt.Local {
+		parts = append(parts, "remote")
+	}
+	if pt.OnlyMedia {
+		parts = append(parts, "media")
+	}
+	return strings.Join(parts, ":")
+}
+
+// Name is used for the column title, eg. "federated (remote, media)"
+func (pt PublicTimeline) Name() string {
+	parts := strings.Split(pt.TimelineID(), ":")
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return fmt.Sprintf("%s (%s)", parts[0], strings.Join(parts[1:], ", "))
+}
+
+// ParsePublicTimeline is the reverse of PublicTimeline.TimelineID
+func ParsePublicTimeline(timelineID string) PublicTimeline {
+	parts := strings.Split(timelineID, ":")
+	pt := PublicTimeline{Local: parts[0] == "local"}
+	for _, p := range parts[1:] {
+		switch p {
+		case "remote":
+			pt.RemoteOnly = !pt.Local
+		case "media":
+			pt.OnlyMedia = true
+		}
+	}
+	return pt
+}
+
+// streamPath is the streaming API endpoint for the timeline. eg. "public/local"
+func (pt PublicTimeline) streamPath() string {
+	if pt.Local {
+		return "public/local"
+	}
+	if pt.RemoteOnly {
+		return "public/remote"
+	}
+	return "public"
+}
+
+// getPublicTimeline gets the local or federated timeline. go-mastodon doesn't support the
+// remote filter (or filter results), so call the API directly.
+func (c *MastodonBackend) getPublicTimeline(pt PublicTimeline, pg mastodon.Pagination) ([]*mastodon.Status, error) {
+	params := url.Values{}
+	if pt.Local {
+		params.Set("local", "true")
+	}
+	if pt.RemoteOnly && !pt.Local {
+		params.Set("remote", "true")
+	}
+	if pt.OnlyMedia {
+		params.Set("only_media", "true")
+	}
+	statuses, err := c.getStatuses("/api/v1/timelines/public", paginationParams(params, pg))
+	if err != nil {
+		log.Errorf("unable to get timelineID %s : err %s", pt.TimelineID(), err)
+		return nil, err
+	}
+	return statuses, nil
+}
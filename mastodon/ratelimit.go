@@ -0,0 +1,265 @@
+package mastodon
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// idempotent requests are retried this many times on 429/5xx before giving up.
+	MaxRetries = 3
+
+	// first retry is after roughly this long, doubling for each retry after that.
+	RetryBaseDelay = 500 * time.Millisecond
+
+	// background requests never wait longer than this for a retry or for the rate limit to reset.
+	MaxRetryDelay = 5 * time.Minute
+
+	// interactive requests (anything not made with BackgroundContext) are run from the UI, so they
+	// give up rather than wait longer than this, and time out if they take longer than InteractiveTimeout.
+	MaxInteractiveDelay = 2 * time.Second
+	InteractiveTimeout  = 15 * time.Second
+
+	// once less than this fraction of the rate limit is left, requests are spread out over
+	// the time until it resets.
+	rateLimitReserve = 0.1
+)
+
+// ErrRateLimited is returned for interactive requests when the rate limit would make them wait too long.
+var ErrRateLimited = errors.New("rate limited, try again later")
+
+type backgroundKey struct{}
+
+// BackgroundContext marks requests made with ctx as background work (eg. polling or streaming), which
+// can wait out the rate limit resetting and has no timeout.
+func BackgroundContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+func isBackground(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundKey{}).(bool)
+	return background
+}
+
+// maxDelay is the longest a request can wait for a retry or the rate limit, without failing.
+func maxDelay(ctx context.Context) time.Duration {
+	if isBackground(ctx) {
+		return MaxRetryDelay
+	}
+	delay := MaxInteractiveDelay
+	if deadline, ok := ctx.Deadline(); ok {
+		delay = min(delay, time.Until(deadline))
+	}
+	return delay
+}
+
+// cancelBody cancels the request's timeout once the response has been read.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RateLimitBudget is what's left of the instance's rate limit. Limit is 0 if the instance
+// hasn't told us (yet).
+type RateLimitBudget struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// Low returns true if most of the budget has been used and the limit hasn't reset yet.
+// Background work (eg. polling timelines) should hold off so there's budget left for the user.
+func (b RateLimitBudget) Low() bool {
+	return b.Limit > 0 && time.Now().Before(b.Reset) && float64(b.Remaining) < float64(b.Limit)*rateLimitReserve
+}
+
+// RateLimitTransport sits under the Mastodon client. It tracks the X-RateLimit-* headers, throttles
+// all requests once the budget is low, and retries idempotent requests with jittered backoff on
+// 429 and 5xx responses.
+type RateLimitTransport struct {
+	next http.RoundTripper
+
+	lock   sync.Mutex
+	budget RateLimitBudget
+
+	// sleep waits for d, or until ctx is done. Replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRateLimitTransport wraps next, or http.DefaultTransport if next is nil.
+func NewRateLimitTransport(next http.RoundTripper) *RateLimitTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &RateLimitTransport{
+		next:  next,
+		sleep: sleepContext,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Budget returns what's left of the rate limit, as of the last response.
+func (t *RateLimitTransport) Budget() RateLimitBudget {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.budget
+}
+
+// RoundTrip waits until the rate limit allows the request, sends it and retries if needed.
+// Interactive requests without a deadline are given InteractiveTimeout.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Deadline(); ok || isBackground(req.Context()) {
+		return t.roundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), InteractiveTimeout)
+	resp, err := t.roundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *RateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		delay := t.throttleDelay()
+		if delay > maxDelay(req.Context()) {
+			return nil, ErrRateLimited
+		}
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		t.updateBudget(resp)
+
+		if !retryable(req, resp) || attempt == MaxRetries {
+			return resp, nil
+		}
+
+		// the body has to be sent again.
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		// not worth waiting, so let the caller see the failure.
+		delay = t.retryDelay(resp, attempt)
+		if delay > maxDelay(req.Context()) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable returns true for idempotent requests that failed with 429 or 5xx.
+func retryable(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return false
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// throttleDelay is how long to wait before sending the next request. Nothing while there's plenty of
+// budget, then the time left is shared out between the requests left. Once the budget has run out,
+// we wait for it to reset.
+func (t *RateLimitTransport) throttleDelay() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	b := t.budget
+	untilReset := time.Until(b.Reset)
+	if b.Limit == 0 || untilReset <= 0 || float64(b.Remaining) >= float64(b.Limit)*rateLimitReserve {
+		return 0
+	}
+
+	delay := untilReset
+	if b.Remaining > 0 {
+		delay = untilReset / time.Duration(b.Remaining+1)
+
+		// count the request we're about to make, so concurrent requests are spread out too.
+		t.budget.Remaining--
+	}
+	return min(delay, MaxRetryDelay)
+}
+
+// retryDelay is how long to wait before retrying. For 429 the server tells us when the limit
+// resets, otherwise it's exponential backoff with jitter.
+func (t *RateLimitTransport) retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return min(time.Duration(seconds)*time.Second, MaxRetryDelay)
+		}
+		if reset := time.Until(t.Budget().Reset); reset > 0 {
+			return min(reset, MaxRetryDelay)
+		}
+	}
+
+	backoff := RetryBaseDelay << attempt
+	jitter := time.Duration(rand.Int63n(int64(backoff)))
+	return min(backoff/2+jitter, MaxRetryDelay)
+}
+
+// updateBudget records the rate limit headers from the response. Responses without them
+// (eg. from a proxy) leave the budget alone.
+func (t *RateLimitTransport) updateBudget(resp *http.Response) {
+	limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := time.Parse(time.RFC3339, resp.Header.Get("X-RateLimit-Reset"))
+	if err != nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.budget = RateLimitBudget{Limit: limit, Remaining: remaining, Reset: reset}
+}
@@ -0,0 +1,292 @@
+package mastodon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubServer is a stand-in Mastodon instance. Each request gets the next status code in
+// statuses (200 once they run out), along with the rate limit headers.
+type stubServer struct {
+	*httptest.Server
+
+	lock      sync.Mutex
+	statuses  []int
+	requests  int
+	remaining int
+	reset     time.Time
+	headers   map[string]string
+}
+
+func newStubServer(t *testing.T, statuses ...int) *stubServer {
+	s := &stubServer{statuses: statuses, remaining: 300, reset: time.Now().Add(5 * time.Minute)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.requests++
+		w.Header().Set("X-RateLimit-Limit", "300")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(s.remaining))
+		w.Header().Set("X-RateLimit-Reset", s.reset.UTC().Format(time.RFC3339))
+		for k, v := range s.headers {
+			w.Header().Set(k, v)
+		}
+
+		status := http.StatusOK
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) requestCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.requests
+}
+
+// newTestTransport records how long the transport would have slept, instead of sleeping.
+func newTestTransport() (*RateLimitTransport, *[]time.Duration) {
+	var slept []time.Duration
+	tr := NewRateLimitTransport(nil)
+	tr.sleep = func(ctx context.Context, d time.Duration) error {
+		if d > 0 {
+			slept = append(slept, d)
+		}
+		return nil
+	}
+	return tr, &slept
+}
+
+// doRequest sends a background request, which is allowed to wait for retries and the rate limit.
+func doRequest(t *testing.T, tr *RateLimitTransport, method string, url string, body string) *http.Response {
+	t.Helper()
+
+	resp, err := sendRequest(BackgroundContext(context.Background()), tr, method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func sendRequest(ctx context.Context, tr *RateLimitTransport, method string, url string, body string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+func TestRateLimitTransportReadsBudget(t *testing.T) {
+	server := newStubServer(t)
+	server.remaining = 120
+	tr, slept := newTestTransport()
+
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	budget := tr.Budget()
+	if budget.Limit != 300 || budget.Remaining != 120 {
+		t.Errorf("budget = %d/%d, want 120/300", budget.Remaining, budget.Limit)
+	}
+	if !budget.Reset.Equal(server.reset.Truncate(time.Second)) {
+		t.Errorf("reset = %v, want %v", budget.Reset, server.reset)
+	}
+	if budget.Low() {
+		t.Errorf("budget should not be low")
+	}
+	if len(*slept) != 0 {
+		t.Errorf("slept %v with plenty of budget", *slept)
+	}
+}
+
+func TestRateLimitTransportRetriesIdempotentRequests(t *testing.T) {
+	server := newStubServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+	tr, slept := newTestTransport()
+
+	resp := doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if server.requestCount() != 3 {
+		t.Errorf("requests = %d, want 3", server.requestCount())
+	}
+	if len(*slept) != 2 {
+		t.Fatalf("slept %v, want 2 backoffs", *slept)
+	}
+	for i, d := range *slept {
+		base := RetryBaseDelay << i
+		if d < base/2 || d >= base*3/2 {
+			t.Errorf("backoff %d = %v, want between %v and %v", i, d, base/2, base*3/2)
+		}
+	}
+}
+
+func TestRateLimitTransportRetriesBody(t *testing.T) {
+	var bodies []string
+	var lock sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	tr, _ := newTestTransport()
+
+	doRequest(t, tr, http.MethodPut, server.URL, "title=news")
+
+	if len(bodies) != 2 || bodies[0] != "title=news" || bodies[1] != "title=news" {
+		t.Errorf("bodies = %q, want the body sent twice", bodies)
+	}
+}
+
+func TestRateLimitTransportGivesUp(t *testing.T) {
+	server := newStubServer(t, 500, 500, 500, 500, 500, 500)
+	tr, _ := newTestTransport()
+
+	resp := doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if server.requestCount() != MaxRetries+1 {
+		t.Errorf("requests = %d, want %d", server.requestCount(), MaxRetries+1)
+	}
+}
+
+func TestRateLimitTransportDoesNotRetryPost(t *testing.T) {
+	server := newStubServer(t, http.StatusServiceUnavailable)
+	tr, _ := newTestTransport()
+
+	resp := doRequest(t, tr, http.MethodPost, server.URL, "status=hello")
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if server.requestCount() != 1 {
+		t.Errorf("requests = %d, want 1", server.requestCount())
+	}
+}
+
+func TestRateLimitTransportHonoursRetryAfter(t *testing.T) {
+	server := newStubServer(t, http.StatusTooManyRequests)
+	server.headers = map[string]string{"Retry-After": "7"}
+	tr, slept := newTestTransport()
+
+	resp := doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 7*time.Second {
+		t.Errorf("slept %v, want [7s]", *slept)
+	}
+}
+
+func TestRateLimitTransportThrottlesWhenBudgetLow(t *testing.T) {
+	server := newStubServer(t)
+	server.remaining = 5
+	server.reset = time.Now().Add(time.Minute)
+	tr, slept := newTestTransport()
+
+	// first request learns the budget, the next is throttled.
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+	if !tr.Budget().Low() {
+		t.Fatalf("budget should be low")
+	}
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	if len(*slept) != 1 {
+		t.Fatalf("slept %v, want 1 throttle", *slept)
+	}
+	if d := (*slept)[0]; d <= 0 || d > time.Minute/6 {
+		t.Errorf("throttled for %v, want time until reset shared between the remaining requests", d)
+	}
+}
+
+func TestRateLimitTransportWaitsForResetWhenExhausted(t *testing.T) {
+	server := newStubServer(t)
+	server.remaining = 0
+	server.reset = time.Now().Add(30 * time.Second)
+	tr, slept := newTestTransport()
+
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+
+	if len(*slept) != 1 || (*slept)[0] < 25*time.Second || (*slept)[0] > 30*time.Second {
+		t.Errorf("slept %v, want until the limit resets", *slept)
+	}
+}
+
+func TestRateLimitTransportInteractiveFailsFastWhenExhausted(t *testing.T) {
+	server := newStubServer(t)
+	server.remaining = 0
+	server.reset = time.Now().Add(30 * time.Second)
+	tr, slept := newTestTransport()
+
+	doRequest(t, tr, http.MethodGet, server.URL, "")
+	_, err := sendRequest(context.Background(), tr, http.MethodGet, server.URL, "")
+
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("err = %v, want ErrRateLimited", err)
+	}
+	if server.requestCount() != 1 || len(*slept) != 0 {
+		t.Errorf("requests = %d, slept %v, want the request to fail without waiting", server.requestCount(), *slept)
+	}
+}
+
+func TestRateLimitTransportInteractiveDoesNotWaitForRetryAfter(t *testing.T) {
+	server := newStubServer(t, http.StatusTooManyRequests)
+	server.headers = map[string]string{"Retry-After": "7"}
+	tr, slept := newTestTransport()
+
+	resp, err := sendRequest(context.Background(), tr, http.MethodGet, server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+	if server.requestCount() != 1 || len(*slept) != 0 {
+		t.Errorf("requests = %d, slept %v, want no retry", server.requestCount(), *slept)
+	}
+}
+
+func TestRateLimitTransportInteractiveRetriesQuickly(t *testing.T) {
+	server := newStubServer(t, http.StatusServiceUnavailable)
+	tr, slept := newTestTransport()
+
+	resp, err := sendRequest(context.Background(), tr, http.MethodGet, server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK || len(*slept) != 1 {
+		t.Errorf("status = %d, slept %v, want a short backoff then success", resp.StatusCode, *slept)
+	}
+}
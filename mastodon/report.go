@@ -0,0 +1,91 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// why an account is being reported.
+const (
+	ReportCategorySpam      = "spam"
+	ReportCategoryLegal     = "legal"
+	ReportCategoryViolation = "violation"
+	ReportCategoryOther     = "other"
+)
+
+// reportStatusesLimit is how many of the author's recent statuses are offered to add to a report.
+const reportStatusesLimit = 20
+
+// Rule is one of the instance's rules. Reports in the violation category say which rules were broken.
+type Rule struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// Report is a report of an account (and optionally some of its statuses) to the moderators.
+type Report struct {
+	AccountID mastodon.ID
+	StatusIDs []mastodon.ID
+	Category  string
+
+	// only for ReportCategoryViolation
+	RuleIDs []string
+
+	Comment string
+
+	// also send the report to the moderators of the account's instance, if it's remote.
+	Forward bool
+}
+
+// GetInstanceRules returns the rules of the instance we're logged in to.
+func (c *MastodonBackend) GetInstanceRules() ([]Rule, error) {
+	var rules []Rule
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/instance/rules", nil, &rules)
+	if err != nil {
+		log.Errorf("unable to get instance rules : err %s", err)
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetAccountStatuses returns the account's most recent statuses, so they can be added to a report.
+func (c *MastodonBackend) GetAccountStatuses(accountID mastodon.ID) ([]mastodon.Status, error) {
+	statuses, err := c.client.GetAccountStatuses(c.ctx, accountID, &mastodon.Pagination{Limit: reportStatusesLimit})
+	if err != nil {
+		log.Errorf("unable to get statuses for account %s : err %s", accountID, err)
+		return nil, err
+	}
+
+	var nonPtrStatuses []mastodon.Status
+	for _, s := range statuses {
+		nonPtrStatuses = append(nonPtrStatuses, *s)
+	}
+	return nonPtrStatuses, nil
+}
+
+// Report sends the report to the moderators.
+func (c *MastodonBackend) Report(report Report) error {
+	params := url.Values{}
+	params.Set("account_id", string(report.AccountID))
+	for _, id := range report.StatusIDs {
+		params.Add("status_ids[]", string(id))
+	}
+	params.Set("category", report.Category)
+	if report.Category == ReportCategoryViolation {
+		for _, id := range report.RuleIDs {
+			params.Add("rule_ids[]", id)
+		}
+	}
+	params.Set("comment", report.Comment)
+	params.Set("forward", strconv.FormatBool(report.Forward))
+
+	err := c.doAPI(c.ctx, http.MethodPost, "/api/v1/reports", params, nil)
+	if err != nil {
+		log.Errorf("unable to report account %s : err %s", report.AccountID, err)
+		return err
+	}
+	return nil
+}
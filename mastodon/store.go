@@ -0,0 +1,687 @@
+package mastodon
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// only the newest statuses in each timeline are kept on disk.
+	MaxStoredTimelineStatuses = 400
+
+	// how often statuses and notifications older than the retention period are removed.
+	StorePruneInterval = time.Hour
+
+	// changes are batched up for this long before being written, so a burst of streamed
+	// statuses is one write.
+	StoreWriteDelay = 2 * time.Second
+)
+
+const storeSchema = `
+CREATE TABLE IF NOT EXISTS statuses (
+	id TEXT PRIMARY KEY,
+	created_at INTEGER NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS timeline_statuses (
+	timeline TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	status_id TEXT NOT NULL REFERENCES statuses(id) ON DELETE CASCADE,
+	PRIMARY KEY (timeline, status_id)
+);
+CREATE TABLE IF NOT EXISTS timeline_gaps (
+	timeline TEXT NOT NULL,
+	below_id TEXT NOT NULL REFERENCES statuses(id) ON DELETE CASCADE,
+	PRIMARY KEY (timeline, below_id)
+);
+CREATE TABLE IF NOT EXISTS outbox (
+	id INTEGER PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS notifications (
+	timeline TEXT NOT NULL,
+	id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	created_at INTEGER NOT NULL,
+	status_id TEXT NOT NULL,
+	data TEXT NOT NULL,
+	PRIMARY KEY (timeline, id)
+);
+`
+
+type sqliteDriver struct {
+	*sqlite.Driver
+}
+
+// Open turns on foreign keys for every connection, so deleting a status removes it from timelines.
+func (d sqliteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return conn, err
+	}
+	c := conn.(interface {
+		Exec(stmt string, args []driver.Value) (driver.Result, error)
+	})
+	if _, err := c.Exec("PRAGMA foreign_keys = on;", nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func init() {
+	sql.Register("sqlite3", sqliteDriver{Driver: &sqlite.Driver{}})
+}
+
+// Store keeps the timeline cache and notifications on disk, so columns can be displayed
+// straight away on startup. One database per account.
+// All methods are safe to call on a nil Store, in which case nothing is persisted.
+// The Queue methods are cheap enough to call while holding the cache lock, the changes are
+// written in the background.
+type Store struct {
+	db *sql.DB
+
+	// statuses and notifications older than this are removed.
+	retention time.Duration
+
+	// changes waiting to be written. Only the latest copy of each timeline is kept.
+	lock                 sync.Mutex
+	pendingStatuses      map[mastodon.ID]mastodon.Status
+	pendingDeletes       map[mastodon.ID]bool
+	pendingTimelines     map[string][]mastodon.ID
+	pendingNotifications map[string][]Notification
+	clearNotifications   bool
+
+	// only one flush at a time, so changes are written in order.
+	flushLock sync.Mutex
+
+	changed chan struct{}
+	done    chan struct{}
+}
+
+// StorePath is the database for the account, under ~/.shipdon
+func StorePath(accountName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	// account names are user@instance, but the instance may include a port.
+	name := strings.NewReplacer(":", "_", "/", "_", "\\", "_").Replace(accountName)
+	return filepath.Join(homeDir, ".shipdon", "cache-"+name+".db"), nil
+}
+
+// OpenStore opens (or creates) the database at path.
+func OpenStore(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Errorf("unable to create directory for cache %s : err %s", path, err)
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		log.Errorf("unable to open cache %s : err %s", path, err)
+		return nil, err
+	}
+
+	// sqlite only allows a single writer.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(storeSchema); err != nil {
+		log.Errorf("unable to create cache tables in %s : err %s", path, err)
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{
+		db:                   db,
+		retention:            retention,
+		pendingStatuses:      make(map[mastodon.ID]mastodon.Status),
+		pendingDeletes:       make(map[mastodon.ID]bool),
+		pendingTimelines:     make(map[string][]mastodon.ID),
+		pendingNotifications: make(map[string][]Notification),
+		changed:              make(chan struct{}, 1),
+		done:                 make(chan struct{}),
+	}
+	go s.writeChanges()
+	return s, nil
+}
+
+// Close writes any queued changes and closes the database.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	close(s.done)
+	s.Flush()
+	return s.db.Close()
+}
+
+// QueueStatuses queues statuses to be added (or replaced).
+func (s *Store) QueueStatuses(statuses []mastodon.Status) {
+	if s == nil || len(statuses) == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	for _, status := range statuses {
+		s.pendingStatuses[status.ID] = status
+		delete(s.pendingDeletes, status.ID)
+	}
+	s.lock.Unlock()
+	s.notifyChanged()
+}
+
+// QueueDeleteStatuses queues statuses to be removed.
+func (s *Store) QueueDeleteStatuses(ids []mastodon.ID) {
+	if s == nil || len(ids) == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	for _, id := range ids {
+		s.pendingDeletes[id] = true
+		delete(s.pendingStatuses, id)
+	}
+	s.lock.Unlock()
+	s.notifyChanged()
+}
+
+// QueueTimeline queues the statuses (and gap markers) in a timeline to be saved, see SaveTimeline.
+func (s *Store) QueueTimeline(timeline string, ids []mastodon.ID) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.pendingTimelines[timeline] = slices.Clone(ids[:min(len(ids), MaxStoredTimelineStatuses)])
+	s.lock.Unlock()
+	s.notifyChanged()
+}
+
+// QueueNotifications queues the notifications in a notifications timeline to be saved.
+func (s *Store) QueueNotifications(timeline string, notifications []Notification) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.pendingNotifications[timeline] = slices.Clone(notifications)
+	s.lock.Unlock()
+	s.notifyChanged()
+}
+
+// QueueClearNotifications queues removing the notifications from every notifications timeline.
+func (s *Store) QueueClearNotifications() {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	s.clearNotifications = true
+	clear(s.pendingNotifications)
+	s.lock.Unlock()
+	s.notifyChanged()
+}
+
+func (s *Store) notifyChanged() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+// writeChanges writes queued changes in the background until the store is closed.
+func (s *Store) writeChanges() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.changed:
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(StoreWriteDelay):
+		}
+		s.Flush()
+	}
+}
+
+// Flush writes the queued changes now.
+func (s *Store) Flush() error {
+	if s == nil {
+		return nil
+	}
+
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+
+	s.lock.Lock()
+	statuses := make([]mastodon.Status, 0, len(s.pendingStatuses))
+	for _, status := range s.pendingStatuses {
+		statuses = append(statuses, status)
+	}
+	deletes := make([]mastodon.ID, 0, len(s.pendingDeletes))
+	for id := range s.pendingDeletes {
+		deletes = append(deletes, id)
+	}
+	timelines := s.pendingTimelines
+	notifications := s.pendingNotifications
+	clearNotifications := s.clearNotifications
+
+	s.pendingStatuses = make(map[mastodon.ID]mastodon.Status)
+	s.pendingDeletes = make(map[mastodon.ID]bool)
+	s.pendingTimelines = make(map[string][]mastodon.ID)
+	s.pendingNotifications = make(map[string][]Notification)
+	s.clearNotifications = false
+	s.lock.Unlock()
+
+	// statuses have to be saved before the timelines that refer to them.
+	var errs []error
+	if clearNotifications {
+		errs = append(errs, s.ClearNotifications())
+	}
+	errs = append(errs, s.DeleteStatuses(deletes), s.SaveStatuses(statuses))
+	for timeline, ids := range timelines {
+		errs = append(errs, s.SaveTimeline(timeline, ids))
+	}
+	for timeline, n := range notifications {
+		errs = append(errs, s.SaveNotifications(timeline, n))
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveStatuses adds (or replaces) statuses.
+func (s *Store) SaveStatuses(statuses []mastodon.Status) error {
+	if s == nil || len(statuses) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Errorf("unable to save statuses : err %s", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, status := range statuses {
+		data, err := json.Marshal(status)
+		if err != nil {
+			log.Errorf("unable to marshal status %s : err %s", status.ID, err)
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO statuses (id, created_at, data) VALUES (?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET data = excluded.data`, string(status.ID), status.CreatedAt.Unix(), string(data))
+		if err != nil {
+			log.Errorf("unable to save status %s : err %s", status.ID, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteStatuses removes statuses, and with them their place in any timeline.
+func (s *Store) DeleteStatuses(ids []mastodon.ID) error {
+	if s == nil || len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Errorf("unable to delete statuses : err %s", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM statuses WHERE id = ?`, string(id)); err != nil {
+			log.Errorf("unable to delete status %s : err %s", id, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveTimeline replaces the statuses (and gap markers) in a timeline, newest first. Only the newest
+// MaxStoredTimelineStatuses are kept, and statuses that haven't been saved are skipped.
+func (s *Store) SaveTimeline(timeline string, ids []mastodon.ID) error {
+	if s == nil {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Errorf("unable to save timeline %s : err %s", timeline, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"timeline_statuses", "timeline_gaps"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE timeline = ?`, timeline); err != nil {
+			log.Errorf("unable to clear timeline %s : err %s", timeline, err)
+			return err
+		}
+	}
+
+	for i, id := range ids[:min(len(ids), MaxStoredTimelineStatuses)] {
+		query := `INSERT OR IGNORE INTO timeline_statuses (timeline, position, status_id)
+			SELECT ?, ?, id FROM statuses WHERE id = ?`
+		args := []interface{}{timeline, i, string(id)}
+		if IsGapID(id) {
+			query = `INSERT OR IGNORE INTO timeline_gaps (timeline, below_id)
+				SELECT ?, id FROM statuses WHERE id = ?`
+			args = []interface{}{timeline, string(gapBelowID(id))}
+		}
+
+		if _, err := tx.Exec(query, args...); err != nil {
+			log.Errorf("unable to save timeline %s : err %s", timeline, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveNotifications replaces the notifications in a notifications timeline, newest first.
+func (s *Store) SaveNotifications(timeline string, notifications []Notification) error {
+	if s == nil {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		log.Errorf("unable to save notifications %s : err %s", timeline, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM notifications WHERE timeline = ?`, timeline); err != nil {
+		log.Errorf("unable to clear notifications %s : err %s", timeline, err)
+		return err
+	}
+
+	for i, n := range notifications {
+		data, err := json.Marshal(n)
+		if err != nil {
+			log.Errorf("unable to marshal notification %s : err %s", n.ID, err)
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT OR IGNORE INTO notifications (timeline, id, position, created_at, status_id, data)
+			VALUES (?, ?, ?, ?, ?, ?)`, timeline, string(n.ID), i, n.CreatedAt.Unix(), string(n.StatusID), string(data))
+		if err != nil {
+			log.Errorf("unable to save notification %s : err %s", n.ID, err)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ClearNotifications removes the notifications from every notifications timeline.
+func (s *Store) ClearNotifications() error {
+	if s == nil {
+		return nil
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM notifications`); err != nil {
+		log.Errorf("unable to clear notifications : err %s", err)
+		return err
+	}
+	return nil
+}
+
+// SaveOutboxItem adds (or replaces) an item waiting in the outbox.
+func (s *Store) SaveOutboxItem(item OutboxItem) error {
+	if s == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		log.Errorf("unable to marshal outbox item %d : err %s", item.ID, err)
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT OR REPLACE INTO outbox (id, data) VALUES (?, ?)`, item.ID, string(data))
+	if err != nil {
+		log.Errorf("unable to save outbox item %d : err %s", item.ID, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteOutboxItem removes an item from the outbox.
+func (s *Store) DeleteOutboxItem(id int64) error {
+	if s == nil {
+		return nil
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM outbox WHERE id = ?`, id); err != nil {
+		log.Errorf("unable to delete outbox item %d : err %s", id, err)
+		return err
+	}
+	return nil
+}
+
+// LoadOutbox returns the items waiting in the outbox, oldest first.
+func (s *Store) LoadOutbox() ([]OutboxItem, error) {
+	var items []OutboxItem
+	if s == nil {
+		return items, nil
+	}
+
+	rows, err := s.db.Query(`SELECT data FROM outbox ORDER BY id`)
+	if err != nil {
+		log.Errorf("unable to load outbox : err %s", err)
+		return items, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		var item OutboxItem
+		if err := rows.Scan(&data); err != nil {
+			log.Errorf("unable to load outbox item : err %s", err)
+			return items, err
+		}
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			log.Errorf("unable to unmarshal outbox item : err %s", err)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Prune removes notifications and statuses older than the retention period. Statuses that
+// notifications still refer to (eg. a recent favourite of an old status) are kept.
+func (s *Store) Prune() error {
+	if s == nil {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-s.retention).Unix()
+
+	if _, err := s.db.Exec(`DELETE FROM notifications WHERE created_at < ?`, cutoff); err != nil {
+		log.Errorf("unable to prune notifications : err %s", err)
+		return err
+	}
+
+	_, err := s.db.Exec(`DELETE FROM statuses WHERE created_at < ?
+		AND id NOT IN (SELECT status_id FROM notifications)`, cutoff)
+	if err != nil {
+		log.Errorf("unable to prune statuses : err %s", err)
+		return err
+	}
+	return nil
+}
+
+// LoadTimelines loads the saved statuses and timelines into the cache.
+func (s *Store) LoadTimelines(tc *TimelineCache) error {
+	if s == nil {
+		return nil
+	}
+
+	statuses := make(map[mastodon.ID]mastodon.Status)
+	rows, err := s.db.Query(`SELECT data FROM statuses`)
+	if err != nil {
+		log.Errorf("unable to load statuses : err %s", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		var status mastodon.Status
+		if err := rows.Scan(&data); err != nil {
+			log.Errorf("unable to load status : err %s", err)
+			return err
+		}
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			log.Errorf("unable to unmarshal status : err %s", err)
+			continue
+		}
+		statuses[status.ID] = status
+	}
+	if err := rows.Err(); err != nil {
+		log.Errorf("unable to load statuses : err %s", err)
+		return err
+	}
+
+	timelines := make(map[string][]mastodon.ID)
+	timelineRows, err := s.db.Query(`SELECT timeline, status_id FROM timeline_statuses ORDER BY timeline, position`)
+	if err != nil {
+		log.Errorf("unable to load timelines : err %s", err)
+		return err
+	}
+	defer timelineRows.Close()
+
+	for timelineRows.Next() {
+		var timeline, id string
+		if err := timelineRows.Scan(&timeline, &id); err != nil {
+			log.Errorf("unable to load timeline : err %s", err)
+			return err
+		}
+		if _, ok := statuses[mastodon.ID(id)]; ok {
+			timelines[timeline] = append(timelines[timeline], mastodon.ID(id))
+		}
+	}
+	if err := timelineRows.Err(); err != nil {
+		log.Errorf("unable to load timelines : err %s", err)
+		return err
+	}
+
+	gapRows, err := s.db.Query(`SELECT timeline, below_id FROM timeline_gaps`)
+	if err != nil {
+		log.Errorf("unable to load timeline gaps : err %s", err)
+		return err
+	}
+	defer gapRows.Close()
+
+	for gapRows.Next() {
+		var timeline, belowID string
+		if err := gapRows.Scan(&timeline, &belowID); err != nil {
+			log.Errorf("unable to load timeline gap : err %s", err)
+			return err
+		}
+		if slices.Contains(timelines[timeline], mastodon.ID(belowID)) {
+			timelines[timeline] = append(timelines[timeline], GapID(mastodon.ID(belowID)))
+			sortTimeline(timelines[timeline])
+		}
+	}
+	if err := gapRows.Err(); err != nil {
+		log.Errorf("unable to load timeline gaps : err %s", err)
+		return err
+	}
+
+	tc.load(statuses, timelines)
+	return nil
+}
+
+// LoadNotifications returns the saved notifications, keyed by timelineID.
+func (s *Store) LoadNotifications() (map[string][]Notification, error) {
+	notifications := make(map[string][]Notification)
+	if s == nil {
+		return notifications, nil
+	}
+
+	rows, err := s.db.Query(`SELECT timeline, data FROM notifications ORDER BY timeline, position`)
+	if err != nil {
+		log.Errorf("unable to load notifications : err %s", err)
+		return notifications, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timeline, data string
+		var n Notification
+		if err := rows.Scan(&timeline, &data); err != nil {
+			log.Errorf("unable to load notification : err %s", err)
+			return notifications, err
+		}
+		if err := json.Unmarshal([]byte(data), &n); err != nil {
+			log.Errorf("unable to unmarshal notification : err %s", err)
+			continue
+		}
+		notifications[timeline] = append(notifications[timeline], n)
+	}
+	return notifications, rows.Err()
+}
+
+// openStore opens the account's database and loads the timelines, notifications and outbox saved last time.
+// If the database can't be opened, the cache is only kept in memory.
+func (c *MastodonBackend) openStore() {
+	path, err := StorePath(c.account.Name)
+	if err != nil {
+		log.Errorf("unable to find cache for %s : err %s", c.account.Name, err)
+		return
+	}
+
+	store, err := OpenStore(path, c.config.CacheRetention())
+	if err != nil {
+		return
+	}
+
+	store.Prune()
+	store.LoadTimelines(c.timelineMessageCache)
+	notifications, _ := store.LoadNotifications()
+	outbox, _ := store.LoadOutbox()
+
+	c.lock.Lock()
+	c.store = store
+	c.notifications = notifications
+	c.outbox = outbox
+	for _, item := range outbox {
+		c.nextOutboxID = max(c.nextOutboxID, item.ID)
+	}
+	c.lock.Unlock()
+
+	c.timelineMessageCache.SetStore(store)
+	go c.pruneStore()
+}
+
+// pruneStore regularly removes statuses and notifications older than the retention period.
+func (c *MastodonBackend) pruneStore() {
+	for {
+		time.Sleep(StorePruneInterval)
+		c.store.Prune()
+	}
+}
@@ -0,0 +1,248 @@
+package mastodon
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-mastodon"
+)
+
+func openTestStore(t *testing.T, path string) *Store {
+	t.Helper()
+
+	store, err := OpenStore(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// reopen closes the store (writing anything queued) and opens it again.
+func reopen(t *testing.T, store *Store, path string) *Store {
+	t.Helper()
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return openTestStore(t, path)
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store := openTestStore(t, path)
+	defer func() { store.Close() }()
+
+	cache := NewTimelineCache()
+	cache.SetStore(store)
+
+	boost := testStatus(100)
+	boosted := testStatus(5)
+	boost.Reblog = &boosted
+	cache.InsertIntoTimeline("home", append(statusRange(21, 30), boost))
+	cache.InsertIntoTimeline("home", statusRange(1, 10))
+	cache.AddGap("home", "10")
+	cache.AddToTimeline("bookmarks", true, []mastodon.Status{testStatus(3), testStatus(9), testStatus(1)}, false)
+
+	notifications := []Notification{
+		{ID: "2", Type: "favourite", CreatedAt: time.Now(), Account: mastodon.Account{ID: "7", Acct: "someone"}, StatusID: "3"},
+		{ID: "1", Type: "follow", CreatedAt: time.Now(), Account: mastodon.Account{ID: "8"}},
+	}
+	store.QueueNotifications("notifications", notifications)
+
+	outbox := OutboxItem{ID: 1, Kind: OutboxPost, Toot: &mastodon.Toot{Status: "hello"}, IdempotencyKey: "abc", QueuedAt: time.Now()}
+	if err := store.SaveOutboxItem(outbox); err != nil {
+		t.Fatal(err)
+	}
+
+	store = reopen(t, store, path)
+	loaded := NewTimelineCache()
+	if err := store.LoadTimelines(loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	home, _ := loaded.GetTimelineDetails("home")
+	want := append(append([]mastodon.ID{"100"}, idRange(21, 30)...), GapID("10"))
+	want = append(want, idRange(1, 10)...)
+	if !slices.Equal(home.messages, want) {
+		t.Errorf("home = %v, want %v", home.messages, want)
+	}
+	if home.sinceID != "100" {
+		t.Errorf("home sinceID = %q, want 100", home.sinceID)
+	}
+
+	bookmarks, _ := loaded.GetTimelineDetails("bookmarks")
+	if want := []mastodon.ID{"3", "9", "1"}; !slices.Equal(bookmarks.messages, want) {
+		t.Errorf("bookmarks = %v, want %v (order kept)", bookmarks.messages, want)
+	}
+
+	statuses := loaded.GetAllStatusForTimeline("home")
+	if statuses[0].Reblog == nil || statuses[0].Reblog.ID != "5" {
+		t.Errorf("boost loaded as %+v", statuses[0])
+	}
+	if statuses[1].Content != "<p>status 30</p>" {
+		t.Errorf("status loaded as %+v", statuses[1])
+	}
+
+	gotNotifications, err := store.LoadNotifications()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := gotNotifications["notifications"]
+	if len(n) != 2 || n[0].ID != "2" || n[0].Account.Acct != "someone" || n[0].StatusID != "3" || n[1].ID != "1" {
+		t.Errorf("notifications = %+v", n)
+	}
+
+	gotOutbox, err := store.LoadOutbox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotOutbox) != 1 || gotOutbox[0].Toot.Status != "hello" || gotOutbox[0].IdempotencyKey != "abc" {
+		t.Errorf("outbox = %+v", gotOutbox)
+	}
+}
+
+func TestStoreQueuedChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(cache *TimelineCache, store *Store)
+		want   []mastodon.ID
+	}{
+		{
+			name:   "nothing changed",
+			change: func(cache *TimelineCache, store *Store) {},
+			want:   idRange(1, 5),
+		},
+		{
+			name: "deleted",
+			change: func(cache *TimelineCache, store *Store) {
+				cache.DeleteStatus("3")
+			},
+			want: []mastodon.ID{"5", "4", "2", "1"},
+		},
+		{
+			name: "added then deleted before being written",
+			change: func(cache *TimelineCache, store *Store) {
+				cache.InsertIntoTimeline("home", []mastodon.Status{testStatus(6)})
+				cache.DeleteStatus("6")
+			},
+			want: idRange(1, 5),
+		},
+		{
+			name: "deleted then added again",
+			change: func(cache *TimelineCache, store *Store) {
+				cache.DeleteStatus("5")
+				cache.InsertIntoTimeline("home", []mastodon.Status{testStatus(5)})
+			},
+			want: idRange(1, 5),
+		},
+		{
+			name: "cleared",
+			change: func(cache *TimelineCache, store *Store) {
+				cache.ClearTimeline("home")
+			},
+		},
+		{
+			name: "only the newest are kept",
+			change: func(cache *TimelineCache, store *Store) {
+				cache.InsertIntoTimeline("home", statusRange(6, MaxStoredTimelineStatuses+10))
+			},
+			want: idRange(11, MaxStoredTimelineStatuses+10),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cache.db")
+			store := openTestStore(t, path)
+			defer func() { store.Close() }()
+
+			cache := NewTimelineCache()
+			cache.SetStore(store)
+
+			cache.InsertIntoTimeline("home", statusRange(1, 5))
+			if err := store.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			tc.change(cache, store)
+
+			store = reopen(t, store, path)
+			loaded := NewTimelineCache()
+			if err := store.LoadTimelines(loaded); err != nil {
+				t.Fatal(err)
+			}
+			home, _ := loaded.GetTimelineDetails("home")
+			if !slices.Equal(home.messages, tc.want) {
+				t.Errorf("home = %v, want %v", home.messages, tc.want)
+			}
+		})
+	}
+}
+
+func TestStoreWritesInBackground(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "cache.db"))
+	defer store.Close()
+	store.QueueStatuses(statusRange(1, 3))
+	store.QueueTimeline("home", idRange(1, 3))
+
+	deadline := time.Now().Add(StoreWriteDelay + 5*time.Second)
+	for {
+		loaded := NewTimelineCache()
+		store.LoadTimelines(loaded)
+		if home, _ := loaded.GetTimelineDetails("home"); slices.Equal(home.messages, idRange(1, 3)) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("queued changes weren't written")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "cache.db"))
+	defer store.Close()
+
+	old := func(id int) mastodon.Status {
+		s := testStatus(id)
+		s.CreatedAt = time.Now().Add(-48 * time.Hour)
+		return s
+	}
+	store.SaveStatuses([]mastodon.Status{old(1), old(2), testStatus(3)})
+	store.SaveTimeline("home", idRange(1, 3))
+	store.SaveNotifications("notifications", []Notification{
+		{ID: "1", Type: "favourite", CreatedAt: time.Now(), StatusID: "2"},
+		{ID: "2", Type: "mention", CreatedAt: time.Now().Add(-48 * time.Hour), StatusID: "1"},
+	})
+
+	if err := store.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewTimelineCache()
+	store.LoadTimelines(loaded)
+	home, _ := loaded.GetTimelineDetails("home")
+	if want := []mastodon.ID{"3", "2"}; !slices.Equal(home.messages, want) {
+		t.Errorf("home = %v, want %v (old status kept for the recent notification)", home.messages, want)
+	}
+
+	notifications, _ := store.LoadNotifications()
+	if n := notifications["notifications"]; len(n) != 1 || n[0].ID != "1" {
+		t.Errorf("notifications = %+v, want only the recent one", n)
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var store *Store
+	store.QueueStatuses(statusRange(1, 3))
+	store.QueueTimeline("home", idRange(1, 3))
+	store.QueueClearNotifications()
+	if err := store.Flush(); err != nil {
+		t.Error(err)
+	}
+	if err := store.LoadTimelines(NewTimelineCache()); err != nil {
+		t.Error(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Error(err)
+	}
+}
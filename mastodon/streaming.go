@@ -0,0 +1,268 @@
+package mastodon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kpfaulkner/shipdon/events"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// backoff between reconnect attempts to the streaming API. Doubles on every failure up to the max.
+	StreamMinBackoff = 1 * time.Second
+	StreamMaxBackoff = 2 * time.Minute
+
+	// maximum size of a single line from the streaming API (statuses can be quite large).
+	streamMaxLineSize = 4 * 1024 * 1024
+)
+
+// timelineStream is a single connection to the Mastodon streaming API feeding a timeline.
+type timelineStream struct {
+	refreshType events.RefreshType
+	timelineID  string
+
+	// path and query for the streaming endpoint. eg. "user" or "hashtag" with tag=foo
+	path   string
+	params url.Values
+
+	cancel context.CancelFunc
+
+	// connected is set while we have a live connection to the server. While not connected
+	// the timeline needs to be kept up to date via polling.
+	connected atomic.Bool
+}
+
+// StartStream opens a streaming connection for the timeline (if the timeline type supports streaming).
+// The connection is kept open (reconnecting with backoff) until StopStream is called.
+// Timeline types that cannot be streamed are ignored and will continue to rely on polling.
+func (c *MastodonBackend) StartStream(refreshType events.RefreshType, timelineID string) {
+	var path string
+	params := url.Values{}
+
+	switch refreshType {
+	case events.HOME_REFRESH:
+		path = "user"
+	case events.NOTIFICATION_REFRESH:
+		path = "user/notification"
+	case events.LIST_REFRESH:
+		path = "list"
+		params.Set("list", timelineID)
+	case events.HASHTAG_REFRESH:
+		path = "hashtag"
+		params.Set("tag", timelineID)
+	case events.LOCAL_REFRESH, events.FEDERATED_REFRESH:
+		pt := ParsePublicTimeline(timelineID)
+		path = pt.streamPath()
+		if pt.OnlyMedia {
+			params.Set("only_media", "true")
+		}
+	default:
+		return
+	}
+
+	c.timelineMessageCache.SetTimelineContext(timelineID, FilterContextForRefreshType(refreshType))
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.streams[timelineID]; ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	s := &timelineStream{
+		refreshType: refreshType,
+		timelineID:  timelineID,
+		path:        path,
+		params:      params,
+		cancel:      cancel,
+	}
+	c.streams[timelineID] = s
+
+	go c.runStream(ctx, s)
+}
+
+// StopStream closes the streaming connection for a timeline (if there is one).
+func (c *MastodonBackend) StopStream(timelineID string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if s, ok := c.streams[timelineID]; ok {
+		s.cancel()
+		delete(c.streams, timelineID)
+	}
+}
+
+// IsStreaming returns true if the timeline currently has a live streaming connection.
+// If false, the timeline should be refreshed by polling.
+func (c *MastodonBackend) IsStreaming(timelineID string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	s, ok := c.streams[timelineID]
+	return ok && s.connected.Load()
+}
+
+// runStream keeps a stream connected until the context is cancelled, backing off between
+// failed connection attempts.
+func (c *MastodonBackend) runStream(ctx context.Context, s *timelineStream) {
+	backoff := StreamMinBackoff
+	for {
+		start := time.Now()
+		err := c.readStream(ctx, s)
+		s.connected.Store(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// if the connection was healthy for a while, then start backing off from scratch again.
+		if time.Since(start) > StreamMaxBackoff {
+			backoff = StreamMinBackoff
+		}
+
+		// jitter so all the streams dont reconnect at exactly the same time.
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		log.Warnf("stream for timeline %s disconnected, reconnecting in %s : err %v", s.timelineID, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > StreamMaxBackoff {
+			backoff = StreamMaxBackoff
+		}
+	}
+}
+
+// readStream connects to the streaming API and processes events until the connection drops.
+func (c *MastodonBackend) readStream(ctx context.Context, s *timelineStream) error {
+	u, err := url.Parse(c.streamingURL())
+	if err != nil {
+		return err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v1/streaming/" + s.path
+	u.RawQuery = s.params.Encode()
+
+	// streams stay open, so mustn't get the timeout interactive requests have.
+	req, err := http.NewRequestWithContext(BackgroundContext(ctx), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.client.Config.AccessToken)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.client.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from streaming API : %s", resp.Status)
+	}
+
+	s.connected.Store(true)
+	log.Debugf("stream for timeline %s connected", s.timelineID)
+
+	// catch up on anything we missed while not connected.
+	events.FireEvent(events.NewRefreshEvent(c.AccountName(), s.timelineID, false, s.refreshType))
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), streamMaxLineSize)
+
+	var name string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// blank line terminates an event.
+			if name != "" {
+				c.handleStreamEvent(s, name, strings.Join(data, "\n"))
+			}
+			name = ""
+			data = nil
+		case strings.HasPrefix(line, ":"):
+			// comment/heartbeat.
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream closed by server")
+}
+
+// handleStreamEvent pushes a single streaming payload into the cache.
+func (c *MastodonBackend) handleStreamEvent(s *timelineStream, name string, data string) {
+	switch name {
+	case "update":
+		if s.refreshType == events.NOTIFICATION_REFRESH {
+			return
+		}
+		var status mastodon.Status
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			log.Errorf("unable to decode streamed status for timeline %s : err %s", s.timelineID, err)
+			return
+		}
+		c.timelineMessageCache.InsertIntoTimeline(s.timelineID, []mastodon.Status{status})
+
+	case "status.update":
+		var status mastodon.Status
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			log.Errorf("unable to decode streamed status edit for timeline %s : err %s", s.timelineID, err)
+			return
+		}
+		c.timelineMessageCache.UpdateStatus(status)
+
+	case "delete":
+		c.timelineMessageCache.DeleteStatus(mastodon.ID(data))
+
+	case "notification":
+		if s.refreshType != events.NOTIFICATION_REFRESH {
+			return
+		}
+		var notification mastodon.Notification
+		if err := json.Unmarshal([]byte(data), &notification); err != nil {
+			log.Errorf("unable to decode streamed notification : err %s", err)
+			return
+		}
+		if ParseNotificationFilter(s.timelineID).Accepts(notification.Type) {
+			c.addNotifications(s.timelineID, []*mastodon.Notification{&notification}, false)
+		}
+
+	default:
+		// filters_changed, announcements etc. Not interested (yet).
+		return
+	}
+
+	events.FireEvent(events.NewTimelineUpdatedEvent(c.AccountName(), s.timelineID))
+}
+
+// streamingURL returns the base URL for the streaming API. Instances can host streaming
+// on a separate domain, which is advertised in the instance details.
+func (c *MastodonBackend) streamingURL() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.streamingBaseURL != "" {
+		return c.streamingBaseURL
+	}
+	return c.client.Config.Server
+}
@@ -0,0 +1,67 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+)
+
+// getThread gets the whole conversation around a status. Ancestors first (oldest first), then the status
+// itself, then the replies ordered as a tree (each reply directly after the status it replies to).
+func (c *MastodonBackend) getThread(statusID mastodon.ID) ([]*mastodon.Status, error) {
+	// called directly (rather than with go-mastodon) to keep the server's filter results.
+	var status filteredStatus
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/statuses/"+string(statusID), nil, &status)
+	if err != nil {
+		log.Errorf("unable to get statusID %s : err %s", statusID, err)
+		return nil, err
+	}
+
+	var filteredContext struct {
+		Ancestors   []*filteredStatus `json:"ancestors"`
+		Descendants []*filteredStatus `json:"descendants"`
+	}
+	err = c.doAPI(c.ctx, http.MethodGet, "/api/v1/statuses/"+string(statusID)+"/context", nil, &filteredContext)
+	if err != nil {
+		log.Errorf("unable to get context for statusID %s : err %s", statusID, err)
+		return nil, err
+	}
+
+	c.timelineMessageCache.AddFilterResults(&status)
+	c.timelineMessageCache.AddFilterResults(filteredContext.Ancestors...)
+	c.timelineMessageCache.AddFilterResults(filteredContext.Descendants...)
+
+	context := &mastodon.Context{}
+	for _, s := range filteredContext.Ancestors {
+		context.Ancestors = append(context.Ancestors, s.status())
+	}
+	for _, s := range filteredContext.Descendants {
+		context.Descendants = append(context.Descendants, s.status())
+	}
+	return threadOrder(status.status(), context), nil
+}
+
+// threadOrder flattens the context into display order, with descendants depth first.
+func threadOrder(status *mastodon.Status, context *mastodon.Context) []*mastodon.Status {
+	statuses := append([]*mastodon.Status{}, context.Ancestors...)
+	statuses = append(statuses, status)
+
+	// replies keyed by the status they're replying to. Keeps the order the server gave us (oldest first).
+	replies := make(map[mastodon.ID][]*mastodon.Status)
+	for _, s := range context.Descendants {
+		if parent, ok := s.InReplyToID.(string); ok {
+			replies[mastodon.ID(parent)] = append(replies[mastodon.ID(parent)], s)
+		}
+	}
+
+	var addReplies func(id mastodon.ID)
+	addReplies = func(id mastodon.ID) {
+		for _, r := range replies[id] {
+			statuses = append(statuses, r)
+			addReplies(r.ID)
+		}
+	}
+	addReplies(status.ID)
+
+	return statuses
+}
@@ -0,0 +1,90 @@
+package mastodon
+
+import (
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// how many of each kind of trend to get.
+	TrendsLimit = 10
+)
+
+// TrendingLink is a link card that is being shared a lot, with its daily usage.
+type TrendingLink struct {
+	mastodon.Card
+	History []mastodon.History `json:"history"`
+}
+
+// Trends are the trending tags and links. Trending statuses are kept as a regular timeline.
+type Trends struct {
+	Tags  []*mastodon.Tag
+	Links []*TrendingLink
+}
+
+// GetTrends returns the trending tags and links from the last refresh.
+func (c *MastodonBackend) GetTrends() (Trends, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.trends, nil
+}
+
+// refreshTrends gets the trending tags, statuses and links. Statuses are added to the timeline.
+func (c *MastodonBackend) refreshTrends(timelineID string) error {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(TrendsLimit))
+
+	var trends Trends
+	err := c.doAPI(c.ctx, http.MethodGet, "/api/v1/trends/tags", params, &trends.Tags)
+	if err != nil {
+		log.Errorf("unable to get trending tags : err %s", err)
+		return err
+	}
+
+	err = c.doAPI(c.ctx, http.MethodGet, "/api/v1/trends/links", params, &trends.Links)
+	if err != nil {
+		log.Errorf("unable to get trending links : err %s", err)
+		return err
+	}
+
+	var statuses []mastodon.Status
+	err = c.doAPI(c.ctx, http.MethodGet, "/api/v1/trends/statuses", params, &statuses)
+	if err != nil {
+		log.Errorf("unable to get trending statuses : err %s", err)
+		return err
+	}
+
+	c.lock.Lock()
+	c.trends = trends
+	c.lock.Unlock()
+
+	// kept in trending order.
+	_, err = c.timelineMessageCache.AddToTimeline(timelineID, true, statuses, false)
+	return err
+}
+
+// HistoryUses returns the daily uses from a trend's history, oldest first. The API gives us
+// newest first, with the numbers as strings.
+func HistoryUses(history []mastodon.History) []int {
+	uses := make([]int, len(history))
+	for i, h := range history {
+		n, err := strconv.Atoi(h.Uses)
+		if err != nil {
+			log.Debugf("unable to parse uses %s : err %s", h.Uses, err)
+		}
+		uses[len(history)-1-i] = n
+	}
+	return uses
+}
+
+// TotalUses is the sum of the daily uses.
+func TotalUses(history []mastodon.History) int {
+	total := 0
+	for _, n := range HistoryUses(history) {
+		total += n
+	}
+	return total
+}
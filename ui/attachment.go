@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/shiny/materialdesign/icons"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Mastodon only allows 4 attachments per status.
+	MaxAttachments = 4
+
+	// how often to check if the server has finished processing an upload, and how many times to check.
+	mediaProcessingPollDelay = 2 * time.Second
+	mediaProcessingMaxPolls  = 60
+
+	attachmentThumbnailSize = 64
+)
+
+type attachmentState int
+
+const (
+	attachmentUploading attachmentState = iota
+	attachmentProcessing
+	attachmentReady
+	attachmentFailed
+)
+
+func (s attachmentState) String() string {
+	switch s {
+	case attachmentUploading:
+		return "uploading"
+	case attachmentProcessing:
+		return "processing"
+	case attachmentReady:
+		return "ready"
+	default:
+		return "upload failed"
+	}
+}
+
+// composeAttachment is media attached to the toot currently being composed.
+type composeAttachment struct {
+	fileName string
+
+	// nil if the file isn't an image we can decode (eg. video)
+	thumbnail *widget.Image
+
+	// account the media was uploaded to. Can't be used when posting from another account.
+	backend mastodon2.Backend
+
+	// already attached to the status being edited. The server won't let us change it via UpdateMedia.
+	attached bool
+
+	description  widget.Editor
+	focus        widget.Editor
+	removeButton widget.Clickable
+
+	// state and mediaID are updated by the upload goroutine.
+	lock    sync.Mutex
+	state   attachmentState
+	mediaID mastodon.ID
+}
+
+func newComposeAttachment(backend mastodon2.Backend, fileName string, data []byte) *composeAttachment {
+	a := &composeAttachment{
+		fileName: fileName,
+		backend:  backend,
+		state:    attachmentUploading,
+	}
+	a.description.SingleLine = false
+	a.focus.SingleLine = true
+
+	if img, _, err := image.Decode(bytes.NewReader(data)); err == nil {
+		a.thumbnail = &widget.Image{
+			Src: paint.NewImageOp(resizeImage(img, attachmentThumbnailSize, 0)),
+			Fit: widget.Contain,
+		}
+	}
+	return a
+}
+
+// newExistingAttachment is media that has already been uploaded, eg. when editing or redrafting a status.
+func newExistingAttachment(backend mastodon2.Backend, attachment mastodon.Attachment, attached bool) *composeAttachment {
+	a := &composeAttachment{
+		fileName: path.Base(attachment.URL),
+		backend:  backend,
+		attached: attached,
+		state:    attachmentReady,
+		mediaID:  attachment.ID,
+	}
+	a.description.SingleLine = false
+	a.description.SetText(attachment.Description)
+	a.focus.SingleLine = true
+	return a
+}
+
+func (a *composeAttachment) setState(state attachmentState, mediaID mastodon.ID) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.state = state
+	a.mediaID = mediaID
+}
+
+func (a *composeAttachment) getState() (attachmentState, mastodon.ID) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.state, a.mediaID
+}
+
+// focalPoint parses the focus editor ("x,y" with both between -1.0 and 1.0).
+// Defaults to the centre of the image.
+func (a *composeAttachment) focalPoint() (float64, float64) {
+	parts := strings.Split(a.focus.Text(), ",")
+	if len(parts) != 2 {
+		return 0, 0
+	}
+
+	x, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0
+	}
+	y, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return 0, 0
+	}
+	return clampFocus(x), clampFocus(y)
+}
+
+func clampFocus(f float64) float64 {
+	if f < -1 {
+		return -1
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// upload sends the media to the server, then polls until the server has finished processing it.
+// Blocking, so should be called in its own goroutine. invalidate is called whenever the state changes.
+func (a *composeAttachment) upload(data []byte, invalidate func()) {
+	defer invalidate()
+
+	attachment, err := a.backend.UploadMedia(a.fileName, data)
+	if err != nil {
+		a.setState(attachmentFailed, "")
+		return
+	}
+
+	for i := 0; attachment.URL == ""; i++ {
+		if i == mediaProcessingMaxPolls {
+			log.Errorf("gave up waiting for media %s to be processed", attachment.ID)
+			a.setState(attachmentFailed, attachment.ID)
+			return
+		}
+
+		a.setState(attachmentProcessing, attachment.ID)
+		invalidate()
+		time.Sleep(mediaProcessingPollDelay)
+
+		attachment, err = a.backend.GetMedia(attachment.ID)
+		if err != nil {
+			a.setState(attachmentFailed, "")
+			return
+		}
+	}
+
+	a.setState(attachmentReady, attachment.ID)
+}
+
+// Layout displays thumbnail, upload state and the alt text/focal point editors.
+func (a *composeAttachment) Layout(gtx C, th *ShipdonTheme) D {
+	state, _ := a.getState()
+
+	return layout.Flex{
+		Axis: layout.Horizontal,
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			size := gtx.Dp(attachmentThumbnailSize)
+			gtx.Constraints.Min = image.Point{X: size, Y: size}
+			gtx.Constraints.Max = gtx.Constraints.Min
+			if a.thumbnail == nil {
+				return defaultAvatar.Layout(gtx)
+			}
+			return a.thumbnail.Layout(gtx)
+		}),
+		layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Flex{
+				Axis: layout.Vertical,
+			}.Layout(gtx,
+				layout.Rigid(material.Caption(&th.Theme, fmt.Sprintf("%s (%s)", a.fileName, state)).Layout),
+				layout.Rigid(material.Editor(&th.Theme, &a.description, "Alt text").Layout),
+				layout.Rigid(material.Editor(&th.Theme, &a.focus, "Focal point x,y (-1.0 to 1.0)").Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			ic, _ := widget.NewIcon(icons.NavigationCancel)
+			return newIconButton(th, &a.removeButton, ic, th.IconActiveColour).Layout(gtx)
+		}),
+	)
+}
@@ -6,12 +6,20 @@ import (
 	"gioui.org/op/paint"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
 	"github.com/mattn/go-mastodon"
 	"golang.org/x/exp/shiny/materialdesign/icons"
 	"image"
 	"image/color"
 )
 
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+	VisibilityDirect   = "direct"
+)
+
 // ComposeColumn defines the top-level presentation of your UI.
 type ComposeColumn struct {
 	// th configures styling of widgets.
@@ -19,19 +27,69 @@ type ComposeColumn struct {
 	ComponentState
 	gtx C
 
-	postTootDetails widget.Editor
-	searchQuery     widget.Editor
-	searchButton    widget.Clickable
-	postTootButton  widget.Clickable
-	settingsButton  widget.Clickable
-	refreshButton   widget.Clickable
-	cancelButton    widget.Clickable
+	postTootDetails      widget.Editor
+	spoilerText          widget.Editor
+	searchQuery          widget.Editor
+	searchButton         widget.Clickable
+	postTootButton       widget.Clickable
+	settingsButton       widget.Clickable
+	refreshButton        widget.Clickable
+	bookmarksButton      widget.Clickable
+	favouritesButton     widget.Clickable
+	conversationsButton  widget.Clickable
+	exploreButton        widget.Clickable
+	listsButton          widget.Clickable
+	filtersButton        widget.Clickable
+	moderationButton     widget.Clickable
+	followRequestsButton widget.Clickable
+
+	// opening the local and federated timelines.
+	localButton      widget.Clickable
+	federatedButton  widget.Clickable
+	publicOnlyMedia  widget.Bool
+	publicRemoteOnly widget.Bool
+
+	// notification types to include in a new notifications column. One per mastodon2.NotificationTypes.
+	notificationsButton widget.Clickable
+	notificationTypes   []widget.Bool
+	cancelButton        widget.Clickable
+	attachButton        widget.Clickable
+
+	// media attached to the toot being composed.
+	attachments []*composeAttachment
+
+	// attachments are created in the background once the file has been chosen.
+	newAttachments chan *composeAttachment
+
+	// mark media as sensitive
+	sensitive widget.Bool
+
+	// poll to attach to the toot (can't have both media and a poll)
+	poll *pollBuilder
+
+	// who can see the toot. One of the Visibility constants.
+	visibility widget.Enum
+
+	// ISO 639 language code of the toot. Left empty for the server to decide.
+	language widget.Editor
 
 	// if we're replying... know the status that we're replying to.
 	replyStatusID mastodon.ID
 
+	// if we're editing one of our statuses, the status being edited.
+	editStatusID mastodon.ID
+
 	// status list used when showing search results
 	searchResults []*mastodon.Status
+
+	// posts, favourites and boosts waiting to be sent from the selected account.
+	outboxItems  []*outboxItemState
+	outboxStates map[int64]*outboxItemState
+
+	// all logged in accounts. ComponentState.backend is the one currently selected
+	// for posting and searching.
+	backends        []mastodon2.Backend
+	accountSelector widget.Enum
 }
 
 // NewComposeColumn builds a messageColumns using a controller and backend.
@@ -39,15 +97,143 @@ func NewComposeColumn(componentState ComponentState, th *ShipdonTheme) *ComposeC
 	p := &ComposeColumn{
 		th:             th,
 		ComponentState: componentState,
+		newAttachments: make(chan *composeAttachment, MaxAttachments),
+		poll:           newPollBuilder(),
 	}
 
 	p.postTootDetails.SingleLine = false
+	p.spoilerText.SingleLine = true
+	p.language.SingleLine = true
+	p.visibility.Value = VisibilityPublic
 	p.postTootDetails.Submit = false
 	p.searchQuery.SingleLine = true
 	p.searchQuery.Submit = true
 	return p
 }
 
+// setBackends updates the accounts that can be posted from. Keeps the current
+// selection if it still exists, otherwise falls back to the first account.
+func (p *ComposeColumn) setBackends(backends []mastodon2.Backend) {
+	p.backends = backends
+	if !p.selectAccount(p.accountSelector.Value) && len(backends) > 0 {
+		p.selectAccount(backends[0].AccountName())
+	}
+}
+
+// selectAccount makes the named account the one used for posting and searching.
+// Returns false if there is no such account.
+func (p *ComposeColumn) selectAccount(name string) bool {
+	for _, b := range p.backends {
+		if b.AccountName() == name {
+			p.backend = b
+			p.accountSelector.Value = name
+			return true
+		}
+	}
+	return false
+}
+
+// layoutAccountSelector displays which account to post from. Only shown if logged in with multiple accounts.
+func (p *ComposeColumn) layoutAccountSelector(gtx C) D {
+	if len(p.backends) < 2 {
+		return D{}
+	}
+
+	var children []layout.FlexChild
+	for _, b := range p.backends {
+		name := b.AccountName()
+		children = append(children, layout.Rigid(material.RadioButton(&p.th.Theme, &p.accountSelector, name, name).Layout))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// layoutAttachments displays media attached to the toot, and the sensitive media checkbox.
+func (p *ComposeColumn) layoutAttachments(gtx C) D {
+	if len(p.attachments) == 0 {
+		return D{}
+	}
+
+	var children []layout.FlexChild
+	for _, a := range p.attachments {
+		a := a
+		children = append(children,
+			layout.Rigid(layout.Spacer{Height: 5}.Layout),
+			layout.Rigid(func(gtx C) D {
+				return a.Layout(gtx, p.th)
+			}))
+	}
+	children = append(children, layout.Rigid(material.CheckBox(&p.th.Theme, &p.sensitive, "Mark media as sensitive").Layout))
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// clearToot removes everything about the toot being composed.
+func (p *ComposeColumn) clearToot() {
+	p.postTootDetails.SetText("")
+	p.spoilerText.SetText("")
+	p.replyStatusID = ""
+	p.editStatusID = ""
+	p.attachments = nil
+	p.sensitive.Value = false
+	p.poll.reset()
+	p.visibility.Value = VisibilityPublic
+}
+
+// loadStatus fills in the compose column from one of our existing statuses, so it can be edited or redrafted.
+// attached is true if the media is still attached to the status (ie. editing rather than redrafting).
+func (p *ComposeColumn) loadStatus(backend mastodon2.Backend, status mastodon.Status, source *mastodon.Source, attached bool) {
+	p.clearToot()
+	p.selectAccount(backend.AccountName())
+
+	p.postTootDetails.SetText(source.Text)
+	p.spoilerText.SetText(source.SpoilerText)
+	p.language.SetText(status.Language)
+	if status.Visibility != "" {
+		p.visibility.Value = status.Visibility
+	}
+	if id, ok := status.InReplyToID.(string); ok {
+		p.replyStatusID = mastodon.ID(id)
+	}
+
+	for _, a := range status.MediaAttachments {
+		p.attachments = append(p.attachments, newExistingAttachment(backend, a, attached))
+	}
+	p.sensitive.Value = status.Sensitive
+
+	if status.Poll != nil {
+		p.poll.enabled.Value = true
+		p.poll.multiple.Value = status.Poll.Multiple
+		p.poll.options = nil
+		for _, o := range status.Poll.Options {
+			ed := &widget.Editor{SingleLine: true}
+			ed.SetText(o.Title)
+			p.poll.options = append(p.poll.options, ed)
+		}
+	}
+}
+
+// layoutVisibilityAndLanguage displays who can see the toot and what language it is in.
+func (p *ComposeColumn) layoutVisibilityAndLanguage(gtx C) D {
+	return layout.Flex{
+		Axis: layout.Vertical,
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{
+				Axis: layout.Horizontal,
+			}.Layout(gtx,
+				layout.Rigid(material.RadioButton(&p.th.Theme, &p.visibility, VisibilityPublic, "Public").Layout),
+				layout.Rigid(material.RadioButton(&p.th.Theme, &p.visibility, VisibilityUnlisted, "Unlisted").Layout),
+				layout.Rigid(material.RadioButton(&p.th.Theme, &p.visibility, VisibilityPrivate, "Followers").Layout),
+				layout.Rigid(material.RadioButton(&p.th.Theme, &p.visibility, VisibilityDirect, "Direct").Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			ed := material.Editor(&p.th.Theme, &p.language, "Language (eg. en)")
+			return ed.Layout(gtx)
+		}),
+		layout.Rigid(layout.Spacer{Height: 5}.Layout),
+	)
+}
+
 // Layout builds your UI within the operation list in gtx.
 func (p *ComposeColumn) Layout(gtx C) D {
 
@@ -78,11 +264,33 @@ func (p *ComposeColumn) layoutCreateStatusForm(gtx C) D {
 					Axis:    layout.Vertical,
 					Spacing: layout.SpaceEnd,
 				}.Layout(gtx,
+					layout.Rigid(p.layoutAccountSelector),
+					layout.Rigid(func(gtx C) D {
+						if p.editStatusID == "" {
+							return D{}
+						}
+						return material.Caption(&p.th.Theme, "Editing status").Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ed := material.Editor(&p.th.Theme, &p.spoilerText, "Content warning")
+						return ed.Layout(gtx)
+					}),
+					layout.Rigid(layout.Spacer{Height: 5}.Layout),
 					layout.Rigid(func(gtx C) D {
 						gtx.Constraints = gtx.Constraints.AddMin(image.Point{Y: 100})
 						ed := material.Editor(&p.th.Theme, &p.postTootDetails, "Toot")
 						return ed.Layout(gtx)
 					}),
+					layout.Rigid(p.layoutAttachments),
+					layout.Rigid(func(gtx C) D {
+						if len(p.attachments) > 0 {
+							return D{}
+						}
+						return p.poll.Layout(gtx, p.th, p.backend.PollLimits())
+					}),
+					layout.Rigid(p.layoutVisibilityAndLanguage),
+					layout.Rigid(layout.Spacer{Height: 5}.Layout),
+					layout.Rigid(p.layoutOutbox),
 					layout.Rigid(layout.Spacer{Height: 10}.Layout),
 					layout.Rigid(func(gtx C) D {
 						ed := material.Editor(&p.th.Theme, &p.searchQuery, "Search")
@@ -92,6 +300,9 @@ func (p *ComposeColumn) layoutCreateStatusForm(gtx C) D {
 
 					// search results... split into status, user or hashtag?
 					layout.Rigid(layout.Spacer{Height: 10}.Layout),
+					layout.Rigid(p.layoutPublicTimelines),
+					layout.Rigid(layout.Spacer{Height: 10}.Layout),
+					layout.Rigid(p.layoutNotificationColumns),
 				)
 			}),
 
@@ -107,6 +318,15 @@ func (p *ComposeColumn) layoutCreateStatusForm(gtx C) D {
 						postToot := newIconButton(p.th, &p.postTootButton, ic, p.th.IconActiveColour)
 						return postToot.Layout(gtx)
 					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.EditorAttachFile)
+						colour := p.th.IconActiveColour
+						if len(p.attachments) >= MaxAttachments {
+							colour = p.th.IconInactiveColour
+						}
+						attachButton := newIconButton(p.th, &p.attachButton, ic, colour)
+						return attachButton.Layout(gtx)
+					}),
 					layout.Rigid(func(gtx C) D {
 						ic, _ := widget.NewIcon(icons.ActionSettings)
 						//settingsButton := material.IconButton(p.th, &p.settingsButton, ic, "Settings").Layout(gtx)
@@ -118,6 +338,46 @@ func (p *ComposeColumn) layoutCreateStatusForm(gtx C) D {
 						refreshButton := newIconButton(p.th, &p.refreshButton, ic, p.th.IconActiveColour)
 						return refreshButton.Layout(gtx)
 					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ActionBookmark)
+						bookmarksButton := newIconButton(p.th, &p.bookmarksButton, ic, p.th.IconActiveColour)
+						return bookmarksButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ToggleStar)
+						favouritesButton := newIconButton(p.th, &p.favouritesButton, ic, p.th.IconActiveColour)
+						return favouritesButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.CommunicationForum)
+						conversationsButton := newIconButton(p.th, &p.conversationsButton, ic, p.th.IconActiveColour)
+						return conversationsButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.SocialPersonAdd)
+						followRequestsButton := newIconButton(p.th, &p.followRequestsButton, ic, p.th.IconActiveColour)
+						return followRequestsButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ActionTrendingUp)
+						exploreButton := newIconButton(p.th, &p.exploreButton, ic, p.th.IconActiveColour)
+						return exploreButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ActionViewList)
+						listsButton := newIconButton(p.th, &p.listsButton, ic, p.th.IconActiveColour)
+						return listsButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ContentFilterList)
+						filtersButton := newIconButton(p.th, &p.filtersButton, ic, p.th.IconActiveColour)
+						return filtersButton.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ContentBlock)
+						moderationButton := newIconButton(p.th, &p.moderationButton, ic, p.th.IconActiveColour)
+						return moderationButton.Layout(gtx)
+					}),
 					layout.Rigid(func(gtx C) D {
 						ic, _ := widget.NewIcon(icons.NavigationCancel)
 						ib := newIconButton(p.th, &p.cancelButton, ic, p.th.IconActiveColour)
@@ -144,6 +404,76 @@ func (p *ComposeColumn) layoutCreateStatusForm(gtx C) D {
 	})
 }
 
+// layoutPublicTimelines displays buttons to open the local and federated timelines, and their filters.
+func (p *ComposeColumn) layoutPublicTimelines(gtx C) D {
+	return layout.Flex{
+		Axis: layout.Vertical,
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{
+				Axis: layout.Horizontal,
+			}.Layout(gtx,
+				layout.Rigid(material.Button(&p.th.Theme, &p.localButton, "Local").Layout),
+				layout.Rigid(layout.Spacer{Width: 5}.Layout),
+				layout.Rigid(material.Button(&p.th.Theme, &p.federatedButton, "Federated").Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{
+				Axis: layout.Horizontal,
+			}.Layout(gtx,
+				layout.Rigid(material.CheckBox(&p.th.Theme, &p.publicOnlyMedia, "Media only").Layout),
+				layout.Rigid(material.CheckBox(&p.th.Theme, &p.publicRemoteOnly, "Remote only").Layout),
+			)
+		}),
+	)
+}
+
+// publicTimeline is the local or federated timeline with the filters selected.
+func (p *ComposeColumn) publicTimeline(local bool) mastodon2.PublicTimeline {
+	return mastodon2.PublicTimeline{
+		Local:      local,
+		OnlyMedia:  p.publicOnlyMedia.Value,
+		RemoteOnly: p.publicRemoteOnly.Value && !local,
+	}
+}
+
+// layoutNotificationColumns displays a button to open a notifications column, with checkboxes for
+// the types of notification to include.
+func (p *ComposeColumn) layoutNotificationColumns(gtx C) D {
+	if len(p.notificationTypes) != len(mastodon2.NotificationTypes) {
+		p.notificationTypes = make([]widget.Bool, len(mastodon2.NotificationTypes))
+		p.notificationTypes[0].Value = true
+	}
+
+	// 4 checkboxes per row to fit in the column.
+	const perRow = 4
+	children := []layout.FlexChild{
+		layout.Rigid(material.Button(&p.th.Theme, &p.notificationsButton, "Notifications").Layout),
+	}
+	for start := 0; start < len(mastodon2.NotificationTypes); start += perRow {
+		var row []layout.FlexChild
+		for i := start; i < min(start+perRow, len(mastodon2.NotificationTypes)); i++ {
+			row = append(row, layout.Rigid(material.CheckBox(&p.th.Theme, &p.notificationTypes[i], mastodon2.NotificationTypes[i]).Layout))
+		}
+		children = append(children, layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, row...)
+		}))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// notificationFilter is the filter for the notification types selected.
+func (p *ComposeColumn) notificationFilter() mastodon2.NotificationFilter {
+	var selected []string
+	for i, t := range mastodon2.NotificationTypes {
+		if i < len(p.notificationTypes) && p.notificationTypes[i].Value {
+			selected = append(selected, t)
+		}
+	}
+	return mastodon2.NewNotificationFilter(selected)
+}
+
 // layoutHeader displays a simple top bar.
 func (p *ComposeColumn) layoutHeader(gtx C) D {
 	return layout.Stack{}.Layout(gtx,
@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"gioui.org/app"
+	"gioui.org/font/gofont"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// confirm asks the user to confirm an action that can't be undone. Returns true if they did.
+func confirm(title string, message string) bool {
+	w := new(app.Window)
+	w.Option(
+		app.Title(title),
+		app.Size(unit.Dp(400), unit.Dp(150)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	var okButton widget.Clickable
+	var cancelButton widget.Clickable
+
+	confirmed := false
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return confirmed
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if okButton.Clicked(gtx) {
+				confirmed = true
+				w.Perform(system.ActionClose)
+			}
+			if cancelButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(material.Body1(th, message).Layout),
+					layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+							layout.Rigid(material.Button(th, &okButton, "OK").Layout),
+							layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+							layout.Rigid(material.Button(th, &cancelButton, "Cancel").Layout),
+						)
+					}),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
+
+// alert shows the user a message, such as an error, that only needs acknowledging.
+func alert(title string, message string) {
+	w := new(app.Window)
+	w.Option(
+		app.Title(title),
+		app.Size(unit.Dp(400), unit.Dp(150)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	var okButton widget.Clickable
+
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if okButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(material.Body1(th, message).Layout),
+					layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout),
+					layout.Rigid(material.Button(th, &okButton, "OK").Layout),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
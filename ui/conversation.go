@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"gioui.org/x/richtext"
+	"github.com/mattn/go-mastodon"
+	"image"
+	"strings"
+)
+
+const (
+	// most participant avatars shown for a conversation.
+	maxConversationAvatars = 4
+)
+
+// ConversationState is a direct message conversation in the conversations column.
+type ConversationState struct {
+	ComponentState
+
+	conversation mastodon.Conversation
+
+	// one per participant (up to maxConversationAvatars)
+	avatars []widget.Image
+
+	// last status in the conversation.
+	Details     richtext.InteractiveText
+	DetailStyle richtext.TextStyle
+
+	// opens the conversation in a thread column.
+	OpenButton widget.Clickable
+
+	th *ShipdonTheme
+}
+
+// NewConversationState builds an empty state.
+func NewConversationState(componentState ComponentState, th *ShipdonTheme) *ConversationState {
+	return &ConversationState{
+		ComponentState: componentState,
+		th:             th,
+	}
+}
+
+func (cs *ConversationState) syncConversationToUI(conversation mastodon.Conversation) {
+	cs.conversation = conversation
+
+	cs.avatars = nil
+	for i, a := range conversation.Accounts {
+		if i == maxConversationAvatars {
+			break
+		}
+		cs.avatars = append(cs.avatars, loadAvatar(a.Username, a.Avatar))
+	}
+
+	var spans []richtext.SpanStyle
+	if conversation.LastStatus != nil {
+		spans = generateDetailsSpanStyles(*conversation.LastStatus, cs.th)
+	}
+	cs.DetailStyle = richtext.Text(&cs.Details, cs.th.Shaper, spans...)
+}
+
+// participants is the display names of everyone in the conversation (other than us).
+func (cs *ConversationState) participants() string {
+	var names []string
+	for _, a := range cs.conversation.Accounts {
+		if a.DisplayName != "" {
+			names = append(names, a.DisplayName)
+		} else {
+			names = append(names, a.Username)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+type ConversationStyle struct {
+	state *ConversationState
+}
+
+func NewConversationStyle(th *material.Theme, conversation *ConversationState) ConversationStyle {
+	return ConversationStyle{
+		state: conversation,
+	}
+}
+
+// Layout displays the participants and last status. Unread conversations are outlined.
+func (i ConversationStyle) Layout(gtx C) D {
+	const spacing = unit.Dp(4)
+
+	return i.state.OpenButton.Layout(gtx, func(gtx C) D {
+		return layout.Stack{}.Layout(gtx,
+			layout.Expanded(func(gtx C) D {
+				rrect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(10))
+				paint.FillShape(gtx.Ops, i.state.th.Bg, rrect.Op(gtx.Ops))
+
+				if i.state.conversation.Unread {
+					border := widget.Border{Color: i.state.th.ContrastBg, CornerRadius: unit.Dp(10), Width: unit.Dp(2)}
+					border.Layout(gtx, func(gtx C) D {
+						return D{Size: gtx.Constraints.Min}
+					})
+				}
+				return D{Size: gtx.Constraints.Min}
+			}),
+
+			layout.Stacked(func(gtx C) D {
+				return layout.UniformInset(spacing).Layout(gtx, func(gtx C) D {
+					gtx.Constraints.Min.X = gtx.Constraints.Max.X
+
+					return layout.Flex{
+						Axis: layout.Vertical,
+					}.Layout(gtx,
+						layout.Rigid(func(gtx C) D {
+							var children []layout.FlexChild
+							for _, a := range i.state.avatars {
+								a := a
+								children = append(children, layout.Rigid(a.Layout))
+							}
+
+							children = append(children,
+								layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+								layout.Flexed(1, func(gtx C) D {
+									l := material.Body1(&i.state.th.Theme, i.state.participants())
+									if i.state.conversation.Unread {
+										l.Font.Weight = font.Bold
+									}
+									return l.Layout(gtx)
+								}),
+								layout.Rigid(func(gtx C) D {
+									if i.state.conversation.LastStatus == nil {
+										return D{}
+									}
+									return material.Label(&i.state.th.Theme, unit.Sp(15), statusAge(i.state.conversation.LastStatus.CreatedAt)).Layout(gtx)
+								}),
+							)
+							return layout.Flex{
+								Axis:      layout.Horizontal,
+								Alignment: layout.Middle,
+							}.Layout(gtx, children...)
+						}),
+
+						layout.Rigid(layout.Spacer{Height: spacing}.Layout),
+
+						layout.Rigid(i.state.DetailStyle.Layout),
+					)
+				})
+			}),
+		)
+	})
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"gioui.org/app"
 	"gioui.org/layout"
@@ -11,6 +12,7 @@ import (
 	"gioui.org/op/paint"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
+	"gioui.org/x/explorer"
 	"gioui.org/x/richtext"
 	"git.sr.ht/~gioverse/skel/stream"
 	"github.com/inkeliz/giohyperlink"
@@ -22,8 +24,11 @@ import (
 	"image"
 	"image/color"
 	"image/gif"
-	"math/rand"
+	"io"
+	"os"
+	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	"net/http"
@@ -87,13 +92,17 @@ type UI struct {
 	tracing   bool
 	traceDone context.CancelFunc
 
-	backend *mastodon2.MastodonBackend
+	// one backend per account.
+	backends []mastodon2.Backend
 
 	eventListener *events.EventListener
 
 	// Theme used... will be determined by config
 	th  *ShipdonTheme
 	cfg *config.Config
+
+	// native file picker, used for attaching media.
+	explorer *explorer.Explorer
 }
 
 func NewUI(
@@ -101,7 +110,7 @@ func NewUI(
 	w *app.Window,
 	composeColumn *ComposeColumn,
 	messageColumns []*MessageColumn,
-	backend *mastodon2.MastodonBackend,
+	backends []mastodon2.Backend,
 	eventListener *events.EventListener,
 	cfg *config.Config) *UI {
 	ui := &UI{
@@ -109,10 +118,12 @@ func NewUI(
 		w:              w,
 		composeColumn:  composeColumn,
 		messageColumns: messageColumns,
-		backend:        backend,
+		backends:       backends,
 		eventListener:  eventListener,
 		cfg:            cfg,
+		explorer:       explorer.NewExplorer(w),
 	}
+	composeColumn.setBackends(backends)
 
 	ui.columnList.List.Axis = layout.Horizontal
 
@@ -132,35 +143,22 @@ func (u *UI) Run() error {
 	var ops op.Ops
 	var inset = layout.UniformInset(8)
 
-	err := u.backend.LoginWithOAuth2()
-	if err != nil {
-		log.Warningf("unable to login with OAuth. %v", err)
-
-		instanceURL := openInstanceWindow()
-		if instanceURL != "" {
-			instanceLoginURL, err := u.backend.GenerateOAuthLoginURL(instanceURL)
-			if err != nil {
-				// FIXME(kpfaulkner) panic for now.. handle nicely later.
-				panic("unable to query instance URL")
-			}
-			if err := giohyperlink.Open(instanceLoginURL); err != nil {
-				log.Debugf("error: opening hyperlink: %v", err)
-			}
-		} else {
-			// FIXME(kpfaulkner) panic for now.. handle nicely later.
-			panic("unable to query instance URL")
+	// accounts we can't log in to are skipped for this session, but left in the config.
+	var backends []mastodon2.Backend
+	for _, backend := range u.backends {
+		if err := u.login(backend); err != nil {
+			log.Errorf("unable to login %v", err)
+			continue
 		}
-
-		// open browser to get code.
-		code := openLoginWindow()
-		if code != "" {
-			u.backend.GenerateConfigWithCode(code)
-		} else {
-			// FIXME(kpfaulkner) panic for now.. handle nicely later.
-			panic("bad code")
-		}
-		log.Debugf("CODE is %s\n", code)
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 {
+		return errors.New("unable to login to any account")
 	}
+	u.backends = backends
+
+	// account names are only known once logged in.
+	u.composeColumn.setBackends(u.backends)
 
 	// separate go routine for downloading of images
 	go downloadImages(imageChannel)
@@ -168,17 +166,33 @@ func (u *UI) Run() error {
 	// get list from Mastodon, then create correct number of message columns
 	u.messageColumns = u.generateMessageColumns()
 
-	// regular refresh of all columns
+	// redraw whenever the backend tells us a timeline has changed underneath us (eg. streaming)
+	u.eventListener.RegisterReceiver(events.TIMELINE_UPDATED, u.timelineUpdatedCallback)
+
+	// stream what we can, the rest falls back to the polling below.
+	for _, col := range u.messageColumns {
+		col.backend.StartStream(getRefreshTypeForColumnType(col.columnType), col.timelineID)
+	}
+
+	// regular refresh of all columns that are not being streamed.
 	go func() {
 		for {
 
 			for _, col := range u.messageColumns {
 
-				// put random delays in so we're not hammering the server all at once.
-				go func(col *MessageColumn) {
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-					events.FireEvent(events.NewRefreshEvent(col.timelineID, true, getRefreshTypeForColumnType(col.columnType)))
-				}(col)
+				if col.backend.IsStreaming(col.timelineID) {
+					continue
+				}
+
+				// the transport spreads requests out, but once the rate limit is low leave what's
+				// left for the user until it resets.
+				if col.backend.RateLimit().Low() {
+					continue
+				}
+
+				// top up what we have (possibly loaded from disk) with anything newer, so the
+				// reading position isn't lost.
+				events.FireEvent(col.refreshEvent(false))
 				//events.FireEvent(events.NewRefreshEvent(col.timelineID, false, getRefreshTypeForColumnType(col.columnType)))
 			}
 
@@ -201,6 +215,7 @@ func (u *UI) Run() error {
 	// Iterate events from our Gio window.
 	for {
 		ev := u.w.Event()
+		u.explorer.ListenEvents(ev)
 		switch ev := ev.(type) {
 		case app.DestroyEvent:
 			// If we get a destroy event, the window is closing. This may be due to
@@ -254,88 +269,378 @@ func (u *UI) Run() error {
 
 		}
 	}
+}
+
+// postToot posts the toot being composed. Fails if any attachments haven't finished uploading.
+func (u *UI) postToot() error {
+	cc := u.composeColumn
+	toot := &mastodon.Toot{
+		Status:      cc.postTootDetails.Text(),
+		InReplyToID: cc.replyStatusID,
+		SpoilerText: cc.spoilerText.Text(),
+		Visibility:  cc.visibility.Value,
+		Language:    strings.TrimSpace(cc.language.Text()),
+	}
+
+	for _, a := range cc.attachments {
+		state, mediaID := a.getState()
+		if state != attachmentReady {
+			return fmt.Errorf("attachment %s is %s", a.fileName, state)
+		}
+		if a.backend != cc.backend {
+			return fmt.Errorf("attachment %s was uploaded to account %s", a.fileName, a.backend.AccountName())
+		}
+
+		if !a.attached {
+			x, y := a.focalPoint()
+			if err := cc.backend.UpdateMedia(mediaID, a.description.Text(), x, y); err != nil {
+				return err
+			}
+		}
+		toot.MediaIDs = append(toot.MediaIDs, mediaID)
+	}
+
+	if len(toot.MediaIDs) > 0 {
+		toot.Sensitive = cc.sensitive.Value
+	}
+
+	toot.Poll = cc.poll.tootPoll()
+	if toot.Poll != nil && len(toot.MediaIDs) > 0 {
+		return fmt.Errorf("toot can't have both media and a poll")
+	}
+
+	if cc.editStatusID != "" {
+		return cc.backend.EditStatus(cc.editStatusID, toot)
+	}
+	return cc.backend.Post(toot)
+}
+
+// replyMentions is the text to start a reply with. Direct messages are only seen by the accounts
+// mentioned, so replies to them mention everyone in the conversation (apart from us).
+func replyMentions(status mastodon.Status, accountID mastodon.ID) string {
+	mentions := fmt.Sprintf("@%s ", status.Account.Acct)
+	if status.Visibility != VisibilityDirect {
+		return mentions
+	}
+
+	for _, m := range status.Mentions {
+		if m.ID == accountID || m.ID == status.Account.ID {
+			continue
+		}
+		mentions += fmt.Sprintf("@%s ", m.Acct)
+	}
+	return mentions
+}
+
+// replyVisibility is the visibility to use when replying to a status. Replies stay as visible
+// as the status being replied to.
+func replyVisibility(status mastodon.Status) string {
+	if status.Reblog != nil {
+		status = *status.Reblog
+	}
+	if status.Visibility == "" {
+		return VisibilityPublic
+	}
+	return status.Visibility
+}
+
+// chooseAttachment opens the file picker then uploads the chosen file in the background.
+// Blocks while the file picker is open, so should be called in its own goroutine.
+func (u *UI) chooseAttachment(backend mastodon2.Backend) {
+	f, err := u.explorer.ChooseFile(".png", ".jpg", ".jpeg", ".gif", ".webp", ".mp4", ".mov", ".webm")
+	if err != nil {
+		if err != explorer.ErrUserDecline {
+			log.Errorf("unable to choose file %v", err)
+		}
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		log.Errorf("unable to read file %v", err)
+		return
+	}
+
+	fileName := "upload"
+	if file, ok := f.(*os.File); ok {
+		fileName = filepath.Base(file.Name())
+	}
+
+	a := newComposeAttachment(backend, fileName, data)
+	u.composeColumn.newAttachments <- a
+	u.w.Invalidate()
+	a.upload(data, u.w.Invalidate)
+}
+
+// login logs the backend in, walking the user through the OAuth flow if we don't have
+// a token for the account yet. Returns an error if the user cancels or the details are wrong.
+func (u *UI) login(backend mastodon2.Backend) error {
+	err := backend.LoginWithOAuth2()
+	if err == nil {
+		return nil
+	}
+	log.Warningf("unable to login with OAuth. %v", err)
+
+	instanceURL := openInstanceWindow()
+	if instanceURL == "" {
+		return errors.New("no instance URL entered")
+	}
+
+	instanceLoginURL, err := backend.GenerateOAuthLoginURL(instanceURL)
+	if err != nil {
+		log.Errorf("unable to query instance URL %s : %v", instanceURL, err)
+		return fmt.Errorf("unable to query instance %s: %w", instanceURL, err)
+	}
+	if err := giohyperlink.Open(instanceLoginURL); err != nil {
+		log.Debugf("error: opening hyperlink: %v", err)
+	}
+
+	// open browser to get code.
+	code := openLoginWindow()
+	if code == "" {
+		return errors.New("no authorisation code entered")
+	}
+
+	if err := backend.GenerateConfigWithCode(code); err != nil {
+		log.Errorf("unable to login with code %v", err)
+		return fmt.Errorf("unable to login with that code: %w", err)
+	}
+	return nil
+}
+
+// addAccount logs in with a new account and adds its columns.
+func (u *UI) addAccount() {
+	acct := u.cfg.AddAccount()
+	backend, err := mastodon2.NewMastodonBackend(u.eventListener, u.cfg, acct)
+	if err != nil {
+		log.Errorf("unable to create backend for new account %v", err)
+		u.cfg.RemoveAccount(acct.Name)
+		return
+	}
+
+	if err := u.login(backend); err != nil {
+		log.Errorf("unable to login to new account %v", err)
+		u.cfg.RemoveAccount(acct.Name)
+		alert("Add account", "Unable to add account: "+err.Error())
+		return
+	}
+	u.backends = append(u.backends, backend)
+	u.composeColumn.setBackends(u.backends)
+
+	columns := u.generateMessageColumnsForBackend(backend)
+	for _, col := range columns {
+		backend.StartStream(getRefreshTypeForColumnType(col.columnType), col.timelineID)
+		events.FireEvent(col.refreshEvent(true))
+	}
+	u.messageColumns = append(u.messageColumns, columns...)
+	u.updateColumnSettings()
+}
+
+// removeDeletedAccounts drops the backends (and their columns) for any accounts that are
+// no longer in the config.
+func (u *UI) removeDeletedAccounts() {
+	var backends []mastodon2.Backend
+	for _, b := range u.backends {
+		if u.cfg.AccountByName(b.AccountName()) != nil {
+			backends = append(backends, b)
+		}
+	}
+
+	if len(backends) == len(u.backends) {
+		return
+	}
+
+	var columns []*MessageColumn
+	for _, col := range u.messageColumns {
+		if slices.Contains(backends, col.backend) {
+			columns = append(columns, col)
+		} else {
+			col.backend.StopStream(col.timelineID)
+		}
+	}
+
+	u.backends = backends
+	u.messageColumns = columns
+	u.composeColumn.setBackends(u.backends)
+	u.updateColumnSettings()
+}
+
+// updateColumnSettings applies the display settings to every column.
+func (u *UI) updateColumnSettings() {
+	for _, col := range u.messageColumns {
+		u.applyColumnSettings(col)
+	}
+}
+
+// applyColumnSettings applies the display settings to a column. The account name is only
+// shown if there is more than one account.
+func (u *UI) applyColumnSettings(col *MessageColumn) {
+	col.showAccountName = len(u.backends) > 1
+	col.expandContentWarnings = u.cfg.ExpandContentWarnings
+}
+
+// timelineUpdatedCallback redraws the window when the backend has changed a timeline.
+func (u *UI) timelineUpdatedCallback(e events.Event) error {
+	u.w.Invalidate()
 	return nil
 }
 
 // generateMessageColumns queries mastodon for list names
-// then generates the right number of columns (including home and notifications)
+// then generates the right number of columns (including home and notifications) for every account.
 func (u *UI) generateMessageColumns() []*MessageColumn {
 	var columns []*MessageColumn
+	for _, backend := range u.backends {
+		columns = append(columns, u.generateMessageColumnsForBackend(backend)...)
+	}
+	return columns
+}
+
+// generateMessageColumnsForBackend generates the columns for a single account.
+func (u *UI) generateMessageColumnsForBackend(backend mastodon2.Backend) []*MessageColumn {
+	var columns []*MessageColumn
 
 	// search, home and notifications are special cases.
-	columns = append(columns, NewMessageColumn(NewComponentState(u.controller, u.backend), "search", "search", SearchColumn, u.th))
-	columns = append(columns, NewMessageColumn(NewComponentState(u.controller, u.backend), "home", "home", HomeColumn, u.th))
-	columns = append(columns, NewMessageColumn(NewComponentState(u.controller, u.backend), "notifications", "notifications", NotificationsColumn, u.th))
+	columns = append(columns, u.newMessageColumn(backend, "search", "search", SearchColumn))
+	columns = append(columns, u.newMessageColumn(backend, "home", "home", HomeColumn))
+	columns = append(columns, u.newMessageColumn(backend, "notifications", "notifications", NotificationsColumn))
 
-	lists, err := u.backend.GetLists()
+	lists, err := backend.GetLists()
 	if err != nil {
 		log.Errorf("unable to get lists %v", err)
 		return columns
 	}
 
+	var listsToNotDisplay []string
+	if acct := u.cfg.AccountByName(backend.AccountName()); acct != nil {
+		listsToNotDisplay = acct.ListsToNotDisplay
+	}
+
 	// should be a map, but leave as slice for now.
 	for _, l := range lists {
 		//columnID := fmt.Sprintf("!%s", l.ID)
 		columnID := string(l.ID)
-		if !slices.Contains(u.cfg.ListsToNotDisplay, columnID) {
-			columns = append(columns, NewMessageColumn(NewComponentState(u.controller, u.backend), l.Title, columnID, ListColumn, u.th))
+		if !slices.Contains(listsToNotDisplay, columnID) {
+			columns = append(columns, u.newMessageColumn(backend, l.Title, columnID, ListColumn))
 		}
 	}
 
 	return columns
 }
 
-// addNewHashTagColumn adds a new column for a hashtag if one doesn't already exist
-// Also will have to start polling for new content.
-func (u *UI) addNewHashTagColumn(tag string) {
+// syncListColumns adds, removes and renames the account's list columns to match its lists and
+// the hidden lists setting.
+func (u *UI) syncListColumns(backend mastodon2.Backend) {
+	lists, err := backend.GetLists()
+	if err != nil {
+		log.Errorf("unable to get lists %v", err)
+		return
+	}
 
-	columnAlreadyExists := false
-	// checks that username column doesn't already exist.
-	for _, c := range u.messageColumns {
-		if c.timelineID == tag {
-			columnAlreadyExists = true
-			break
+	var listsToNotDisplay []string
+	if acct := u.cfg.AccountByName(backend.AccountName()); acct != nil {
+		listsToNotDisplay = acct.ListsToNotDisplay
+	}
+
+	var columns []*MessageColumn
+	for _, col := range u.messageColumns {
+		if col.backend != backend || col.columnType != ListColumn {
+			columns = append(columns, col)
+			continue
+		}
+
+		i := slices.IndexFunc(lists, func(l *mastodon2.List) bool { return string(l.ID) == col.timelineID })
+		if i == -1 || slices.Contains(listsToNotDisplay, col.timelineID) {
+			backend.StopStream(col.timelineID)
+			continue
 		}
+		col.timelineName = lists[i].Title
+		columns = append(columns, col)
 	}
+	u.messageColumns = columns
 
-	if !columnAlreadyExists {
-		u.messageColumns = append(u.messageColumns, NewMessageColumn(NewComponentState(u.controller, u.backend), tag, tag, HashTagColumn, u.th))
-		events.FireEvent(events.NewRefreshEvent(tag, true, events.HASHTAG_REFRESH))
+	for _, l := range lists {
+		columnID := string(l.ID)
+		if slices.Contains(listsToNotDisplay, columnID) || u.hasColumn(backend, columnID) {
+			continue
+		}
+		col := u.newMessageColumn(backend, l.Title, columnID, ListColumn)
+		u.messageColumns = append(u.messageColumns, col)
+		backend.StartStream(events.LIST_REFRESH, columnID)
+		events.FireEvent(col.refreshEvent(true))
 	}
 }
 
-// addNewUsernameColumn adds a new column for a username if one doesn't already exist
-// Also will have to start polling for new content.
-func (u *UI) addNewUsernameColumn(username string, userID string) {
+// newMessageColumn creates a column for a timeline belonging to the backend's account.
+func (u *UI) newMessageColumn(backend mastodon2.Backend, timelineName string, timelineID string, columnType ColumnType) *MessageColumn {
+	col := NewMessageColumn(NewComponentState(u.controller, backend), timelineName, timelineID, columnType, u.th)
+	u.applyColumnSettings(col)
+	return col
+}
 
-	columnAlreadyExists := false
-	// checks that username column doesn't already exist.
+// hasColumn returns true if the account already has a column for the timeline.
+func (u *UI) hasColumn(backend mastodon2.Backend, timelineID string) bool {
 	for _, c := range u.messageColumns {
-		if c.timelineID == userID {
-			columnAlreadyExists = true
-			break
+		if c.backend == backend && c.timelineID == timelineID {
+			return true
 		}
 	}
+	return false
+}
 
-	if !columnAlreadyExists {
-		u.messageColumns = append(u.messageColumns, NewMessageColumn(NewComponentState(u.controller, u.backend), username, userID, UserColumn, u.th))
-		events.FireEvent(events.NewRefreshEvent(userID, true, events.USER_REFRESH))
+// addNewColumn adds a column for one of the account's own timelines (eg. bookmarks) if it
+// doesn't already have one.
+func (u *UI) addNewColumn(backend mastodon2.Backend, timelineName string, timelineID string, columnType ColumnType) {
+	if !u.hasColumn(backend, timelineID) {
+		col := u.newMessageColumn(backend, timelineName, timelineID, columnType)
+		u.messageColumns = append(u.messageColumns, col)
+		events.FireEvent(col.refreshEvent(true))
 	}
 }
 
-func (u *UI) createColumnForThreadWithStatus(status mastodon.Status) {
-	if status.InReplyToID != nil {
-		statusID := string(status.ID)
-		u.messageColumns = append(u.messageColumns, NewMessageColumn(NewComponentState(u.controller, u.backend), "thread", statusID, ThreadColumn, u.th))
-		events.FireEvent(events.NewRefreshEvent(statusID, true, events.THREAD_REFRESH))
+// addNewPublicColumn adds a column for the local or federated timeline and streams it.
+func (u *UI) addNewPublicColumn(backend mastodon2.Backend, pt mastodon2.PublicTimeline, columnType ColumnType) {
+	if !u.hasColumn(backend, pt.TimelineID()) {
+		u.addNewColumn(backend, pt.Name(), pt.TimelineID(), columnType)
+		backend.StartStream(getRefreshTypeForColumnType(columnType), pt.TimelineID())
+	}
+}
+
+// addNewHashTagColumn adds a new column for a hashtag if one doesn't already exist
+// Also will have to start polling for new content.
+func (u *UI) addNewHashTagColumn(backend mastodon2.Backend, tag string) {
+
+	// checks that hashtag column doesn't already exist.
+	if !u.hasColumn(backend, tag) {
+		col := u.newMessageColumn(backend, tag, tag, HashTagColumn)
+		u.messageColumns = append(u.messageColumns, col)
+		events.FireEvent(col.refreshEvent(true))
+		backend.StartStream(events.HASHTAG_REFRESH, tag)
+	}
+}
+
+// addNewUsernameColumn adds a new column for a username if one doesn't already exist
+// Also will have to start polling for new content.
+func (u *UI) addNewUsernameColumn(backend mastodon2.Backend, username string, userID string) {
+
+	// checks that username column doesn't already exist.
+	if !u.hasColumn(backend, userID) {
+		col := u.newMessageColumn(backend, username, userID, UserColumn)
+		u.messageColumns = append(u.messageColumns, col)
+		events.FireEvent(col.refreshEvent(true))
+	}
+}
+
+func (u *UI) createColumnForThreadWithStatus(backend mastodon2.Backend, status mastodon.Status) {
+	if hasThread(status) {
+		u.addNewColumn(backend, "thread", string(threadStatus(status).ID), ThreadColumn)
 	}
 }
 
 func (u *UI) handleComposeColumnEvents(gtx layout.Context) error {
 	_, ok := u.composeColumn.cancelButton.Update(gtx)
 	if ok {
-		u.composeColumn.replyStatusID = "0"
-		u.composeColumn.postTootDetails.SetText("")
+		u.composeColumn.clearToot()
 	}
 
 	if u.composeColumn.cancelButton.Hovered() {
@@ -344,31 +649,150 @@ func (u *UI) handleComposeColumnEvents(gtx layout.Context) error {
 
 	_, ok = u.composeColumn.postTootButton.Update(gtx)
 	if ok {
-		err := u.backend.Post(u.composeColumn.postTootDetails.Text(), u.composeColumn.replyStatusID)
+		err := u.postToot()
 		if err != nil {
 			log.Errorf("error posting %+v", err)
+		} else {
+			// clear out toot and any reply details.
+			u.composeColumn.clearToot()
 		}
+	}
 
-		// clear out toot and any reply details.
-		u.composeColumn.postTootDetails.SetText("")
-		u.composeColumn.replyStatusID = "0"
+	for _, state := range u.composeColumn.outboxItems {
+		if _, ok = state.editButton.Update(gtx); ok {
+			u.composeColumn.editOutboxItem(state.item)
+			u.composeColumn.backend.DiscardOutboxItem(state.item.ID)
+		}
+		if _, ok = state.discardButton.Update(gtx); ok {
+			u.composeColumn.backend.DiscardOutboxItem(state.item.ID)
+		}
+	}
+
+	_, ok = u.composeColumn.poll.addOptionButton.Update(gtx)
+	if ok {
+		u.composeColumn.poll.addOption(u.composeColumn.backend.PollLimits())
+	}
+
+	_, ok = u.composeColumn.attachButton.Update(gtx)
+	if ok && len(u.composeColumn.attachments) < MaxAttachments && !u.composeColumn.poll.enabled.Value {
+		go u.chooseAttachment(u.composeColumn.backend)
+	}
+
+	// pick up any files chosen since the last frame.
+	for pending := true; pending; {
+		select {
+		case a := <-u.composeColumn.newAttachments:
+			if len(u.composeColumn.attachments) < MaxAttachments {
+				u.composeColumn.attachments = append(u.composeColumn.attachments, a)
+			}
+		default:
+			pending = false
+		}
+	}
+
+	for i, a := range u.composeColumn.attachments {
+		_, ok = a.removeButton.Update(gtx)
+		if ok {
+			u.composeColumn.attachments = slices.Delete(u.composeColumn.attachments, i, i+1)
+			break
+		}
+	}
+
+	if u.composeColumn.accountSelector.Update(gtx) {
+		u.composeColumn.selectAccount(u.composeColumn.accountSelector.Value)
 	}
 
 	_, ok = u.composeColumn.settingsButton.Update(gtx)
 	if ok {
-		openSettingsWindow(u.cfg)
+		lists := make(map[string][]*mastodon2.List)
+		for _, b := range u.backends {
+			if l, err := b.GetLists(); err == nil {
+				lists[b.AccountName()] = l
+			}
+		}
+
+		addAccount := openSettingsWindow(u.cfg, lists)
+		u.removeDeletedAccounts()
+		for _, b := range u.backends {
+			u.syncListColumns(b)
+		}
+		u.updateColumnSettings()
+		if addAccount {
+			u.addAccount()
+		}
 	}
 
 	_, ok = u.composeColumn.refreshButton.Update(gtx)
 	if ok {
 		for _, col := range u.messageColumns {
-			events.FireEvent(events.NewRefreshEvent(col.timelineID, false, getRefreshTypeForColumnType(col.columnType)))
+			events.FireEvent(col.refreshEvent(false))
 		}
 
 		// totally unscientific... but sleep a little then refresh :)
 		u.delayInvalidate(2)
 	}
 
+	_, ok = u.composeColumn.bookmarksButton.Update(gtx)
+	if ok {
+		u.addNewColumn(u.composeColumn.backend, "bookmarks", "bookmarks", BookmarksColumn)
+	}
+
+	_, ok = u.composeColumn.favouritesButton.Update(gtx)
+	if ok {
+		u.addNewColumn(u.composeColumn.backend, "favourites", "favourites", FavouritesColumn)
+	}
+
+	_, ok = u.composeColumn.conversationsButton.Update(gtx)
+	if ok {
+		u.addNewColumn(u.composeColumn.backend, "conversations", "conversations", ConversationsColumn)
+	}
+
+	_, ok = u.composeColumn.listsButton.Update(gtx)
+	if ok {
+		if openListsWindow(u.composeColumn.backend) {
+			u.syncListColumns(u.composeColumn.backend)
+		}
+	}
+
+	_, ok = u.composeColumn.moderationButton.Update(gtx)
+	if ok {
+		openModerationWindow(u.composeColumn.backend)
+	}
+
+	_, ok = u.composeColumn.filtersButton.Update(gtx)
+	if ok {
+		openFiltersWindow(u.composeColumn.backend)
+	}
+
+	_, ok = u.composeColumn.notificationsButton.Update(gtx)
+	if ok {
+		nf := u.composeColumn.notificationFilter()
+		if !u.hasColumn(u.composeColumn.backend, nf.TimelineID()) {
+			u.addNewColumn(u.composeColumn.backend, nf.Name(), nf.TimelineID(), NotificationsColumn)
+			u.composeColumn.backend.StartStream(events.NOTIFICATION_REFRESH, nf.TimelineID())
+		}
+	}
+
+	_, ok = u.composeColumn.followRequestsButton.Update(gtx)
+	if ok {
+		u.addNewColumn(u.composeColumn.backend, "follow requests", "follow_requests", FollowRequestsColumn)
+	}
+
+	_, ok = u.composeColumn.exploreButton.Update(gtx)
+	if ok {
+		u.addNewColumn(u.composeColumn.backend, "explore", "explore", ExploreColumn)
+	}
+
+	_, ok = u.composeColumn.localButton.Update(gtx)
+	if ok {
+		u.addNewPublicColumn(u.composeColumn.backend, u.composeColumn.publicTimeline(true), LocalColumn)
+	}
+
+	_, ok = u.composeColumn.federatedButton.Update(gtx)
+	if ok {
+		u.addNewPublicColumn(u.composeColumn.backend, u.composeColumn.publicTimeline(false), FederatedColumn)
+	}
+
 	performSearch := false
 	// submit search via return/enter
 	if ev, ok := u.composeColumn.searchQuery.Update(gtx); ok {
@@ -385,7 +809,7 @@ func (u *UI) handleComposeColumnEvents(gtx layout.Context) error {
 	if performSearch {
 		u.composeColumn.searchResults = nil
 		log.Debugf("searching for %s", u.composeColumn.searchQuery.Text())
-		res, err := u.backend.Search(u.composeColumn.searchQuery.Text())
+		res, err := u.composeColumn.backend.Search(u.composeColumn.searchQuery.Text())
 		if err != nil {
 			log.Errorf("error searching %+v", err)
 			return nil
@@ -417,13 +841,96 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 			}
 		}
 
+		_, ok = c.listsClickable.Update(gtx)
+		if ok {
+			if account, _ := c.backend.GetUserDetails(); account != nil {
+				openUserListsWindow(c.backend, account)
+			}
+		}
+
+		_, ok = c.muteClickable.Update(gtx)
+		if ok {
+			if account, relationship := c.backend.GetUserDetails(); account != nil && relationship != nil {
+				if relationship.Muting {
+					if err := c.backend.UnmuteAccount(account.ID); err != nil {
+						log.Errorf("error unmuting account %+v", err)
+						alert("Unmute", "Unable to unmute @"+account.Acct+": "+err.Error())
+					}
+				} else {
+					openMuteWindow(c.backend, *account)
+				}
+				c.backend.RefreshUserRelationship()
+				u.delayInvalidate(2)
+			}
+		}
+
+		_, ok = c.blockClickable.Update(gtx)
+		if ok {
+			if account, relationship := c.backend.GetUserDetails(); account != nil && relationship != nil {
+				var err error
+				if relationship.Blocking {
+					err = c.backend.UnblockAccount(account.ID)
+				} else {
+					err = c.backend.BlockAccount(account.ID)
+				}
+				if err != nil {
+					log.Errorf("error changing block %+v", err)
+					alert("Block", "Unable to change block of @"+account.Acct+": "+err.Error())
+				}
+				c.backend.RefreshUserRelationship()
+				u.delayInvalidate(2)
+			}
+		}
+
+		_, ok = c.domainBlockClickable.Update(gtx)
+		if ok {
+			if account, relationship := c.backend.GetUserDetails(); account != nil && relationship != nil {
+				domain := mastodon2.AccountDomain(*account)
+				var err error
+				if relationship.DomainBlocking {
+					err = c.backend.UnblockDomain(domain)
+				} else {
+					err = c.backend.BlockDomain(domain)
+				}
+				if err != nil {
+					log.Errorf("error changing domain block %+v", err)
+					alert("Block domain", "Unable to change block of "+domain+": "+err.Error())
+				}
+				c.backend.RefreshUserRelationship()
+				u.delayInvalidate(2)
+			}
+		}
+
+		_, ok = c.reportClickable.Update(gtx)
+		if ok {
+			if account, _ := c.backend.GetUserDetails(); account != nil {
+				openReportWindow(c.backend, *account, nil)
+			}
+		}
+
+		_, ok = c.clearNotificationsButton.Update(gtx)
+		if ok && confirm("Clear notifications", "Dismiss all notifications for "+c.backend.AccountName()+"? This can't be undone.") {
+			if err := c.backend.ClearNotifications(); err != nil {
+				log.Errorf("error clearing notifications %+v", err)
+			}
+			u.delayInvalidate(2)
+		}
+
+		for gapID, button := range c.gapButtons {
+			if _, ok = button.Update(gtx); ok {
+				go c.backend.LoadGap(getRefreshTypeForColumnType(c.columnType), c.timelineID, gapID)
+			}
+		}
+
 		_, ok = c.removeColumnButton.Update(gtx)
 		if ok {
 			log.Debugf("remove column  %s", c.timelineID)
 
 			if c.columnType == ListColumn {
-				u.cfg.ListsToNotDisplay = append(u.cfg.ListsToNotDisplay, c.timelineID)
-				u.cfg.Save()
+				if acct := u.cfg.AccountByName(c.backend.AccountName()); acct != nil {
+					acct.ListsToNotDisplay = append(acct.ListsToNotDisplay, c.timelineID)
+					u.cfg.Save()
+				}
 			}
 
 			if c.columnType == SearchColumn {
@@ -435,6 +942,8 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 				u.composeColumn.searchQuery.SetText("")
 				u.delayInvalidate(2)
 			} else {
+				c.backend.StopStream(c.timelineID)
+
 				// actually remove column
 				if len(u.messageColumns) == colNum {
 					u.messageColumns = u.messageColumns[:colNum]
@@ -444,6 +953,68 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 			}
 		}
 
+		for _, t := range c.trendingTags {
+			_, ok = t.button.Update(gtx)
+			if ok {
+				log.Debugf("trending tag clicked %s", t.tag.Name)
+				u.addNewHashTagColumn(c.backend, t.tag.Name)
+			}
+		}
+
+		for _, l := range c.trendingLinks {
+			_, ok = l.button.Update(gtx)
+			if ok {
+				if err := giohyperlink.Open(l.link.URL); err != nil {
+					log.Debugf("error: opening hyperlink: %v", err)
+				}
+			}
+		}
+
+		for _, fs := range c.followRequestStateList {
+			if _, ok = fs.AcceptButton.Update(gtx); ok {
+				if err := c.backend.AuthorizeFollowRequest(fs.account.ID); err != nil {
+					log.Errorf("error accepting follow request %+v", err)
+				}
+				delete(c.followRequestStateCache, fs.account.ID)
+			}
+			if _, ok = fs.RejectButton.Update(gtx); ok {
+				if err := c.backend.RejectFollowRequest(fs.account.ID); err != nil {
+					log.Errorf("error rejecting follow request %+v", err)
+				}
+				delete(c.followRequestStateCache, fs.account.ID)
+			}
+		}
+
+		for _, ns := range c.notificationStateList {
+			if _, ok = ns.AcceptButton.Update(gtx); ok {
+				if err := c.backend.AuthorizeFollowRequest(ns.notification.Account.ID); err != nil {
+					log.Errorf("error accepting follow request %+v", err)
+				}
+				delete(c.notificationStateCache, ns.notification.ID)
+			}
+			if _, ok = ns.RejectButton.Update(gtx); ok {
+				if err := c.backend.RejectFollowRequest(ns.notification.Account.ID); err != nil {
+					log.Errorf("error rejecting follow request %+v", err)
+				}
+				delete(c.notificationStateCache, ns.notification.ID)
+			}
+		}
+
+		for _, cs := range c.conversationStateList {
+			_, ok = cs.OpenButton.Update(gtx)
+			if ok && cs.conversation.LastStatus != nil {
+				log.Debugf("opening conversation %s", cs.conversation.ID)
+				if cs.conversation.Unread {
+					if err := c.backend.MarkConversationRead(cs.conversation.ID); err != nil {
+						log.Errorf("error marking conversation read %+v", err)
+					}
+				}
+
+				// the last status may not be a reply, so can't use createColumnForThreadWithStatus
+				u.addNewColumn(c.backend, "thread", string(cs.conversation.LastStatus.ID), ThreadColumn)
+			}
+		}
+
 		for _, t := range c.statusStateList {
 
 			if t.img != nil {
@@ -454,6 +1025,48 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 				}
 			}
 
+			_, ok = t.ShowMoreButton.Update(gtx)
+			if ok {
+				t.expanded = !t.expanded
+			}
+
+			_, ok = t.ShowFilteredButton.Update(gtx)
+			if ok {
+				t.filterRevealed = true
+			}
+
+			_, ok = t.ShowMediaButton.Update(gtx)
+			if ok {
+				t.mediaRevealed = true
+			}
+
+			if poll := statusPoll(t.status); poll != nil {
+				_, ok = t.poll.voteButton.Update(gtx)
+				if ok {
+					if choices := t.poll.selectedChoices(poll); len(choices) > 0 {
+						go func(backend mastodon2.Backend, statusID mastodon.ID) {
+							if err := backend.VotePoll(statusID, choices); err != nil {
+								log.Errorf("error voting %+v", err)
+							}
+							u.w.Invalidate()
+						}(t.backend, t.status.ID)
+					}
+				}
+
+				if t.poll.needsRefresh(poll) {
+					t.poll.lastRefreshed = time.Now()
+					go func(backend mastodon2.Backend, statusID mastodon.ID) {
+						if err := backend.RefreshPoll(statusID); err != nil {
+							log.Errorf("error refreshing poll %+v", err)
+						}
+						u.w.Invalidate()
+					}(t.backend, t.status.ID)
+
+					// make sure we get another frame to check if the poll needs refreshing again.
+					u.delayInvalidate(int(PollRefreshInterval.Seconds()))
+				}
+			}
+
 			o, event, ok := t.Details.Update(gtx)
 			if ok {
 				switch event.Type {
@@ -466,14 +1079,14 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 
 					if tag, ok := o.Get("tag").(string); ok && tag != "" {
 						log.Debugf("tag clicked %s\n", tag)
-						u.addNewHashTagColumn(tag)
+						u.addNewHashTagColumn(c.backend, tag)
 						//events.FireEvent(events.NewRefreshEvent(tag, false))
 					}
 
 					if username, ok := o.Get("username").(string); ok && username != "" {
 						if userID, ok := o.Get("userID").(mastodon.ID); ok && userID != "" {
 							log.Debugf("username clicked %s : %s\n", username, userID)
-							u.addNewUsernameColumn(username, string(userID))
+							u.addNewUsernameColumn(c.backend, username, string(userID))
 						}
 					}
 
@@ -488,7 +1101,7 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 					if username, ok := o.Get("username").(string); ok && username != "" {
 						if userID, ok := o.Get("userID").(mastodon.ID); ok && userID != "" {
 							log.Debugf("username clicked %s : %s\n", username, userID)
-							u.addNewUsernameColumn(username, string(userID))
+							u.addNewUsernameColumn(c.backend, username, string(userID))
 						}
 					}
 				}
@@ -504,8 +1117,12 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 			_, ok = t.ReplyButton.Update(gtx)
 			if ok {
 				log.Debugf("reply for toot %+v\n", t.status.ID)
-				u.composeColumn.postTootDetails.SetText(fmt.Sprintf("@%s ", t.status.Account.Acct))
+				u.composeColumn.postTootDetails.SetText(replyMentions(t.status, c.backend.AccountID()))
 				u.composeColumn.replyStatusID = t.status.ID
+				u.composeColumn.visibility.Value = replyVisibility(t.status)
+
+				// status IDs are per instance, so have to reply from the account that saw the status.
+				u.composeColumn.selectAccount(c.backend.AccountName())
 			}
 
 			_, ok = t.FavouriteButton.Update(gtx)
@@ -517,15 +1134,98 @@ func (u *UI) handleMessageColumnEvents(gtx layout.Context) error {
 				t.backend.SetFavourite(t.status.ID, !favStatus)
 			}
 
-			_, ok = t.ViewThreadButton.Update(gtx)
+			_, ok = t.BookmarkButton.Update(gtx)
 			if ok {
-				log.Debugf("viewing threadfor toot %+v\n", t.status.ID)
+				log.Debugf("bookmark for toot %+v\n", t.status.ID)
+				bookmarked, _ := t.status.Bookmarked.(bool)
+				if err := t.backend.SetBookmark(t.status.ID, !bookmarked); err != nil {
+					log.Errorf("error bookmarking status %+v", err)
+				}
+			}
 
-				if t.status.InReplyToID != nil {
-					u.createColumnForThreadWithStatus(t.status)
+			_, ok = t.MoreButton.Update(gtx)
+			if ok {
+				t.showActions = !t.showActions
+			}
+
+			_, ok = t.MuteButton.Update(gtx)
+			if ok {
+				log.Debugf("mute author of toot %+v\n", t.status.ID)
+				t.showActions = false
+				openMuteWindow(t.backend, statusAuthor(t.status))
+			}
+
+			_, ok = t.BlockButton.Update(gtx)
+			if ok {
+				log.Debugf("block author of toot %+v\n", t.status.ID)
+				t.showActions = false
+				if err := t.backend.BlockAccount(statusAuthor(t.status).ID); err != nil {
+					log.Errorf("error blocking account %+v", err)
+					alert("Block", "Unable to block @"+statusAuthor(t.status).Acct+": "+err.Error())
+				}
+			}
+
+			_, ok = t.BlockDomainButton.Update(gtx)
+			if ok {
+				log.Debugf("block domain of toot %+v\n", t.status.ID)
+				t.showActions = false
+				domain := mastodon2.AccountDomain(statusAuthor(t.status))
+				if err := t.backend.BlockDomain(domain); err != nil {
+					log.Errorf("error blocking domain %+v", err)
+					alert("Block domain", "Unable to block "+domain+": "+err.Error())
 				}
 			}
 
+			_, ok = t.ReportButton.Update(gtx)
+			if ok {
+				log.Debugf("report toot %+v\n", t.status.ID)
+				t.showActions = false
+				status := t.status
+				if status.Reblog != nil {
+					status = *status.Reblog
+				}
+				openReportWindow(t.backend, status.Account, &status)
+			}
+
+			_, ok = t.EditButton.Update(gtx)
+			if ok {
+				log.Debugf("edit toot %+v\n", t.status.ID)
+				source, err := t.backend.GetStatusSource(t.status.ID)
+				if err != nil {
+					log.Errorf("error getting status source %+v", err)
+				} else {
+					u.composeColumn.loadStatus(t.backend, t.status, source, true)
+					u.composeColumn.editStatusID = t.status.ID
+				}
+			}
+
+			_, ok = t.DeleteButton.Update(gtx)
+			if ok && confirm("Delete post", "Delete this post? This can't be undone.") {
+				log.Debugf("delete toot %+v\n", t.status.ID)
+				if err := t.backend.DeleteStatus(t.status.ID); err != nil {
+					log.Errorf("error deleting status %+v", err)
+				}
+			}
+
+			_, ok = t.RedraftButton.Update(gtx)
+			if ok && confirm("Delete and redraft", "Delete this post and load it into the compose column? Replies, boosts and favourites will be lost.") {
+				log.Debugf("delete and redraft toot %+v\n", t.status.ID)
+				source, err := t.backend.GetStatusSource(t.status.ID)
+				if err != nil {
+					log.Errorf("error getting status source %+v", err)
+				} else if err := t.backend.DeleteStatus(t.status.ID); err != nil {
+					log.Errorf("error deleting status %+v", err)
+				} else {
+					u.composeColumn.loadStatus(t.backend, t.status, source, false)
+				}
+			}
+
+			_, ok = t.ViewThreadButton.Update(gtx)
+			if ok {
+				log.Debugf("viewing threadfor toot %+v\n", t.status.ID)
+				u.createColumnForThreadWithStatus(c.backend, t.status)
+			}
+
 		}
 	}
 	return nil
@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+
+	"gioui.org/layout"
+	"github.com/kpfaulkner/shipdon/config"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+)
+
+// newTestUI is a UI with a home column for the fixture account, synced as if it had been drawn.
+func newTestUI(t *testing.T) (*UI, *MessageColumn, *mastodon2.FakeBackend) {
+	t.Helper()
+
+	p, backend := newTestColumn(t, "home", HomeColumn)
+	syncColumn(t, p)
+
+	u := &UI{
+		composeColumn:  NewComposeColumn(NewComponentState(nil, backend), GenerateLightTheme()),
+		messageColumns: []*MessageColumn{p},
+		backends:       []mastodon2.Backend{backend},
+		th:             GenerateLightTheme(),
+		cfg:            &config.Config{},
+	}
+	u.composeColumn.setBackends(u.backends)
+	return u, p, backend
+}
+
+// statusState is the column's state for the status with the given ID.
+func statusState(t *testing.T, p *MessageColumn, id mastodon.ID) *StatusState {
+	t.Helper()
+
+	i := slices.IndexFunc(p.statusStateList, func(ss *StatusState) bool { return ss.status.ID == id })
+	if i == -1 {
+		t.Fatalf("status %s not in column %v", id, columnIDs(p))
+	}
+	return p.statusStateList[i]
+}
+
+func TestHandleStatusEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status mastodon.ID
+		click  func(ss *StatusState)
+		check  func(t *testing.T, u *UI, backend *mastodon2.FakeBackend)
+	}{
+		{
+			name:   "favourite",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.FavouriteButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if s := statusState(t, u.messageColumns[0], fixtureStatus); s.status.Favourited != true {
+					t.Error("not favourited")
+				}
+			},
+		},
+		{
+			name:   "unfavourite",
+			status: fixtureReply,
+			click:  func(ss *StatusState) { ss.FavouriteButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if s := statusState(t, u.messageColumns[0], fixtureReply); s.status.Favourited != false {
+					t.Error("still favourited")
+				}
+			},
+		},
+		{
+			name:   "boost",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.BoostButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if s := statusState(t, u.messageColumns[0], fixtureStatus); s.status.Reblogged != true {
+					t.Error("not boosted")
+				}
+			},
+		},
+		{
+			name:   "bookmark",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.BookmarkButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				bookmarks, _ := backend.GetTimeline("bookmarks")
+				if len(bookmarks) != 1 || bookmarks[0].ID != fixtureStatus {
+					t.Errorf("bookmarks = %+v", bookmarks)
+				}
+			},
+		},
+		{
+			name:   "reply",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.ReplyButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				cc := u.composeColumn
+				if cc.replyStatusID != fixtureStatus || cc.postTootDetails.Text() != "@alice@example.com " || cc.visibility.Value != VisibilityPublic {
+					t.Errorf("replying to %s with %q (%s)", cc.replyStatusID, cc.postTootDetails.Text(), cc.visibility.Value)
+				}
+			},
+		},
+		{
+			name:   "edit",
+			status: fixtureReply,
+			click:  func(ss *StatusState) { ss.EditButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				cc := u.composeColumn
+				if cc.editStatusID != fixtureReply || cc.postTootDetails.Text() != "A reply to an older status." {
+					t.Errorf("editing %s with %q", cc.editStatusID, cc.postTootDetails.Text())
+				}
+				if cc.replyStatusID != "110000000000000000" {
+					t.Errorf("edit is a reply to %s", cc.replyStatusID)
+				}
+			},
+		},
+		{
+			name:   "view thread",
+			status: fixtureReply,
+			click:  func(ss *StatusState) { ss.ViewThreadButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if len(u.messageColumns) != 2 {
+					t.Fatalf("%d columns, want a thread column added", len(u.messageColumns))
+				}
+				if col := u.messageColumns[1]; col.columnType != ThreadColumn || col.timelineID != string(fixtureReply) {
+					t.Errorf("added column %s (%d)", col.timelineID, col.columnType)
+				}
+			},
+		},
+		{
+			name:   "view thread of status on its own",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.ViewThreadButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if len(u.messageColumns) != 1 {
+					t.Errorf("%d columns, want no thread column", len(u.messageColumns))
+				}
+			},
+		},
+		{
+			name:   "show more",
+			status: fixtureStatus,
+			click:  func(ss *StatusState) { ss.ShowMoreButton.Click() },
+			check: func(t *testing.T, u *UI, backend *mastodon2.FakeBackend) {
+				if !statusState(t, u.messageColumns[0], fixtureStatus).expanded {
+					t.Error("not expanded")
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, p, backend := newTestUI(t)
+
+			tc.click(statusState(t, p, tc.status))
+			if err := u.handleMessageColumnEvents(layout.Context{}); err != nil {
+				t.Fatal(err)
+			}
+			syncColumn(t, p)
+			tc.check(t, u, backend)
+		})
+	}
+}
+
+func TestHandleRemoveColumn(t *testing.T) {
+	u, p, _ := newTestUI(t)
+	u.addNewColumn(p.backend, "bookmarks", "bookmarks", BookmarksColumn)
+
+	p.removeColumnButton.Click()
+	if err := u.handleMessageColumnEvents(layout.Context{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(u.messageColumns) != 1 || u.messageColumns[0].timelineID != "bookmarks" {
+		t.Errorf("columns after removing home: %d", len(u.messageColumns))
+	}
+}
+
+func TestHandleFollowRequestEvents(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		click func(fs *FollowRequestState)
+	}{
+		{"accept", func(fs *FollowRequestState) { fs.AcceptButton.Click() }},
+		{"reject", func(fs *FollowRequestState) { fs.RejectButton.Click() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, _, backend := newTestUI(t)
+			p := u.newMessageColumn(backend, "follow requests", "follow_requests", FollowRequestsColumn)
+			u.messageColumns = []*MessageColumn{p}
+
+			requests, _ := backend.GetFollowRequests()
+			if len(requests) != 1 {
+				t.Fatalf("%d follow requests in the fixture, want 1", len(requests))
+			}
+			fs := NewFollowRequestState(p.ComponentState, p.th)
+			fs.syncFollowRequestToUI(*requests[0])
+			p.followRequestStateList = []*FollowRequestState{fs}
+			p.followRequestStateCache[requests[0].ID] = fs
+
+			tc.click(fs)
+			if err := u.handleMessageColumnEvents(layout.Context{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if requests, _ := backend.GetFollowRequests(); len(requests) != 0 {
+				t.Errorf("follow requests left: %d", len(requests))
+			}
+			if notifications, _ := backend.GetNotifications("notifications"); len(notifications) != 1 {
+				t.Errorf("%d notifications, want the follow request one gone", len(notifications))
+			}
+			if len(p.followRequestStateCache) != 0 {
+				t.Error("follow request state still cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package ui
+
+import (
+	"fmt"
+	"gioui.org/f32"
+	"gioui.org/font"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"image"
+	"image/color"
+	"slices"
+)
+
+const (
+	sparklineWidth  = unit.Dp(80)
+	sparklineHeight = unit.Dp(24)
+)
+
+// trendingTagState is a trending hashtag in the explore column. Clicking opens a hashtag column.
+type trendingTagState struct {
+	tag    *mastodon.Tag
+	button widget.Clickable
+}
+
+// trendingLinkState is a trending link in the explore column. Clicking opens the link in the browser.
+type trendingLinkState struct {
+	link   *mastodon2.TrendingLink
+	button widget.Clickable
+}
+
+// updateTrends syncs the trending tag and link states with the latest trends, keeping
+// existing states (and their clickables) where possible.
+func (p *MessageColumn) updateTrends(trends mastodon2.Trends) {
+	var tags []*trendingTagState
+	for _, t := range trends.Tags {
+		i := slices.IndexFunc(p.trendingTags, func(ts *trendingTagState) bool { return ts.tag.Name == t.Name })
+		if i == -1 {
+			tags = append(tags, &trendingTagState{tag: t})
+			continue
+		}
+		p.trendingTags[i].tag = t
+		tags = append(tags, p.trendingTags[i])
+	}
+	p.trendingTags = tags
+
+	var links []*trendingLinkState
+	for _, l := range trends.Links {
+		i := slices.IndexFunc(p.trendingLinks, func(ls *trendingLinkState) bool { return ls.link.URL == l.URL })
+		if i == -1 {
+			links = append(links, &trendingLinkState{link: l})
+			continue
+		}
+		p.trendingLinks[i].link = l
+		links = append(links, p.trendingLinks[i])
+	}
+	p.trendingLinks = links
+}
+
+// layoutExplore displays trending tags, then trending statuses, then trending links.
+func (p *MessageColumn) layoutExplore(gtx C) D {
+	trends, err := p.backend.GetTrends()
+	if err != nil {
+		log.Errorf("unable to get trends: %s", err)
+	}
+	p.updateTrends(trends)
+
+	messages, err := p.backend.GetTimeline(p.timelineID)
+	if err != nil {
+		log.Errorf("unable to get timeline for %s: %s", p.timelineName, err)
+	}
+	p.updateStatusStateList(gtx, messages)
+
+	const baseInset = unit.Dp(12)
+	inset := layout.Inset{
+		Left:   baseInset,
+		Right:  baseInset,
+		Top:    baseInset * .5,
+		Bottom: baseInset * .5,
+	}
+
+	var items []layout.Widget
+	if len(p.trendingTags) > 0 {
+		items = append(items, p.sectionHeading("Trending tags"))
+		for _, t := range p.trendingTags {
+			t := t
+			items = append(items, func(gtx C) D {
+				return inset.Layout(gtx, func(gtx C) D { return p.layoutTrendingTag(gtx, t) })
+			})
+		}
+	}
+
+	if len(p.statusStateList) > 0 {
+		items = append(items, p.sectionHeading("Trending statuses"))
+		for _, s := range p.statusStateList {
+			s := s
+			items = append(items, func(gtx C) D {
+				return inset.Layout(gtx, NewStatusStyle(&p.th.Theme, s).Layout)
+			})
+		}
+	}
+
+	if len(p.trendingLinks) > 0 {
+		items = append(items, p.sectionHeading("Trending links"))
+		for _, l := range p.trendingLinks {
+			l := l
+			items = append(items, func(gtx C) D {
+				return inset.Layout(gtx, func(gtx C) D { return p.layoutTrendingLink(gtx, l) })
+			})
+		}
+	}
+
+	paint.FillShape(gtx.Ops, p.th.StatusBackgroundColour, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	listStyle := material.List(&p.th.Theme, &p.statusList)
+	listStyle.AnchorStrategy = material.Overlay
+	return listStyle.Layout(gtx, len(items), func(gtx C, index int) D {
+		return items[index](gtx)
+	})
+}
+
+func (p *MessageColumn) sectionHeading(heading string) layout.Widget {
+	return func(gtx C) D {
+		return layout.Inset{Left: 12, Right: 12, Top: 12}.Layout(gtx, func(gtx C) D {
+			l := material.Subtitle1(&p.th.Theme, heading)
+			l.Color = p.th.ContrastBg
+			return l.Layout(gtx)
+		})
+	}
+}
+
+func (p *MessageColumn) layoutTrendingTag(gtx C, t *trendingTagState) D {
+	return t.button.Layout(gtx, func(gtx C) D {
+		return layoutCard(gtx, p.th, func(gtx C) D {
+			return layout.Flex{
+				Axis:      layout.Horizontal,
+				Alignment: layout.Middle,
+			}.Layout(gtx,
+				layout.Flexed(1, func(gtx C) D {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(material.Body1(&p.th.Theme, "#"+t.tag.Name).Layout),
+						layout.Rigid(material.Caption(&p.th.Theme, fmt.Sprintf("%d uses in the last week", mastodon2.TotalUses(t.tag.History))).Layout),
+					)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layoutSparkline(gtx, p.th.ContrastBg, mastodon2.HistoryUses(t.tag.History))
+				}),
+			)
+		})
+	})
+}
+
+func (p *MessageColumn) layoutTrendingLink(gtx C, l *trendingLinkState) D {
+	return l.button.Layout(gtx, func(gtx C) D {
+		return layoutCard(gtx, p.th, func(gtx C) D {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					title := material.Body1(&p.th.Theme, l.link.Title)
+					title.Font.Weight = font.Bold
+					return title.Layout(gtx)
+				}),
+				layout.Rigid(func(gtx C) D {
+					desc := material.Body2(&p.th.Theme, l.link.Description)
+					desc.MaxLines = 3
+					return desc.Layout(gtx)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.Flex{
+						Axis:      layout.Horizontal,
+						Alignment: layout.Middle,
+					}.Layout(gtx,
+						layout.Flexed(1, material.Caption(&p.th.Theme, l.link.ProviderName).Layout),
+						layout.Rigid(func(gtx C) D {
+							return layoutSparkline(gtx, p.th.ContrastBg, mastodon2.HistoryUses(l.link.History))
+						}),
+					)
+				}),
+			)
+		})
+	})
+}
+
+// layoutCard displays w on the same rounded background as statuses.
+func layoutCard(gtx C, th *ShipdonTheme, w layout.Widget) D {
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			rrect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(10))
+			paint.FillShape(gtx.Ops, th.Bg, rrect.Op(gtx.Ops))
+			return D{Size: gtx.Constraints.Min}
+		}),
+		layout.Stacked(func(gtx C) D {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return layout.UniformInset(unit.Dp(8)).Layout(gtx, w)
+		}),
+	)
+}
+
+// layoutSparkline draws a small line chart of values (oldest first).
+func layoutSparkline(gtx C, colour color.NRGBA, values []int) D {
+	size := image.Pt(gtx.Dp(sparklineWidth), gtx.Dp(sparklineHeight))
+	if len(values) < 2 {
+		return D{Size: size}
+	}
+
+	highest := slices.Max(values)
+	if highest == 0 {
+		highest = 1
+	}
+
+	// leave room for the line width at the top and bottom.
+	lineWidth := float32(gtx.Dp(2))
+	height := float32(size.Y) - lineWidth
+	step := float32(size.X) / float32(len(values)-1)
+
+	var path clip.Path
+	path.Begin(gtx.Ops)
+	for i, v := range values {
+		pt := f32.Pt(float32(i)*step, lineWidth/2+height-height*float32(v)/float32(highest))
+		if i == 0 {
+			path.MoveTo(pt)
+		} else {
+			path.LineTo(pt)
+		}
+	}
+	paint.FillShape(gtx.Ops, colour, clip.Stroke{Path: path.End(), Width: lineWidth}.Op())
+	return D{Size: size}
+}
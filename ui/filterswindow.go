@@ -0,0 +1,294 @@
+package ui
+
+import (
+	"gioui.org/app"
+	"gioui.org/font/gofont"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	"slices"
+	"strings"
+	"time"
+)
+
+// filterExpiries are the expiries that can be set on a filter. "unchanged" leaves the current expiry
+// (or no expiry for new filters), "never" removes it.
+var filterExpiries = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"unchanged", 0},
+	{"never", mastodon2.FilterNoExpiry},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"1w", 7 * 24 * time.Hour},
+}
+
+// keywordEditor is one keyword of a filter being edited.
+type keywordEditor struct {
+	id           mastodon.ID
+	keyword      widget.Editor
+	wholeWord    widget.Bool
+	removeButton widget.Clickable
+}
+
+func newKeywordEditor(k mastodon2.FilterKeyword) *keywordEditor {
+	ke := &keywordEditor{id: k.ID}
+	ke.keyword.SingleLine = true
+	ke.keyword.SetText(k.Keyword)
+	ke.wholeWord.Value = k.WholeWord
+	return ke
+}
+
+// filterEditor is the editable state for one filter in the filters window.
+type filterEditor struct {
+	filter mastodon2.Filter
+
+	title    widget.Editor
+	contexts []widget.Bool
+	action   widget.Enum
+	expiry   widget.Enum
+
+	keywords []*keywordEditor
+	// IDs of existing keywords that have been removed, so they can be deleted on save.
+	removedKeywords []mastodon.ID
+
+	addKeywordButton widget.Clickable
+	saveButton       widget.Clickable
+	deleteButton     widget.Clickable
+}
+
+func newFilterEditor(filter mastodon2.Filter) *filterEditor {
+	fe := &filterEditor{filter: filter}
+	fe.title.SingleLine = true
+	fe.title.SetText(filter.Title)
+	fe.contexts = make([]widget.Bool, len(mastodon2.FilterContexts))
+	for i, c := range mastodon2.FilterContexts {
+		fe.contexts[i].Value = slices.Contains(filter.Context, c)
+	}
+	fe.action.Value = filter.FilterAction
+	if fe.action.Value == "" {
+		fe.action.Value = mastodon2.FilterActionWarn
+	}
+	fe.expiry.Value = filterExpiries[0].label
+	for _, k := range filter.Keywords {
+		fe.keywords = append(fe.keywords, newKeywordEditor(k))
+	}
+	return fe
+}
+
+// edited is the filter with the changes made in the window.
+func (fe *filterEditor) edited() mastodon2.Filter {
+	f := fe.filter
+	f.Title = fe.title.Text()
+	f.FilterAction = fe.action.Value
+	f.Context = nil
+	for i, c := range mastodon2.FilterContexts {
+		if fe.contexts[i].Value {
+			f.Context = append(f.Context, c)
+		}
+	}
+	f.Keywords = nil
+	for _, ke := range fe.keywords {
+		keyword := strings.TrimSpace(ke.keyword.Text())
+		if keyword == "" {
+			if ke.id != "" && !slices.Contains(fe.removedKeywords, ke.id) {
+				fe.removedKeywords = append(fe.removedKeywords, ke.id)
+			}
+			continue
+		}
+		f.Keywords = append(f.Keywords, mastodon2.FilterKeyword{ID: ke.id, Keyword: keyword, WholeWord: ke.wholeWord.Value})
+	}
+	return f
+}
+
+// expiresIn is the expiry chosen, 0 if it should be left unchanged or FilterNoExpiry to remove it.
+func (fe *filterEditor) expiresIn() time.Duration {
+	for _, e := range filterExpiries {
+		if e.label == fe.expiry.Value {
+			return e.duration
+		}
+	}
+	return 0
+}
+
+func (fe *filterEditor) expiryText() string {
+	if fe.filter.ExpiresAt == nil {
+		return "Never expires"
+	}
+	if fe.filter.Expired() {
+		return "Expired"
+	}
+	return "Expires in " + timeLeft(*fe.filter.ExpiresAt)
+}
+
+func (fe *filterEditor) Layout(gtx C, th *material.Theme) D {
+	var contexts []layout.FlexChild
+	for i, c := range mastodon2.FilterContexts {
+		contexts = append(contexts, layout.Rigid(material.CheckBox(th, &fe.contexts[i], c).Layout))
+	}
+
+	var expiries []layout.FlexChild
+	for _, e := range filterExpiries {
+		expiries = append(expiries, layout.Rigid(material.RadioButton(th, &fe.expiry, e.label, e.label).Layout))
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(material.Editor(th, &fe.title, "Title").Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, contexts...)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Rigid(material.Body2(th, "Matching statuses: ").Layout),
+				layout.Rigid(material.RadioButton(th, &fe.action, mastodon2.FilterActionWarn, "Show with a warning").Layout),
+				layout.Rigid(material.RadioButton(th, &fe.action, mastodon2.FilterActionHide, "Hide completely").Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				append([]layout.FlexChild{layout.Rigid(material.Body2(th, fe.expiryText()+" : ").Layout)}, expiries...)...)
+		}),
+	}
+
+	for _, ke := range fe.keywords {
+		ke := ke
+		children = append(children, layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, material.Editor(th, &ke.keyword, "Keyword or phrase").Layout),
+				layout.Rigid(material.CheckBox(th, &ke.wholeWord, "Whole word").Layout),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Button(th, &ke.removeButton, "Remove").Layout),
+			)
+		}))
+	}
+
+	children = append(children,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Rigid(material.Button(th, &fe.addKeywordButton, "Add keyword").Layout),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Button(th, &fe.saveButton, "Save").Layout),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Button(th, &fe.deleteButton, "Delete").Layout),
+			)
+		}),
+		layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout),
+	)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// openFiltersWindow lets the user create, edit and delete the account's filters.
+func openFiltersWindow(backend mastodon2.Backend) {
+	w := new(app.Window)
+	w.Option(
+		app.Title("Filters for "+backend.AccountName()),
+		app.Size(unit.Dp(800), unit.Dp(600)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	var editors []*filterEditor
+	for _, f := range backend.GetFilters() {
+		editors = append(editors, newFilterEditor(f))
+	}
+
+	var newButton widget.Clickable
+	var closeButton widget.Clickable
+	var scroll widget.List
+	scroll.Axis = layout.Vertical
+
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if closeButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			if newButton.Clicked(gtx) {
+				fe := newFilterEditor(mastodon2.Filter{
+					Context:      []string{mastodon2.FilterContextHome, mastodon2.FilterContextPublic},
+					FilterAction: mastodon2.FilterActionWarn,
+				})
+				fe.keywords = append(fe.keywords, newKeywordEditor(mastodon2.FilterKeyword{WholeWord: true}))
+				editors = append(editors, fe)
+			}
+
+			saved := false
+			for _, fe := range editors {
+				if fe.addKeywordButton.Clicked(gtx) {
+					fe.keywords = append(fe.keywords, newKeywordEditor(mastodon2.FilterKeyword{WholeWord: true}))
+				}
+
+				fe.keywords = slices.DeleteFunc(fe.keywords, func(ke *keywordEditor) bool {
+					if !ke.removeButton.Clicked(gtx) {
+						return false
+					}
+					if ke.id != "" {
+						fe.removedKeywords = append(fe.removedKeywords, ke.id)
+					}
+					return true
+				})
+
+				if fe.saveButton.Clicked(gtx) && fe.title.Text() != "" {
+					if err := backend.SaveFilter(fe.edited(), fe.removedKeywords, fe.expiresIn()); err == nil {
+						saved = true
+					}
+				}
+			}
+
+			editors = slices.DeleteFunc(editors, func(fe *filterEditor) bool {
+				if !fe.deleteButton.Clicked(gtx) {
+					return false
+				}
+				if fe.filter.ID == "" {
+					return true
+				}
+				return backend.DeleteFilter(fe.filter.ID) == nil
+			})
+
+			// new filters and keywords get their IDs from the server, so reload after a save.
+			if saved {
+				editors = editors[:0]
+				for _, f := range backend.GetFilters() {
+					editors = append(editors, newFilterEditor(f))
+				}
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Flexed(1, material.Body1(th, "Statuses matching a filter are hidden or shown with a warning").Layout),
+							layout.Rigid(material.Button(th, &newButton, "New filter").Layout),
+							layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+							layout.Rigid(material.Button(th, &closeButton, "Close").Layout),
+						)
+					}),
+					layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout),
+					layout.Flexed(1, func(gtx C) D {
+						return material.List(th, &scroll).Layout(gtx, len(editors), func(gtx C, index int) D {
+							return editors[index].Layout(gtx, th)
+						})
+					}),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ui
+
+import (
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/mattn/go-mastodon"
+	"image"
+)
+
+// FollowRequestState is an account waiting for us to accept their follow request.
+type FollowRequestState struct {
+	ComponentState
+
+	account mastodon.Account
+	avatar  widget.Image
+
+	AcceptButton widget.Clickable
+	RejectButton widget.Clickable
+
+	th *ShipdonTheme
+}
+
+// NewFollowRequestState builds an empty state.
+func NewFollowRequestState(componentState ComponentState, th *ShipdonTheme) *FollowRequestState {
+	return &FollowRequestState{
+		ComponentState: componentState,
+		th:             th,
+	}
+}
+
+func (fs *FollowRequestState) syncFollowRequestToUI(account mastodon.Account) {
+	fs.account = account
+	fs.avatar = loadAvatar(account.Username, account.Avatar)
+}
+
+type FollowRequestStyle struct {
+	state *FollowRequestState
+}
+
+func NewFollowRequestStyle(th *material.Theme, followRequest *FollowRequestState) FollowRequestStyle {
+	return FollowRequestStyle{
+		state: followRequest,
+	}
+}
+
+// Layout displays the account requesting to follow us, with buttons to accept or reject it.
+func (i FollowRequestStyle) Layout(gtx C) D {
+	const spacing = unit.Dp(4)
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			rrect := clip.UniformRRect(image.Rectangle{Max: gtx.Constraints.Min}, gtx.Dp(10))
+			paint.FillShape(gtx.Ops, i.state.th.Bg, rrect.Op(gtx.Ops))
+			return D{Size: gtx.Constraints.Min}
+		}),
+
+		layout.Stacked(func(gtx C) D {
+			return layout.UniformInset(spacing).Layout(gtx, func(gtx C) D {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+
+				return layout.Flex{
+					Axis:      layout.Horizontal,
+					Alignment: layout.Middle,
+				}.Layout(gtx,
+					layout.Rigid(i.state.avatar.Layout),
+					layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+					layout.Flexed(1, func(gtx C) D {
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(material.Body1(&i.state.th.Theme, i.state.account.DisplayName).Layout),
+							layout.Rigid(material.Caption(&i.state.th.Theme, "@"+i.state.account.Acct).Layout),
+						)
+					}),
+					layout.Rigid(func(gtx C) D {
+						return layoutFollowRequestButtons(gtx, i.state.th, &i.state.AcceptButton, &i.state.RejectButton)
+					}),
+				)
+			})
+		}),
+	)
+}
+
+// layoutFollowRequestButtons displays accept and reject buttons. Used by the follow requests column and notifications.
+func layoutFollowRequestButtons(gtx C, th *ShipdonTheme, accept *widget.Clickable, reject *widget.Clickable) D {
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+		layout.Rigid(material.Button(&th.Theme, accept, "Accept").Layout),
+		layout.Rigid(layout.Spacer{Width: unit.Dp(4)}.Layout),
+		layout.Rigid(material.Button(&th.Theme, reject, "Reject").Layout),
+	)
+}
@@ -52,7 +52,7 @@ func openNewWindow(url string) {
 	}
 
 	log.Debugf("full image download took %d ms", time.Now().Sub(start).Milliseconds())
-	ctx, _ := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
 		<-ctx.Done()
@@ -84,8 +84,12 @@ func openNewWindow(url string) {
 	}
 
 	for {
-		e := w.Event()
-		if e, ok := e.(app.FrameEvent); ok {
+		switch e := w.Event().(type) {
+		case app.DestroyEvent:
+			// window has been closed.
+			cancel()
+			return
+		case app.FrameEvent:
 			gtx := app.NewContext(&ops, e)
 
 			inset.Layout(gtx,
@@ -0,0 +1,287 @@
+package ui
+
+import (
+	"gioui.org/app"
+	"gioui.org/font/gofont"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"slices"
+)
+
+// listEditor is the editable state for one list in the lists window.
+type listEditor struct {
+	list *mastodon2.List
+
+	title         widget.Editor
+	repliesPolicy widget.Enum
+	exclusive     widget.Bool
+
+	saveButton    widget.Clickable
+	deleteButton  widget.Clickable
+	membersButton widget.Clickable
+
+	// members are only retrieved when asked for.
+	showMembers   bool
+	members       []*mastodon.Account
+	removeButtons []widget.Clickable
+}
+
+func newListEditor(list *mastodon2.List) *listEditor {
+	le := &listEditor{list: list}
+	le.title.SingleLine = true
+	le.title.SetText(list.Title)
+	le.repliesPolicy.Value = list.RepliesPolicy
+	le.exclusive.Value = list.Exclusive
+	return le
+}
+
+// edited is the list with the changes made in the window.
+func (le *listEditor) edited() mastodon2.List {
+	l := *le.list
+	l.Title = le.title.Text()
+	l.RepliesPolicy = le.repliesPolicy.Value
+	l.Exclusive = le.exclusive.Value
+	return l
+}
+
+func (le *listEditor) loadMembers(backend mastodon2.Backend) {
+	members, err := backend.GetListAccounts(le.list.ID)
+	if err != nil {
+		log.Errorf("unable to get list members %v", err)
+		return
+	}
+	le.members = members
+	le.removeButtons = make([]widget.Clickable, len(members))
+}
+
+func (le *listEditor) Layout(gtx C, th *material.Theme) D {
+	children := []layout.FlexChild{
+		layout.Rigid(material.Editor(th, &le.title, "Title").Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Rigid(material.Body2(th, "Show replies to: ").Layout),
+				layout.Rigid(material.RadioButton(th, &le.repliesPolicy, mastodon2.RepliesPolicyFollowed, "Followed users").Layout),
+				layout.Rigid(material.RadioButton(th, &le.repliesPolicy, mastodon2.RepliesPolicyList, "List members").Layout),
+				layout.Rigid(material.RadioButton(th, &le.repliesPolicy, mastodon2.RepliesPolicyNone, "No one").Layout),
+			)
+		}),
+		layout.Rigid(material.CheckBox(th, &le.exclusive, "Hide members' statuses from home").Layout),
+		layout.Rigid(func(gtx C) D {
+			membersText := "Members"
+			if le.showMembers {
+				membersText = "Hide Members"
+			}
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Rigid(material.Button(th, &le.saveButton, "Save").Layout),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Button(th, &le.deleteButton, "Delete").Layout),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Button(th, &le.membersButton, membersText).Layout),
+			)
+		}),
+	}
+
+	if le.showMembers {
+		if len(le.members) == 0 {
+			children = append(children, layout.Rigid(material.Caption(th, "No members").Layout))
+		}
+		for i, m := range le.members {
+			i, m := i, m
+			children = append(children, layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, material.Body2(th, m.DisplayName+" @"+m.Acct).Layout),
+					layout.Rigid(material.Button(th, &le.removeButtons[i], "Remove").Layout),
+				)
+			}))
+		}
+	}
+
+	children = append(children, layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout))
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// openListsWindow lets the user create, edit and delete the account's lists and remove members.
+// Returns true if any lists were changed.
+func openListsWindow(backend mastodon2.Backend) bool {
+	lists, err := backend.GetLists()
+	if err != nil {
+		log.Errorf("unable to get lists %v", err)
+		return false
+	}
+
+	w := new(app.Window)
+	w.Option(
+		app.Title("Lists for "+backend.AccountName()),
+		app.Size(unit.Dp(800), unit.Dp(600)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	var editors []*listEditor
+	for _, l := range lists {
+		editors = append(editors, newListEditor(l))
+	}
+
+	newTitle := widget.Editor{SingleLine: true}
+	var createButton widget.Clickable
+	var closeButton widget.Clickable
+	var scroll widget.List
+	scroll.Axis = layout.Vertical
+
+	changed := false
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return changed
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if closeButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			if createButton.Clicked(gtx) && newTitle.Text() != "" {
+				list, err := backend.CreateList(mastodon2.List{Title: newTitle.Text(), RepliesPolicy: mastodon2.RepliesPolicyList})
+				if err == nil {
+					editors = append(editors, newListEditor(list))
+					newTitle.SetText("")
+					changed = true
+				}
+			}
+
+			for _, le := range editors {
+				if le.saveButton.Clicked(gtx) {
+					list, err := backend.UpdateList(le.edited())
+					if err == nil {
+						le.list = list
+						changed = true
+					}
+				}
+
+				if le.membersButton.Clicked(gtx) {
+					le.showMembers = !le.showMembers
+					if le.showMembers {
+						le.loadMembers(backend)
+					}
+				}
+
+				for i := range le.removeButtons {
+					if le.removeButtons[i].Clicked(gtx) {
+						if err := backend.RemoveFromList(le.list.ID, le.members[i].ID); err == nil {
+							le.loadMembers(backend)
+							break
+						}
+					}
+				}
+			}
+
+			editors = slices.DeleteFunc(editors, func(le *listEditor) bool {
+				if !le.deleteButton.Clicked(gtx) {
+					return false
+				}
+				if err := backend.DeleteList(le.list.ID); err != nil {
+					return false
+				}
+				changed = true
+				return true
+			})
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Flexed(1, material.Editor(th, &newTitle, "New list title").Layout),
+							layout.Rigid(material.Button(th, &createButton, "Create").Layout),
+							layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+							layout.Rigid(material.Button(th, &closeButton, "Close").Layout),
+						)
+					}),
+					layout.Rigid(layout.Spacer{Height: unit.Dp(15)}.Layout),
+					layout.Flexed(1, func(gtx C) D {
+						return material.List(th, &scroll).Layout(gtx, len(editors), func(gtx C, index int) D {
+							return editors[index].Layout(gtx, th)
+						})
+					}),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
+
+// openUserListsWindow lets the user choose which lists an account is in.
+func openUserListsWindow(backend mastodon2.Backend, account *mastodon.Account) {
+	lists, err := backend.GetLists()
+	if err != nil {
+		log.Errorf("unable to get lists %v", err)
+		return
+	}
+	memberOf, err := backend.GetAccountLists(account.ID)
+	if err != nil {
+		log.Errorf("unable to get lists for account %v", err)
+		return
+	}
+
+	w := new(app.Window)
+	w.Option(
+		app.Title("Lists for "+account.Acct),
+		app.Size(unit.Dp(400), unit.Dp(400)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	inList := make([]widget.Bool, len(lists))
+	for i, l := range lists {
+		inList[i].Value = slices.ContainsFunc(memberOf, func(m *mastodon2.List) bool { return m.ID == l.ID })
+	}
+	var saveButton widget.Clickable
+
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if saveButton.Clicked(gtx) {
+				for i, l := range lists {
+					wasIn := slices.ContainsFunc(memberOf, func(m *mastodon2.List) bool { return m.ID == l.ID })
+					switch {
+					case inList[i].Value && !wasIn:
+						backend.AddToList(l.ID, account.ID)
+					case !inList[i].Value && wasIn:
+						backend.RemoveFromList(l.ID, account.ID)
+					}
+				}
+				w.Perform(system.ActionClose)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				children := []layout.FlexChild{
+					layout.Rigid(material.Body1(th, "Lists (you must follow an account to add it)").Layout),
+				}
+				for i, l := range lists {
+					children = append(children, layout.Rigid(material.CheckBox(th, &inList[i], l.Title).Layout))
+				}
+				children = append(children,
+					layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+					layout.Rigid(material.Button(th, &saveButton, "Save").Layout),
+				)
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	"image"
+	"slices"
+)
+
+// markerTimeline is the read marker for the column, empty if the column doesn't have one.
+// Only the unfiltered home and notifications timelines have markers.
+func (p *MessageColumn) markerTimeline() string {
+	switch {
+	case p.columnType == HomeColumn && p.timelineID == "home":
+		return mastodon2.MarkerHome
+	case p.columnType == NotificationsColumn && p.timelineID == mastodon2.NotificationsTimelineID:
+		return mastodon2.MarkerNotifications
+	}
+	return ""
+}
+
+// keepReadingPosition is called before the list is laid out. ids are the statuses (or notifications)
+// in the list, newest first.
+// The first time the column has content, it's scrolled to the read marker, and again if the polled marker
+// is newer than ours (read on another client). Otherwise the list is kept on the same item when newer
+// items are added above it, unless we're at the very top.
+func (p *MessageColumn) keepReadingPosition(ids []mastodon.ID) {
+	timeline := p.markerTimeline()
+	if timeline == "" || len(ids) == 0 {
+		return
+	}
+
+	marker, ok := p.backend.GetMarker(timeline)
+	if !ok {
+		return
+	}
+	if !p.markerRestored || mastodon2.IDNewer(marker.LastReadID, p.markerID) {
+		p.markerRestored = true
+		p.markerID = marker.LastReadID
+		p.dividerID = marker.LastReadID
+		if i := slices.Index(ids, marker.LastReadID); i >= 0 {
+			p.statusList.Position.First = i
+			p.statusList.Position.Offset = 0
+		}
+		return
+	}
+
+	if p.topID == "" || (p.statusList.Position.First == 0 && p.statusList.Position.Offset == 0) {
+		return
+	}
+	if i := slices.Index(ids, p.topID); i >= 0 {
+		p.statusList.Position.First = i
+	}
+}
+
+// markRead is called after the list is laid out. The first visible item has been read, so the
+// marker is moved up to it.
+func (p *MessageColumn) markRead(ids []mastodon.ID) {
+	timeline := p.markerTimeline()
+	if timeline == "" || !p.markerRestored || len(ids) == 0 {
+		return
+	}
+
+	// gap markers can't be read, so use the first status below one.
+	for _, id := range ids[min(p.statusList.Position.First, len(ids)-1):] {
+		if !mastodon2.IsGapID(id) {
+			p.topID = id
+			if mastodon2.IDNewer(id, p.markerID) {
+				p.markerID = id
+			}
+			p.backend.SetMarker(timeline, p.topID)
+			return
+		}
+	}
+}
+
+// layoutWithUnreadDivider displays the item, with the unread divider above it if the marker pointed
+// to it when the column was opened. Everything above the divider was unread.
+func (p *MessageColumn) layoutWithUnreadDivider(gtx C, index int, id mastodon.ID, w layout.Widget) D {
+	if index == 0 || id != p.dividerID {
+		return w(gtx)
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: unit.Dp(12), Right: unit.Dp(12)}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, p.layoutDividerLine),
+					layout.Rigid(func(gtx C) D {
+						l := material.Caption(&p.th.Theme, "unread since marker")
+						l.Color = p.th.ContrastBg
+						return layout.Inset{Left: unit.Dp(4), Right: unit.Dp(4)}.Layout(gtx, l.Layout)
+					}),
+					layout.Flexed(1, p.layoutDividerLine),
+				)
+			})
+		}),
+		layout.Rigid(w),
+	)
+}
+
+func (p *MessageColumn) layoutDividerLine(gtx C) D {
+	size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(2))
+	paint.FillShape(gtx.Ops, p.th.ContrastBg, clip.Rect{Max: size}.Op())
+	return D{Size: size}
+}
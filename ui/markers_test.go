@@ -0,0 +1,42 @@
+package ui
+
+import (
+	"testing"
+
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+)
+
+func TestKeepReadingPositionFollowsNewerMarker(t *testing.T) {
+	p, backend := newTestColumn(t, "home", HomeColumn)
+	ids := []mastodon.ID{"110000000000000004", "110000000000000003", fixtureStatus, fixtureReply}
+
+	// opening the column scrolls to the fixture's marker.
+	p.keepReadingPosition(ids)
+	if p.statusList.Position.First != 3 || p.dividerID != fixtureReply {
+		t.Fatalf("restored to %d (divider %s), want the fixture marker", p.statusList.Position.First, p.dividerID)
+	}
+
+	// reading here moves the marker to the status we're on, which mustn't scroll us anywhere.
+	p.statusList.Position.First = 2
+	p.markRead(ids)
+	p.keepReadingPosition(ids)
+	if p.statusList.Position.First != 2 {
+		t.Errorf("at %d after our own marker update, want 2", p.statusList.Position.First)
+	}
+
+	// a newer marker polled from the server (read on another client) is followed.
+	backend.SetMarker(mastodon2.MarkerHome, "110000000000000004")
+	p.keepReadingPosition(ids)
+	if p.statusList.Position.First != 0 || p.dividerID != "110000000000000004" {
+		t.Errorf("at %d (divider %s), want the newer marker", p.statusList.Position.First, p.dividerID)
+	}
+
+	// scrolling back down to older statuses doesn't get pulled back to the marker.
+	p.statusList.Position.First = 3
+	p.markRead(ids)
+	p.keepReadingPosition(ids)
+	if p.statusList.Position.First != 3 {
+		t.Errorf("at %d after scrolling down, want 3", p.statusList.Position.First)
+	}
+}
@@ -31,10 +31,23 @@ const (
 	UserColumn
 	ThreadColumn
 	SearchColumn
+	BookmarksColumn
+	FavouritesColumn
+	ConversationsColumn
+	LocalColumn
+	FederatedColumn
+	ExploreColumn
+	FollowRequestsColumn
 
 	RefreshTimeDelta = 5 * time.Second
 )
 
+const (
+	// indent per level of replies in a thread column, and the deepest level we'll indent to.
+	ThreadIndent   = unit.Dp(16)
+	MaxThreadDepth = 6
+)
+
 // Define some convenient type aliases to make some things more concise.
 type (
 	C = layout.Context
@@ -47,10 +60,10 @@ type ColumnType int
 // UI state.
 type ComponentState struct {
 	controller *stream.Controller
-	backend    *mastodon2.MastodonBackend
+	backend    mastodon2.Backend
 }
 
-func NewComponentState(controller *stream.Controller, backend *mastodon2.MastodonBackend) ComponentState {
+func NewComponentState(controller *stream.Controller, backend mastodon2.Backend) ComponentState {
 	return ComponentState{
 		controller: controller,
 		backend:    backend,
@@ -85,7 +98,24 @@ type MessageColumn struct {
 
 	// list of status states to display
 	statusStateList []*StatusState
-	statusList      widget.List
+
+	// conversations column only. Cached by conversation ID.
+	conversationStateCache map[mastodon.ID]*ConversationState
+	conversationStateList  []*ConversationState
+
+	// follow requests column only. Cached by account ID.
+	followRequestStateCache map[mastodon.ID]*FollowRequestState
+	followRequestStateList  []*FollowRequestState
+
+	// notifications column only. Only notifications with buttons (eg. follow requests) need to keep
+	// their state between frames. Cached by notification ID.
+	notificationStateCache map[mastodon.ID]*NotificationState
+	notificationStateList  []*NotificationState
+
+	// explore column only.
+	trendingTags  []*trendingTagState
+	trendingLinks []*trendingLinkState
+	statusList    widget.List
 
 	// if now is later than timeStampForRefresh, then refresh via mastodon call.
 	timeStampForRefresh time.Time
@@ -97,12 +127,43 @@ type MessageColumn struct {
 
 	removeColumnButton widget.Clickable
 
+	// notifications columns only. Dismisses all notifications on the server.
+	clearNotificationsButton widget.Clickable
+
+	// read marker (home and notifications columns only). The column is scrolled to the marker once it
+	// has content, and again whenever another client moves it past markerID (the newest marker we've
+	// seen or set). The unread divider is shown above the status the marker was on at the time.
+	markerRestored bool
+	markerID       mastodon.ID
+	dividerID      mastodon.ID
+
+	// first visible item, so we keep our place when newer items are added above it.
+	topID mastodon.ID
+
+	// "load missing posts" buttons, keyed by gap marker ID.
+	gapButtons map[mastodon.ID]*widget.Clickable
+
 	icon *widget.Icon
 
 	nextEventRefreshTime time.Time
 
 	// for following/unfollowing user in the usercolumn
 	followClickable widget.Clickable
+
+	// for choosing which lists the user in the usercolumn is in.
+	listsClickable widget.Clickable
+
+	// for muting/blocking the user in the usercolumn, or their whole instance.
+	muteClickable        widget.Clickable
+	blockClickable       widget.Clickable
+	domainBlockClickable widget.Clickable
+	reportClickable      widget.Clickable
+
+	// display which account the column belongs to. Only needed if logged in with multiple accounts.
+	showAccountName bool
+
+	// show statuses with content warnings already expanded.
+	expandContentWarnings bool
 }
 
 // NewMessageColumn builds a messageColumns using a controller and backend.
@@ -118,6 +179,11 @@ func NewMessageColumn(componentState ComponentState, timelineName string, timeli
 		timeStampForRefresh: time.Now().Add(-10 * time.Second),
 		maxStatusToDisplay:  20,
 		statusStateCache:    make(map[mastodon.ID]StatusStateCacheEntry),
+
+		conversationStateCache:  make(map[mastodon.ID]*ConversationState),
+		followRequestStateCache: make(map[mastodon.ID]*FollowRequestState),
+		notificationStateCache:  make(map[mastodon.ID]*NotificationState),
+		gapButtons:              make(map[mastodon.ID]*widget.Clickable),
 	}
 
 	p.statusList.List.Axis = layout.Vertical
@@ -133,8 +199,18 @@ func NewMessageColumn(componentState ComponentState, timelineName string, timeli
 	return p
 }
 
+// refreshEvent generates the event to refresh this column.
+func (p *MessageColumn) refreshEvent(clearExisting bool) events.RefreshEvent {
+	return events.NewRefreshEvent(p.backend.AccountName(), p.timelineID, clearExisting, getRefreshTypeForColumnType(p.columnType))
+}
+
+// olderRefreshEvent generates the event to retrieve older messages for this column.
+func (p *MessageColumn) olderRefreshEvent() events.RefreshEvent {
+	return events.NewGetOlderRefreshEvents(p.backend.AccountName(), p.timelineID, getRefreshTypeForColumnType(p.columnType))
+}
+
 func (p *MessageColumn) PrintStats() {
-	fmt.Printf("MessageColumn %s: statusCache size %d\n", p.timelineName, len(p.statusStateCache))
+	log.Debugf("MessageColumn %s: statusCache size %d", p.timelineName, len(p.statusStateCache))
 }
 
 // Layout builds your UI within the operation list in gtx.
@@ -235,10 +311,26 @@ func (p *MessageColumn) layoutHeader(gtx C, haveRemoveButton bool) D {
 					return layout.UniformInset(12).Layout(gtx, func(gtx C) D {
 						l := material.H6(&p.th.Theme, p.timelineName)
 						l.Color = p.th.ContrastFg
-						return l.Layout(gtx)
+						if !p.showAccountName {
+							return l.Layout(gtx)
+						}
+
+						a := material.Caption(&p.th.Theme, p.backend.AccountName())
+						a.Color = p.th.ContrastFg
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(l.Layout),
+							layout.Rigid(a.Layout))
 					})
 				}),
 
+				layout.Rigid(func(gtx C) D {
+					if p.columnType != NotificationsColumn {
+						return D{}
+					}
+					ic, _ := widget.NewIcon(icons.CommunicationClearAll)
+					return in.Layout(gtx, material.IconButton(&p.th.Theme, &p.clearNotificationsButton, ic, "Clear Notifications").Layout)
+				}),
+
 				// add remove column button
 				layout.Rigid(func(gtx C) D {
 					if !haveRemoveButton {
@@ -327,6 +419,39 @@ func (p *MessageColumn) layoutUserInfo(gtx C) D {
 						layout.Rigid(func(gtx C) D {
 							return material.Label(&p.th.Theme, unit.Sp(15), fmt.Sprintf("%s", followText)).Layout(gtx)
 						}),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.ActionViewList)
+							listsButton := newIconButton(p.th, &p.listsClickable, ic, p.th.IconBackgroundColour)
+							listsButton.iconColour = p.th.IconActiveColour
+							return listsButton.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.AVVolumeOff)
+							muteButton := newIconButton(p.th, &p.muteClickable, ic, p.th.IconBackgroundColour)
+							muteButton.iconColour = moderationColour(p.th, relationship != nil && relationship.Muting)
+							return muteButton.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.ContentBlock)
+							blockButton := newIconButton(p.th, &p.blockClickable, ic, p.th.IconBackgroundColour)
+							blockButton.iconColour = moderationColour(p.th, relationship != nil && relationship.Blocking)
+							return blockButton.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							if mastodon2.AccountDomain(*userDetails) == "" {
+								return D{}
+							}
+							ic, _ := widget.NewIcon(icons.SocialDomain)
+							domainBlockButton := newIconButton(p.th, &p.domainBlockClickable, ic, p.th.IconBackgroundColour)
+							domainBlockButton.iconColour = moderationColour(p.th, relationship != nil && relationship.DomainBlocking)
+							return domainBlockButton.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.ActionReportProblem)
+							reportButton := newIconButton(p.th, &p.reportClickable, ic, p.th.IconBackgroundColour)
+							reportButton.iconColour = p.th.IconInactiveColour
+							return reportButton.Layout(gtx)
+						}),
 					)
 				}),
 			)
@@ -338,12 +463,22 @@ func (p *MessageColumn) layoutUserInfo(gtx C) D {
 func (p *MessageColumn) layoutNotifications(gtx C) D {
 
 	var err error
-	notifications, err := p.backend.GetNotifications()
+	notifications, err := p.backend.GetNotifications(p.timelineID)
 	if err != nil {
 		log.Errorf("unable to get notifications: %s", err)
 		material.Body1(&p.th.Theme, err.Error()).Layout(gtx)
 	}
 
+	// only the notifications displayed this frame have their buttons checked.
+	p.notificationStateList = []*NotificationState{}
+
+	ids := make([]mastodon.ID, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.ID
+	}
+	p.keepReadingPosition(ids)
+	defer p.markRead(ids)
+
 	if len(notifications) == 0 {
 		return D{
 			Size:     image.Point{400, 600},
@@ -356,25 +491,125 @@ func (p *MessageColumn) layoutNotifications(gtx C) D {
 	listStyle.AnchorStrategy = material.Overlay
 
 	ls := listStyle.Layout(gtx, len(notifications), func(gtx C, index int) D {
+		return p.layoutWithUnreadDivider(gtx, index, ids[index], func(gtx C) D {
+			return p.layoutNotification(gtx, notifications, index)
+		})
+	})
 
-		if time.Now().After(p.nextEventRefreshTime) {
-			// if we're trying to display within 20 of the last element, then fetch older
-			if index > len(notifications)-5 {
-				log.Debugf("retrieve older status updates")
-				// cause messages to get refreshed...
-				events.FireEvent(events.NewGetOlderRefreshEvents(p.timelineID, getRefreshTypeForColumnType(p.columnType)))
-				p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
-			} else {
+	return ls
+}
 
-				// if we've scrolled and have a tasklist thats greater than visible (assumption) but drawing the first one
-				// then refresh.
-				if len(notifications) > 40 && index == 0 {
-					log.Debugf("refreshing timeline %s", p.timelineID)
-					events.FireEvent(events.NewRefreshEvent(p.timelineID, true, getRefreshTypeForColumnType(p.columnType)))
-					p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
-				}
+// layoutNotification displays a single notification from the list.
+func (p *MessageColumn) layoutNotification(gtx C, notifications []*mastodon.Notification, index int) D {
+
+	if time.Now().After(p.nextEventRefreshTime) {
+		// if we're trying to display within 20 of the last element, then fetch older
+		if index > len(notifications)-5 {
+			log.Debugf("retrieve older status updates")
+			// cause messages to get refreshed...
+			events.FireEvent(p.olderRefreshEvent())
+			p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
+		} else {
+
+			// if we've scrolled and have a tasklist thats greater than visible (assumption) but drawing the first one
+			// then get anything newer. Don't clear, that would throw away the read position and gap markers.
+			if len(notifications) > 40 && index == 0 {
+				log.Debugf("refreshing timeline %s", p.timelineID)
+				events.FireEvent(p.refreshEvent(false))
+				p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
 			}
 		}
+	}
+
+	const baseInset = unit.Dp(12)
+	inset := layout.Inset{
+		Left:   baseInset,
+		Right:  baseInset,
+		Top:    baseInset * .5,
+		Bottom: baseInset * .5,
+	}
+	if index == 0 {
+		inset.Top = baseInset
+	}
+	if index == len(p.statusStateList)-1 {
+		inset.Bottom = baseInset
+	}
+
+	switch notifications[index].Type {
+	case "follow":
+		newNotificationState := NewNotificationState(p.ComponentState, p.th)
+		newNotificationState.syncNotificationToUI(*notifications[index], gtx)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
+	case "favourite":
+		newNotificationState := NewNotificationState(p.ComponentState, p.th)
+		newNotificationState.syncNotificationToUI(*notifications[index], gtx)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
+	case "update":
+		newNotificationState := NewNotificationState(p.ComponentState, p.th)
+		newNotificationState.syncNotificationToUI(*notifications[index], gtx)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
+	case "poll":
+		newNotificationState := NewNotificationState(p.ComponentState, p.th)
+		newNotificationState.syncNotificationToUI(*notifications[index], gtx)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
+	case "follow_request":
+		ns, ok := p.notificationStateCache[notifications[index].ID]
+		if !ok {
+			ns = NewNotificationState(p.ComponentState, p.th)
+			p.notificationStateCache[notifications[index].ID] = ns
+		}
+		ns.syncNotificationToUI(*notifications[index], gtx)
+		p.notificationStateList = append(p.notificationStateList, ns)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, ns).Layout)
+	case "reblog":
+		newNotificationState := NewNotificationState(p.ComponentState, p.th)
+		newNotificationState.syncNotificationToUI(*notifications[index], gtx)
+		return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
+	case "mention":
+		newStatusState := NewStatusState(p.ComponentState, p.th)
+		newStatusState.syncStatusToUI(*notifications[index].Status, gtx)
+		media, url := generateMedia(*notifications[index].Status)
+		newStatusState.img = media
+		newStatusState.imgOrigURL = url
+		return inset.Layout(gtx, NewStatusStyle(&p.th.Theme, newStatusState).Layout)
+	}
+
+	return D{
+		Size:     image.Point{400, 600},
+		Baseline: 0,
+	}
+}
+
+func (p *MessageColumn) layoutConversations(gtx C) D {
+	conversations, err := p.backend.GetConversations()
+	if err != nil {
+		log.Errorf("unable to get conversations: %s", err)
+	}
+
+	p.conversationStateList = []*ConversationState{}
+	for _, conv := range conversations {
+		cs, ok := p.conversationStateCache[conv.ID]
+		if !ok {
+			cs = NewConversationState(p.ComponentState, p.th)
+			p.conversationStateCache[conv.ID] = cs
+		}
+
+		// always resync, the last status or unread state may have changed.
+		cs.syncConversationToUI(*conv)
+		p.conversationStateList = append(p.conversationStateList, cs)
+	}
+
+	paint.FillShape(gtx.Ops, p.th.StatusBackgroundColour, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	listStyle := material.List(&p.th.Theme, &p.statusList)
+	listStyle.AnchorStrategy = material.Overlay
+
+	return listStyle.Layout(gtx, len(p.conversationStateList), func(gtx C, index int) D {
+
+		if time.Now().After(p.nextEventRefreshTime) && index > len(p.conversationStateList)-5 {
+			log.Debugf("retrieve older conversations")
+			events.FireEvent(p.olderRefreshEvent())
+			p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
+		}
 
 		const baseInset = unit.Dp(12)
 		inset := layout.Inset{
@@ -386,79 +621,102 @@ func (p *MessageColumn) layoutNotifications(gtx C) D {
 		if index == 0 {
 			inset.Top = baseInset
 		}
-		if index == len(p.statusStateList)-1 {
+		if index == len(p.conversationStateList)-1 {
 			inset.Bottom = baseInset
 		}
+		return inset.Layout(gtx, NewConversationStyle(&p.th.Theme, p.conversationStateList[index]).Layout)
+	})
+}
 
-		switch notifications[index].Type {
-		case "follow":
-			newNotificationState := NewNotificationState(p.ComponentState, p.th)
-			newNotificationState.syncNotificationToUI(*notifications[index], gtx)
-			return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
-		case "favourite":
-			newNotificationState := NewNotificationState(p.ComponentState, p.th)
-			newNotificationState.syncNotificationToUI(*notifications[index], gtx)
-			return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
-		case "update":
-			newNotificationState := NewNotificationState(p.ComponentState, p.th)
-			newNotificationState.syncNotificationToUI(*notifications[index], gtx)
-			return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
-		case "poll":
-			newNotificationState := NewNotificationState(p.ComponentState, p.th)
-			newNotificationState.syncNotificationToUI(*notifications[index], gtx)
-			return inset.Layout(gtx, NewNotificationStyle(&p.th.Theme, newNotificationState).Layout)
-		case "reblog":
-			fmt.Printf("reblog")
-		case "mention":
-			newStatusState := NewStatusState(p.ComponentState, p.th)
-			newStatusState.syncStatusToUI(*notifications[index].Status, gtx)
-			media, url := generateMedia(*notifications[index].Status)
-			newStatusState.img = media
-			newStatusState.imgOrigURL = url
-			return inset.Layout(gtx, NewStatusStyle(&p.th.Theme, newStatusState).Layout)
+func (p *MessageColumn) layoutFollowRequests(gtx C) D {
+	accounts, err := p.backend.GetFollowRequests()
+	if err != nil {
+		log.Errorf("unable to get follow requests: %s", err)
+	}
+
+	p.followRequestStateList = []*FollowRequestState{}
+	for _, account := range accounts {
+		fs, ok := p.followRequestStateCache[account.ID]
+		if !ok {
+			fs = NewFollowRequestState(p.ComponentState, p.th)
+			p.followRequestStateCache[account.ID] = fs
 		}
+		fs.syncFollowRequestToUI(*account)
+		p.followRequestStateList = append(p.followRequestStateList, fs)
+	}
 
-		return D{
-			Size:     image.Point{400, 600},
-			Baseline: 0,
+	paint.FillShape(gtx.Ops, p.th.StatusBackgroundColour, clip.Rect{Max: gtx.Constraints.Max}.Op())
+	listStyle := material.List(&p.th.Theme, &p.statusList)
+	listStyle.AnchorStrategy = material.Overlay
+
+	return listStyle.Layout(gtx, len(p.followRequestStateList), func(gtx C, index int) D {
+
+		if time.Now().After(p.nextEventRefreshTime) && index > len(p.followRequestStateList)-5 {
+			log.Debugf("retrieve older follow requests")
+			events.FireEvent(p.olderRefreshEvent())
+			p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
 		}
 
+		const baseInset = unit.Dp(12)
+		inset := layout.Inset{
+			Left:   baseInset,
+			Right:  baseInset,
+			Top:    baseInset * .5,
+			Bottom: baseInset * .5,
+		}
+		if index == 0 {
+			inset.Top = baseInset
+		}
+		if index == len(p.followRequestStateList)-1 {
+			inset.Bottom = baseInset
+		}
+		return inset.Layout(gtx, NewFollowRequestStyle(&p.th.Theme, p.followRequestStateList[index]).Layout)
 	})
-
-	return ls
 }
 
-func (p *MessageColumn) layoutStatusList(gtx C) D {
-
-	// special case for notifications
-	if p.timelineName == "notifications" {
-		return p.layoutNotifications(gtx)
+// statusEdited returns true if the displayed text of a status has changed between old and new.
+func statusEdited(old mastodon.Status, new mastodon.Status) bool {
+	if old.Reblog != nil && new.Reblog != nil {
+		return statusEdited(*old.Reblog, *new.Reblog)
 	}
+	return old.Content != new.Content ||
+		old.SpoilerText != new.SpoilerText ||
+		old.Sensitive != new.Sensitive ||
+		!old.EditedAt.Equal(new.EditedAt)
+}
 
-	var err error
-	messages, err := p.backend.GetTimeline(p.timelineID)
-	if err != nil {
-		log.Errorf("unable to get timeline for %s: %s", p.timelineName, err)
-		material.Body1(&p.th.Theme, err.Error()).Layout(gtx)
-	}
+// updateStatusStateList sets statusStateList to the states for messages, reusing cached states where possible.
+func (p *MessageColumn) updateStatusStateList(gtx C, messages []mastodon.Status) {
+	p.statusStateList = []*StatusState{}
 
-	if len(messages) == 0 {
-		return D{
-			Size:     image.Point{400, 600},
-			Baseline: 0,
-		}
-	}
+	filterContext := mastodon2.FilterContextForRefreshType(getRefreshTypeForColumnType(p.columnType))
 
-	p.statusStateList = []*StatusState{}
+	var depths map[mastodon.ID]int
+	if p.columnType == ThreadColumn {
+		depths = threadDepths(messages, mastodon.ID(p.timelineID))
+	}
 
 	// any that are not in statusStateCache, add them.
 	for _, status := range messages {
+
+		// gap markers are just displayed as a button, there is no status to sync.
+		if mastodon2.IsGapID(status.ID) {
+			gapState := NewStatusState(p.ComponentState, p.th)
+			gapState.status = status
+			p.statusStateList = append(p.statusStateList, gapState)
+			continue
+		}
+
 		if s, ok := p.statusStateCache[status.ID]; !ok {
 			newStatusState := NewStatusState(p.ComponentState, p.th)
 			newStatusState.syncStatusToUI(status, gtx)
 			p.statusStateCache[status.ID] = StatusStateCacheEntry{
 				statusState: newStatusState,
 			}
+		} else if statusEdited(s.statusState.status, status) {
+
+			// edited statuses need their text regenerated, not just the counts.
+			s.statusState.syncStatusToUI(status, gtx)
 		} else {
 
 			// make sure updates have occured, such as likes, boosts, etc.
@@ -475,6 +733,12 @@ func (p *MessageColumn) layoutStatusList(gtx C) D {
 		}
 		// update images since they might have been downloaded since last time
 		p.statusStateCache[status.ID].statusState.Avatar = generateAvatar(status.Account, secondaryAccount)
+		p.statusStateCache[status.ID].statusState.alwaysExpand = p.expandContentWarnings
+		p.statusStateCache[status.ID].statusState.filterWarnings = p.backend.FilterWarnings(status, filterContext)
+		if p.columnType == ThreadColumn {
+			p.statusStateCache[status.ID].statusState.targetStatus = status.ID == mastodon.ID(p.timelineID)
+			p.statusStateCache[status.ID].statusState.threadDepth = depths[status.ID]
+		}
 		media, url := generateMedia(status)
 
 		// storage widget.Image for later use
@@ -497,9 +761,52 @@ func (p *MessageColumn) layoutStatusList(gtx C) D {
 			delete(p.statusStateCache, k)
 		}
 	}
+}
+
+func (p *MessageColumn) layoutStatusList(gtx C) D {
+
+	// special case for notifications
+	if p.columnType == NotificationsColumn {
+		return p.layoutNotifications(gtx)
+	}
+
+	if p.columnType == ConversationsColumn {
+		return p.layoutConversations(gtx)
+	}
+
+	if p.columnType == ExploreColumn {
+		return p.layoutExplore(gtx)
+	}
+
+	if p.columnType == FollowRequestsColumn {
+		return p.layoutFollowRequests(gtx)
+	}
+
+	var err error
+	messages, err := p.backend.GetTimeline(p.timelineID)
+	if err != nil {
+		log.Errorf("unable to get timeline for %s: %s", p.timelineName, err)
+		material.Body1(&p.th.Theme, err.Error()).Layout(gtx)
+	}
+
+	if len(messages) == 0 {
+		return D{
+			Size:     image.Point{400, 600},
+			Baseline: 0,
+		}
+	}
+
+	p.updateStatusStateList(gtx, messages)
 
 	log.Debugf("statusStateList: %d", len(p.statusStateList))
 
+	ids := make([]mastodon.ID, len(p.statusStateList))
+	for i, ss := range p.statusStateList {
+		ids[i] = ss.status.ID
+	}
+	p.keepReadingPosition(ids)
+	defer p.markRead(ids)
+
 	paint.FillShape(gtx.Ops, p.th.StatusBackgroundColour, clip.Rect{Max: gtx.Constraints.Max}.Op())
 	listStyle := material.List(&p.th.Theme, &p.statusList)
 	listStyle.AnchorStrategy = material.Overlay
@@ -511,15 +818,15 @@ func (p *MessageColumn) layoutStatusList(gtx C) D {
 			if index > len(p.statusStateList)-5 {
 				log.Debugf("retrieve older status updates")
 				// cause messages to get refreshed...
-				events.FireEvent(events.NewGetOlderRefreshEvents(p.timelineID, getRefreshTypeForColumnType(p.columnType)))
+				events.FireEvent(p.olderRefreshEvent())
 				p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
 			} else {
 
 				// if we've scrolled and have a tasklist thats greater than visible (assumption) but drawing the first one
-				// then refresh.
+				// then get anything newer. Don't clear, that would throw away the read position and gap markers.
 				if len(p.statusStateList) > 40 && index == 0 {
 					log.Debugf("refreshing timeline %s", p.timelineID)
-					events.FireEvent(events.NewRefreshEvent(p.timelineID, true, getRefreshTypeForColumnType(p.columnType)))
+					events.FireEvent(p.refreshEvent(false))
 					p.nextEventRefreshTime = time.Now().Add(RefreshTimeDelta)
 				}
 			}
@@ -538,12 +845,55 @@ func (p *MessageColumn) layoutStatusList(gtx C) D {
 		if index == len(p.statusStateList)-1 {
 			inset.Bottom = baseInset
 		}
-		return inset.Layout(gtx, NewStatusStyle(&p.th.Theme, p.statusStateList[index]).Layout)
+		if mastodon2.IsGapID(ids[index]) {
+			return inset.Layout(gtx, func(gtx C) D {
+				return p.layoutGap(gtx, ids[index])
+			})
+		}
+
+		inset.Left += ThreadIndent * unit.Dp(min(p.statusStateList[index].threadDepth, MaxThreadDepth))
+		return p.layoutWithUnreadDivider(gtx, index, ids[index], func(gtx C) D {
+			return inset.Layout(gtx, NewStatusStyle(&p.th.Theme, p.statusStateList[index]).Layout)
+		})
 	})
 
 	return ls
 }
 
+// layoutGap displays a gap in the timeline (statuses we haven't fetched) as a button to load them.
+func (p *MessageColumn) layoutGap(gtx C, gapID mastodon.ID) D {
+	button, ok := p.gapButtons[gapID]
+	if !ok {
+		button = &widget.Clickable{}
+		p.gapButtons[gapID] = button
+	}
+
+	gtx.Constraints.Min.X = gtx.Constraints.Max.X
+	return material.Button(&p.th.Theme, button, "Load missing posts").Layout(gtx)
+}
+
+// threadDepths works out how deeply nested each status in a thread is. The ancestors and the status the
+// thread was opened from aren't indented, each reply is indented one more than the status it replies to.
+func threadDepths(messages []mastodon.Status, targetID mastodon.ID) map[mastodon.ID]int {
+	depths := make(map[mastodon.ID]int)
+	seenTarget := false
+	for _, status := range messages {
+		if !seenTarget {
+			// ancestors and the target itself.
+			depths[status.ID] = 0
+			seenTarget = status.ID == targetID
+			continue
+		}
+
+		if parent, ok := status.InReplyToID.(string); ok {
+			if d, ok := depths[mastodon.ID(parent)]; ok {
+				depths[status.ID] = d + 1
+			}
+		}
+	}
+	return depths
+}
+
 func getRefreshTypeForColumnType(columnType ColumnType) events.RefreshType {
 	switch columnType {
 	case HomeColumn:
@@ -556,6 +906,22 @@ func getRefreshTypeForColumnType(columnType ColumnType) events.RefreshType {
 		return events.HASHTAG_REFRESH
 	case UserColumn:
 		return events.USER_REFRESH
+	case ThreadColumn:
+		return events.THREAD_REFRESH
+	case BookmarksColumn:
+		return events.BOOKMARKS_REFRESH
+	case FavouritesColumn:
+		return events.FAVOURITES_REFRESH
+	case ConversationsColumn:
+		return events.CONVERSATIONS_REFRESH
+	case LocalColumn:
+		return events.LOCAL_REFRESH
+	case FederatedColumn:
+		return events.FEDERATED_REFRESH
+	case ExploreColumn:
+		return events.EXPLORE_REFRESH
+	case FollowRequestsColumn:
+		return events.FOLLOW_REQUESTS_REFRESH
 	}
 	return events.LIST_REFRESH
 }
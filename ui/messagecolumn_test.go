@@ -0,0 +1,196 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"gioui.org/layout"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+)
+
+const (
+	fixtureReply  = mastodon.ID("110000000000000001")
+	fixtureStatus = mastodon.ID("110000000000000002")
+)
+
+func newTestColumn(t *testing.T, timelineID string, columnType ColumnType) (*MessageColumn, *mastodon2.FakeBackend) {
+	t.Helper()
+
+	backend, err := mastodon2.NewFakeBackendFromFile(nil, "../mastodon/testdata/fixture.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMessageColumn(NewComponentState(nil, backend), timelineID, timelineID, columnType, GenerateLightTheme()), backend
+}
+
+// syncColumn updates the column's status states from the backend, as each frame does.
+func syncColumn(t *testing.T, p *MessageColumn) {
+	t.Helper()
+
+	messages, err := p.backend.GetTimeline(p.timelineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.updateStatusStateList(layout.Context{}, messages)
+}
+
+func columnIDs(p *MessageColumn) []mastodon.ID {
+	var ids []mastodon.ID
+	for _, s := range p.statusStateList {
+		ids = append(ids, s.status.ID)
+	}
+	return ids
+}
+
+func TestStatusEdited(t *testing.T) {
+	edited := time.Now()
+	status := mastodon.Status{ID: "1", Content: "<p>hello</p>", SpoilerText: "cw"}
+
+	for _, tc := range []struct {
+		name   string
+		change func(s *mastodon.Status)
+		want   bool
+	}{
+		{"unchanged", func(s *mastodon.Status) {}, false},
+		{"favourited", func(s *mastodon.Status) { s.Favourited = true; s.FavouritesCount = 1 }, false},
+		{"content", func(s *mastodon.Status) { s.Content = "<p>goodbye</p>" }, true},
+		{"content warning", func(s *mastodon.Status) { s.SpoilerText = "" }, true},
+		{"sensitive", func(s *mastodon.Status) { s.Sensitive = true }, true},
+		{"edited at", func(s *mastodon.Status) { s.EditedAt = edited }, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changed := status
+			tc.change(&changed)
+			if got := statusEdited(status, changed); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			// boosts are edited when the boosted status is.
+			boost, changedBoost := mastodon.Status{ID: "2", Reblog: &status}, mastodon.Status{ID: "2", Reblog: &changed}
+			if got := statusEdited(boost, changedBoost); got != tc.want {
+				t.Errorf("boost got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHasThread(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     mastodon.Status
+		want       bool
+		wantThread mastodon.ID
+	}{
+		{"on its own", mastodon.Status{ID: "1"}, false, "1"},
+		{"reply", mastodon.Status{ID: "1", InReplyToID: "0"}, true, "1"},
+		{"root with replies", mastodon.Status{ID: "1", RepliesCount: 2}, true, "1"},
+		{"boost of a reply", mastodon.Status{ID: "2", Reblog: &mastodon.Status{ID: "1", InReplyToID: "0"}}, true, "1"},
+		{"boost of a root with replies", mastodon.Status{ID: "2", Reblog: &mastodon.Status{ID: "1", RepliesCount: 1}}, true, "1"},
+		{"boost on its own", mastodon.Status{ID: "2", Reblog: &mastodon.Status{ID: "1"}}, false, "1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := hasThread(tc.status); got != tc.want {
+				t.Errorf("hasThread = %v, want %v", got, tc.want)
+			}
+			if got := threadStatus(tc.status).ID; got != tc.wantThread {
+				t.Errorf("thread of %s, want %s", got, tc.wantThread)
+			}
+		})
+	}
+}
+
+func TestMessageColumnSyncsWithBackend(t *testing.T) {
+	p, backend := newTestColumn(t, "home", HomeColumn)
+	syncColumn(t, p)
+
+	if got, want := columnIDs(p), []mastodon.ID{fixtureStatus, fixtureReply}; !slices.Equal(got, want) {
+		t.Fatalf("column = %v, want %v", got, want)
+	}
+	if got := p.statusStateList[1].filterWarnings; !slices.Equal(got, []string{"Old news"}) {
+		t.Errorf("filter warnings = %v, want [Old news]", got)
+	}
+	if got := p.statusStateList[0].filterWarnings; len(got) != 0 {
+		t.Errorf("filter warnings for unmatched status = %v", got)
+	}
+	state := p.statusStateList[0]
+
+	for _, tc := range []struct {
+		name   string
+		action func() error
+		check  func(ss *StatusState) bool
+	}{
+		{"favourite", func() error { return backend.SetFavourite(fixtureStatus, true) }, func(ss *StatusState) bool { return ss.status.Favourited == true }},
+		{"boost", func() error { return backend.Boost(fixtureStatus, true) }, func(ss *StatusState) bool { return ss.status.Reblogged == true }},
+		{"edit", func() error { return backend.EditStatus(fixtureStatus, &mastodon.Toot{Status: "edited"}) }, func(ss *StatusState) bool {
+			return ss.status.Content == "<p>edited</p>" && !ss.status.EditedAt.IsZero()
+		}},
+	} {
+		if err := tc.action(); err != nil {
+			t.Fatal(err)
+		}
+		syncColumn(t, p)
+
+		if p.statusStateList[0] != state {
+			t.Errorf("%s: status state wasn't reused", tc.name)
+		}
+		if !tc.check(p.statusStateList[0]) {
+			t.Errorf("%s: status not updated, got %+v", tc.name, p.statusStateList[0].status)
+		}
+	}
+
+	if err := backend.DeleteStatus(fixtureReply); err != nil {
+		t.Fatal(err)
+	}
+	syncColumn(t, p)
+	if got := columnIDs(p); !slices.Equal(got, []mastodon.ID{fixtureStatus}) {
+		t.Errorf("column after delete = %v", got)
+	}
+
+	if err := backend.Post(&mastodon.Toot{Status: "new post"}); err != nil {
+		t.Fatal(err)
+	}
+	syncColumn(t, p)
+	if got := columnIDs(p); len(got) != 2 || got[1] != fixtureStatus {
+		t.Errorf("column after post = %v, want the new post above", got)
+	}
+}
+
+func TestMessageColumnGap(t *testing.T) {
+	p, _ := newTestColumn(t, "home", HomeColumn)
+	p.updateStatusStateList(layout.Context{}, []mastodon.Status{{ID: fixtureStatus}, {ID: mastodon2.GapID(fixtureReply)}, {ID: fixtureReply}})
+
+	if got := len(p.statusStateList); got != 3 {
+		t.Fatalf("%d status states, want 3", got)
+	}
+	if !mastodon2.IsGapID(p.statusStateList[1].status.ID) {
+		t.Errorf("gap not kept in place: %v", columnIDs(p))
+	}
+	if _, ok := p.statusStateCache[mastodon2.GapID(fixtureReply)]; ok {
+		t.Error("gap marker cached as a status")
+	}
+}
+
+func TestMessageColumnThread(t *testing.T) {
+	p, backend := newTestColumn(t, string(fixtureStatus), ThreadColumn)
+	if err := backend.Post(&mastodon.Toot{Status: "a reply", InReplyToID: fixtureStatus}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backend.RefreshMessagesCallback(p.refreshEvent(false)); err != nil {
+		t.Fatal(err)
+	}
+	syncColumn(t, p)
+
+	if got := len(p.statusStateList); got != 2 {
+		t.Fatalf("thread has %d statuses, want 2", got)
+	}
+	target, reply := p.statusStateList[0], p.statusStateList[1]
+	if !target.targetStatus || target.threadDepth != 0 {
+		t.Errorf("target status: target %v depth %d", target.targetStatus, target.threadDepth)
+	}
+	if reply.targetStatus || reply.threadDepth != 1 {
+		t.Errorf("reply: target %v depth %d", reply.targetStatus, reply.threadDepth)
+	}
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"gioui.org/app"
+	"gioui.org/font/gofont"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"image/color"
+	"time"
+)
+
+// muteDurations are the durations offered when muting. 0 is indefinite.
+var muteDurations = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"Indefinitely", 0},
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// moderationColour is the icon colour for mute/block buttons. Active if the mute/block is in place.
+func moderationColour(th *ShipdonTheme, active bool) color.NRGBA {
+	if active {
+		return th.IconActiveColour
+	}
+	return th.IconInactiveColour
+}
+
+// openMuteWindow asks whether notifications should be muted too and for how long, then mutes the account.
+// Returns true if the account was muted.
+func openMuteWindow(backend mastodon2.Backend, account mastodon.Account) bool {
+	w := new(app.Window)
+	w.Option(
+		app.Title("Mute @"+account.Acct),
+		app.Size(unit.Dp(400), unit.Dp(300)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	notifications := widget.Bool{Value: true}
+	duration := widget.Enum{Value: muteDurations[0].label}
+	var muteButton widget.Clickable
+	var cancelButton widget.Clickable
+
+	muted := false
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return muted
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if cancelButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			if muteButton.Clicked(gtx) {
+				var d time.Duration
+				for _, md := range muteDurations {
+					if md.label == duration.Value {
+						d = md.duration
+					}
+				}
+				if err := backend.MuteAccount(account.ID, notifications.Value, d); err != nil {
+					log.Errorf("error muting account %+v", err)
+					alert("Mute", "Unable to mute @"+account.Acct+": "+err.Error())
+				} else {
+					muted = true
+				}
+				w.Perform(system.ActionClose)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				children := []layout.FlexChild{
+					layout.Rigid(material.Body1(th, "Statuses from @"+account.Acct+" will be hidden").Layout),
+					layout.Rigid(material.CheckBox(th, &notifications, "Hide notifications too").Layout),
+					layout.Rigid(material.Body2(th, "Duration").Layout),
+				}
+				for _, md := range muteDurations {
+					children = append(children, layout.Rigid(material.RadioButton(th, &duration, md.label, md.label).Layout))
+				}
+				children = append(children,
+					layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+							layout.Rigid(material.Button(th, &muteButton, "Mute").Layout),
+							layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+							layout.Rigid(material.Button(th, &cancelButton, "Cancel").Layout),
+						)
+					}),
+				)
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
+
+// moderationEntry is a muted/blocked account or blocked domain, with the button to undo it.
+type moderationEntry struct {
+	label  string
+	undo   func() error
+	button widget.Clickable
+}
+
+// loadModerationEntries gets the current mutes, blocks and domain blocks.
+func loadModerationEntries(backend mastodon2.Backend) (mutes []*moderationEntry, blocks []*moderationEntry, domainBlocks []*moderationEntry) {
+	accounts, err := backend.GetMutes()
+	if err != nil {
+		log.Errorf("unable to get mutes %v", err)
+	}
+	for _, a := range accounts {
+		id := a.ID
+		mutes = append(mutes, &moderationEntry{label: a.DisplayName + " @" + a.Acct, undo: func() error { return backend.UnmuteAccount(id) }})
+	}
+
+	accounts, err = backend.GetBlocks()
+	if err != nil {
+		log.Errorf("unable to get blocks %v", err)
+	}
+	for _, a := range accounts {
+		id := a.ID
+		blocks = append(blocks, &moderationEntry{label: a.DisplayName + " @" + a.Acct, undo: func() error { return backend.UnblockAccount(id) }})
+	}
+
+	domains, err := backend.GetDomainBlocks()
+	if err != nil {
+		log.Errorf("unable to get domain blocks %v", err)
+	}
+	for _, d := range domains {
+		domain := d
+		domainBlocks = append(domainBlocks, &moderationEntry{label: domain, undo: func() error { return backend.UnblockDomain(domain) }})
+	}
+	return mutes, blocks, domainBlocks
+}
+
+// openModerationWindow lists the account's mutes, blocks and domain blocks, and lets the user undo them.
+func openModerationWindow(backend mastodon2.Backend) {
+	w := new(app.Window)
+	w.Option(
+		app.Title("Mutes and blocks for "+backend.AccountName()),
+		app.Size(unit.Dp(600), unit.Dp(600)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	mutes, blocks, domainBlocks := loadModerationEntries(backend)
+	var closeButton widget.Clickable
+	var scroll widget.List
+	scroll.Axis = layout.Vertical
+
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if closeButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			undone := false
+			for _, entries := range [][]*moderationEntry{mutes, blocks, domainBlocks} {
+				for _, e := range entries {
+					if e.button.Clicked(gtx) && e.undo() == nil {
+						undone = true
+					}
+				}
+			}
+			if undone {
+				mutes, blocks, domainBlocks = loadModerationEntries(backend)
+			}
+
+			var rows []layout.Widget
+			for _, section := range []struct {
+				heading string
+				undo    string
+				entries []*moderationEntry
+			}{
+				{"Muted accounts", "Unmute", mutes},
+				{"Blocked accounts", "Unblock", blocks},
+				{"Blocked domains", "Unblock", domainBlocks},
+			} {
+				rows = append(rows, material.H6(th, section.heading).Layout)
+				if len(section.entries) == 0 {
+					rows = append(rows, material.Caption(th, "None").Layout)
+				}
+				for _, e := range section.entries {
+					e, undo := e, section.undo
+					rows = append(rows, func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Flexed(1, material.Body2(th, e.label).Layout),
+							layout.Rigid(material.Button(th, &e.button, undo).Layout),
+						)
+					})
+				}
+				rows = append(rows, layout.Spacer{Height: unit.Dp(15)}.Layout)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						return material.List(th, &scroll).Layout(gtx, len(rows), func(gtx C, index int) D {
+							return rows[index](gtx)
+						})
+					}),
+					layout.Rigid(material.Button(th, &closeButton, "Close").Layout),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
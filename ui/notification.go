@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"fmt"
 	"gioui.org/layout"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
@@ -40,6 +39,10 @@ type NotificationState struct {
 	FavouriteButton  widget.Clickable
 	ViewThreadButton widget.Clickable
 
+	// only for follow requests.
+	AcceptButton widget.Clickable
+	RejectButton widget.Clickable
+
 	Avatar widget.Image
 
 	// first image (if exists) to show.
@@ -71,8 +74,8 @@ func NewNotificationState(componentState ComponentState, th *ShipdonTheme) *Noti
 
 func (ss *NotificationState) generateAvatar(notification mastodon.Notification) widget.Image {
 	switch notification.Type {
-	case "favourite":
-		// generate avatar with both current user and person who favourited.
+	case "favourite", "reblog":
+		// generate avatar with both current user and person who favourited/boosted.
 		return generateAvatar(notification.Account, &notification.Status.Account)
 	}
 
@@ -99,10 +102,11 @@ func (ss *NotificationState) generateDetailsSpanStyles(notification mastodon.Not
 	switch notification.Type {
 	case "favourite":
 		spans = generateDetailsSpanStyles(*notification.Status, ss.th)
+	case "reblog":
+		spans = generateDetailsSpanStyles(*notification.Status, ss.th)
 	case "update":
 		spans = generateDetailsSpanStyles(*notification.Status, ss.th)
 	case "poll":
-		//spans = generatePollSpanStyles(*notification.Status, ss.th)
 		spans = generateDetailsSpanStyles(*notification.Status, ss.th)
 	}
 
@@ -133,6 +137,25 @@ func (ss *NotificationState) generateNameSpanStyles(notification mastodon.Notifi
 			Font:    fonts[0].Font,
 		}
 		spans = append(spans, span2)
+	case "follow_request":
+		span := richtext.SpanStyle{
+			Content:     notification.Account.DisplayName,
+			Color:       ss.th.Fg,
+			Size:        unit.Sp(17),
+			Font:        fonts[0].Font,
+			Interactive: true,
+		}
+		span.Set("username", notification.Account.Username)
+		span.Set("userID", notification.Account.ID)
+		spans = append(spans, span)
+
+		span2 := richtext.SpanStyle{
+			Content: " requested to follow you ",
+			Color:   ss.th.BoostedColour,
+			Size:    unit.Sp(16),
+			Font:    fonts[0].Font,
+		}
+		spans = append(spans, span2)
 	case "favourite":
 		span := richtext.SpanStyle{
 			Content:     notification.Account.DisplayName,
@@ -154,7 +177,7 @@ func (ss *NotificationState) generateNameSpanStyles(notification mastodon.Notifi
 		spans = append(spans, span2)
 	case "poll":
 
-		// TODO(kpfaulkner) adjust so we can handle more than just finished polls
+		// poll notifications are only sent once the poll has finished. Open polls are voted on in the status itself.
 		description := "ended"
 
 		span := richtext.SpanStyle{
@@ -176,7 +199,24 @@ func (ss *NotificationState) generateNameSpanStyles(notification mastodon.Notifi
 		}
 		spans = append(spans, span2)
 	case "reblog":
-		log.Debugf("reblog")
+		span := richtext.SpanStyle{
+			Content:     notification.Account.DisplayName,
+			Color:       ss.th.Fg,
+			Size:        unit.Sp(17),
+			Font:        fonts[0].Font,
+			Interactive: true,
+		}
+		span.Set("username", notification.Account.Username)
+		span.Set("userID", notification.Account.ID)
+		spans = append(spans, span)
+
+		span2 := richtext.SpanStyle{
+			Content: " boosted your status ",
+			Color:   ss.th.BoostedColour,
+			Size:    unit.Sp(16),
+			Font:    fonts[0].Font,
+		}
+		spans = append(spans, span2)
 	case "update":
 
 		span := richtext.SpanStyle{
@@ -325,6 +365,13 @@ func (i NotificationStyle) layoutNotification(gtx C) D {
 				// default to just layout of the details (probably a status update)
 				return i.state.DetailStyle.Layout(gtx)
 			}),
+
+			layout.Rigid(func(gtx C) D {
+				if i.state.notification.Type != "follow_request" {
+					return D{}
+				}
+				return layoutFollowRequestButtons(gtx, i.state.th, &i.state.AcceptButton, &i.state.RejectButton)
+			}),
 		)
 	})
 }
@@ -384,14 +431,10 @@ func (i NotificationStyle) layoutPoll(gtx C) D {
 			layout.Rigid(layout.Spacer{Height: spacing}.Layout),
 
 			layout.Rigid(func(gtx C) D {
-				content := ""
-				for ii, o := range i.state.notification.Status.Poll.Options {
-					content = content + fmt.Sprintf("%d : %s : (%d) \n", ii, o.Title, o.VotesCount)
+				if i.state.notification.Status.Poll == nil {
+					return D{}
 				}
-				content = content + fmt.Sprintf("Total Votes: %d\n", i.state.notification.Status.Poll.VotesCount)
-
-				l := material.Label(&i.state.th.Theme, unit.Sp(15), content)
-				return l.Layout(gtx)
+				return layoutPollResults(gtx, i.state.th, i.state.notification.Status.Poll)
 			}),
 		)
 	})
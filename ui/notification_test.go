@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-mastodon"
+)
+
+func TestNotificationDescription(t *testing.T) {
+	account := mastodon.Account{ID: "2", Username: "alice", DisplayName: "Alice"}
+	status := &mastodon.Status{ID: "1", Content: "<p>hello</p>", Account: mastodon.Account{ID: "1"}}
+
+	for _, tc := range []struct {
+		notificationType string
+		want             string
+	}{
+		{"follow", "Alice followed you"},
+		{"follow_request", "Alice requested to follow you"},
+		{"favourite", "Alice favourited your status"},
+		{"reblog", "Alice boosted your status"},
+		{"update", "Alice updated a post"},
+	} {
+		t.Run(tc.notificationType, func(t *testing.T) {
+			ns := NewNotificationState(ComponentState{}, GenerateLightTheme())
+			notification := mastodon.Notification{ID: "1", Type: tc.notificationType, Account: account, Status: status}
+
+			var got string
+			for _, span := range ns.generateNameSpanStyles(notification) {
+				got += span.Content
+			}
+			if strings.TrimSpace(got) != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if tc.notificationType == "reblog" && len(ns.generateDetailsSpanStyles(notification)) == 0 {
+				t.Error("boosted status not shown")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"golang.org/x/exp/shiny/materialdesign/icons"
+	"image"
+	"image/color"
+)
+
+var (
+	onlineColour  = color.NRGBA{R: 0, G: 180, B: 0, A: 255}
+	offlineColour = color.NRGBA{R: 200, G: 0, B: 0, A: 255}
+)
+
+// outboxItemState is an item waiting in the outbox, with buttons to move it back into the compose
+// form or discard it.
+type outboxItemState struct {
+	item mastodon2.OutboxItem
+
+	editButton    widget.Clickable
+	discardButton widget.Clickable
+}
+
+// syncOutbox updates the outbox items from the backend, keeping the button state of items we
+// already have.
+func (p *ComposeColumn) syncOutbox() {
+	items := p.backend.GetOutbox()
+
+	states := make(map[int64]*outboxItemState, len(items))
+	p.outboxItems = p.outboxItems[:0]
+	for _, item := range items {
+		state, ok := p.outboxStates[item.ID]
+		if !ok {
+			state = &outboxItemState{}
+		}
+		state.item = item
+		states[item.ID] = state
+		p.outboxItems = append(p.outboxItems, state)
+	}
+	p.outboxStates = states
+}
+
+// layoutOutbox displays whether the selected account's instance is reachable, and anything waiting
+// to be sent to it.
+func (p *ComposeColumn) layoutOutbox(gtx C) D {
+	p.syncOutbox()
+
+	status := "online"
+	statusColour := onlineColour
+	if !p.backend.IsOnline() {
+		status = "offline"
+		statusColour = offlineColour
+	}
+	if len(p.outboxItems) > 0 {
+		status = fmt.Sprintf("%s : %d in outbox", status, len(p.outboxItems))
+	}
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					size := gtx.Dp(8)
+					paint.FillShape(gtx.Ops, statusColour, clip.Ellipse{Max: image.Pt(size, size)}.Op(gtx.Ops))
+					return D{Size: image.Pt(size, size)}
+				}),
+				layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+				layout.Rigid(material.Caption(&p.th.Theme, status).Layout),
+			)
+		}),
+	}
+
+	for _, state := range p.outboxItems {
+		state := state
+		children = append(children, layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx C) D {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(material.Caption(&p.th.Theme, state.item.Description()).Layout),
+						layout.Rigid(func(gtx C) D {
+							if state.item.Error == "" {
+								return D{}
+							}
+							l := material.Caption(&p.th.Theme, state.item.Error)
+							l.Color = offlineColour
+							return l.Layout(gtx)
+						}),
+					)
+				}),
+				layout.Rigid(func(gtx C) D {
+					if state.item.Kind != mastodon2.OutboxPost {
+						return D{}
+					}
+					ic, _ := widget.NewIcon(icons.ContentCreate)
+					return newIconButton(p.th, &state.editButton, ic, p.th.IconActiveColour).Layout(gtx)
+				}),
+				layout.Rigid(func(gtx C) D {
+					ic, _ := widget.NewIcon(icons.ActionDelete)
+					return newIconButton(p.th, &state.discardButton, ic, p.th.IconActiveColour).Layout(gtx)
+				}),
+			)
+		}))
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// editOutboxItem moves a queued post back into the compose form, so it can be changed and posted again.
+func (p *ComposeColumn) editOutboxItem(item mastodon2.OutboxItem) {
+	p.clearToot()
+	p.postTootDetails.SetText(item.Toot.Status)
+	p.spoilerText.SetText(item.Toot.SpoilerText)
+	p.language.SetText(item.Toot.Language)
+	p.replyStatusID = item.Toot.InReplyToID
+	if item.Toot.Visibility != "" {
+		p.visibility.Value = item.Toot.Visibility
+	}
+
+	if poll := item.Toot.Poll; poll != nil {
+		p.poll.enabled.Value = true
+		p.poll.multiple.Value = poll.Multiple
+		p.poll.hideTotals.Value = poll.HideTotals
+		p.poll.options = nil
+		for _, o := range poll.Options {
+			ed := &widget.Editor{SingleLine: true}
+			ed.SetText(o)
+			p.poll.options = append(p.poll.options, ed)
+		}
+		for _, d := range pollDurations {
+			if int64(d.duration.Seconds()) == poll.ExpiresInSeconds {
+				p.poll.duration.Value = d.label
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package ui
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/mattn/go-mastodon"
+	"image"
+	"strconv"
+	"time"
+)
+
+const (
+	// how often to refresh the results of an open poll while it's being displayed.
+	PollRefreshInterval = 30 * time.Second
+)
+
+// pollState is the interactive state for a poll attached to a status.
+type pollState struct {
+	// single choice polls use choice, multiple choice use choices.
+	choice  widget.Enum
+	choices []widget.Bool
+
+	voteButton widget.Clickable
+
+	// polls are only refreshed while they're being displayed.
+	lastDisplayed time.Time
+	lastRefreshed time.Time
+}
+
+// statusPoll returns the poll attached to the status (or the status it boosts). Nil if no poll.
+func statusPoll(status mastodon.Status) *mastodon.Poll {
+	if status.Reblog != nil {
+		return status.Reblog.Poll
+	}
+	return status.Poll
+}
+
+// pollClosed returns true if the poll has ended.
+func pollClosed(poll *mastodon.Poll) bool {
+	return poll.Expired || (!poll.ExpiresAt.IsZero() && time.Now().After(poll.ExpiresAt))
+}
+
+// selectedChoices returns the indexes of the options chosen.
+func (p *pollState) selectedChoices(poll *mastodon.Poll) []int {
+	var selected []int
+	if poll.Multiple {
+		for i := range p.choices {
+			if p.choices[i].Value {
+				selected = append(selected, i)
+			}
+		}
+		return selected
+	}
+
+	if i, err := strconv.Atoi(p.choice.Value); err == nil {
+		selected = append(selected, i)
+	}
+	return selected
+}
+
+// needsRefresh returns true if the poll is still open, is being displayed and hasn't been
+// refreshed for a while.
+func (p *pollState) needsRefresh(poll *mastodon.Poll) bool {
+	return !pollClosed(poll) &&
+		time.Since(p.lastDisplayed) < time.Second &&
+		time.Since(p.lastRefreshed) > PollRefreshInterval
+}
+
+// Layout displays options to vote for, or the results if we've already voted or the poll has ended.
+func (p *pollState) Layout(gtx C, th *ShipdonTheme, poll *mastodon.Poll) D {
+	p.lastDisplayed = time.Now()
+
+	if poll.Voted || pollClosed(poll) {
+		return layoutPollResults(gtx, th, poll)
+	}
+
+	if len(p.choices) != len(poll.Options) {
+		p.choices = make([]widget.Bool, len(poll.Options))
+	}
+
+	var children []layout.FlexChild
+	for i, o := range poll.Options {
+		if poll.Multiple {
+			children = append(children, layout.Rigid(material.CheckBox(&th.Theme, &p.choices[i], o.Title).Layout))
+		} else {
+			children = append(children, layout.Rigid(material.RadioButton(&th.Theme, &p.choice, strconv.Itoa(i), o.Title).Layout))
+		}
+	}
+
+	children = append(children,
+		layout.Rigid(func(gtx C) D {
+			if len(p.selectedChoices(poll)) == 0 {
+				gtx = gtx.Disabled()
+			}
+			return material.Button(&th.Theme, &p.voteButton, "Vote").Layout(gtx)
+		}),
+		layout.Rigid(material.Caption(&th.Theme, pollSummary(poll)).Layout),
+	)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// layoutPollResults displays each option with a bar showing the percentage of votes it got.
+func layoutPollResults(gtx C, th *ShipdonTheme, poll *mastodon.Poll) D {
+
+	// for multiple choice polls, percentages are out of the number of people who voted.
+	total := poll.VotesCount
+	if poll.Multiple && poll.VotersCount > 0 {
+		total = poll.VotersCount
+	}
+
+	barColour := th.ContrastBg
+	barColour.A = 100
+
+	var children []layout.FlexChild
+	for i, o := range poll.Options {
+		fraction := 0.0
+		if total > 0 {
+			fraction = float64(o.VotesCount) / float64(total)
+		}
+
+		label := fmt.Sprintf("%s  %.0f%%", o.Title, fraction*100)
+		for _, v := range poll.OwnVotes {
+			if v == i {
+				label = "✓ " + label
+			}
+		}
+
+		children = append(children,
+			layout.Rigid(func(gtx C) D {
+				gtx.Constraints.Min.X = gtx.Constraints.Max.X
+				return layout.Stack{}.Layout(gtx,
+					layout.Expanded(func(gtx C) D {
+						width := int(float64(gtx.Constraints.Min.X) * fraction)
+						bar := clip.UniformRRect(image.Rectangle{Max: image.Pt(width, gtx.Constraints.Min.Y)}, gtx.Dp(4))
+						paint.FillShape(gtx.Ops, barColour, bar.Op(gtx.Ops))
+						return D{Size: gtx.Constraints.Min}
+					}),
+					layout.Stacked(func(gtx C) D {
+						return layout.UniformInset(unit.Dp(4)).Layout(gtx, material.Body1(&th.Theme, label).Layout)
+					}),
+				)
+			}),
+			layout.Rigid(layout.Spacer{Height: unit.Dp(2)}.Layout),
+		)
+	}
+
+	children = append(children, layout.Rigid(material.Caption(&th.Theme, pollSummary(poll)).Layout))
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// pollSummary is the number of votes and when the poll closes.
+func pollSummary(poll *mastodon.Poll) string {
+	if pollClosed(poll) {
+		return fmt.Sprintf("%d votes : closed", poll.VotesCount)
+	}
+	if poll.ExpiresAt.IsZero() {
+		return fmt.Sprintf("%d votes", poll.VotesCount)
+	}
+	return fmt.Sprintf("%d votes : %s left", poll.VotesCount, timeLeft(poll.ExpiresAt))
+}
+
+// timeLeft return days, hours or minutes until expiresAt
+func timeLeft(expiresAt time.Time) string {
+	if time.Until(expiresAt).Hours() < 1 {
+		return fmt.Sprintf("%dm", int(time.Until(expiresAt).Minutes()))
+	} else if time.Until(expiresAt).Hours() > 24 {
+		return fmt.Sprintf("%dd", int(time.Until(expiresAt).Hours()/24))
+	} else {
+		return fmt.Sprintf("%dh", int(time.Until(expiresAt).Hours()))
+	}
+}
@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"fmt"
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	"golang.org/x/exp/shiny/materialdesign/icons"
+	"strings"
+	"time"
+)
+
+// pollDurations are the durations offered when creating a poll.
+var pollDurations = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"3d", 3 * 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// pollBuilder is the form for adding a poll to the toot being composed.
+type pollBuilder struct {
+	enabled widget.Bool
+
+	options         []*widget.Editor
+	addOptionButton widget.Clickable
+
+	duration   widget.Enum
+	multiple   widget.Bool
+	hideTotals widget.Bool
+}
+
+func newPollBuilder() *pollBuilder {
+	p := &pollBuilder{}
+	p.reset()
+	return p
+}
+
+// reset clears the poll back to 2 empty options.
+func (p *pollBuilder) reset() {
+	p.enabled.Value = false
+	p.options = []*widget.Editor{{SingleLine: true}, {SingleLine: true}}
+	p.duration.Value = "1d"
+	p.multiple.Value = false
+	p.hideTotals.Value = false
+}
+
+// addOption adds another option, as long as the instance allows it.
+func (p *pollBuilder) addOption(limits mastodon2.PollLimits) {
+	if len(p.options) < limits.MaxOptions {
+		p.options = append(p.options, &widget.Editor{SingleLine: true})
+	}
+}
+
+// tootPoll generates the poll to send with the toot. Nil if no poll has been added.
+func (p *pollBuilder) tootPoll() *mastodon.TootPoll {
+	if !p.enabled.Value {
+		return nil
+	}
+
+	poll := &mastodon.TootPoll{
+		Multiple:   p.multiple.Value,
+		HideTotals: p.hideTotals.Value,
+	}
+	for _, o := range p.options {
+		if option := strings.TrimSpace(o.Text()); option != "" {
+			poll.Options = append(poll.Options, option)
+		}
+	}
+	for _, d := range pollDurations {
+		if d.label == p.duration.Value {
+			poll.ExpiresInSeconds = int64(d.duration.Seconds())
+		}
+	}
+	return poll
+}
+
+// Layout displays the "add poll" checkbox, and if checked the rest of the poll.
+func (p *pollBuilder) Layout(gtx C, th *ShipdonTheme, limits mastodon2.PollLimits) D {
+	children := []layout.FlexChild{
+		layout.Rigid(material.CheckBox(&th.Theme, &p.enabled, "Add poll").Layout),
+	}
+
+	if p.enabled.Value {
+		for i, o := range p.options {
+			ed := material.Editor(&th.Theme, o, fmt.Sprintf("Option %d (max %d characters)", i+1, limits.MaxCharactersPerOption))
+			children = append(children, layout.Rigid(ed.Layout))
+		}
+
+		if len(p.options) < limits.MaxOptions {
+			children = append(children, layout.Rigid(func(gtx C) D {
+				ic, _ := widget.NewIcon(icons.ContentAdd)
+				return newIconButton(th, &p.addOptionButton, ic, th.IconActiveColour).Layout(gtx)
+			}))
+		}
+
+		var durations []layout.FlexChild
+		for _, d := range pollDurations {
+			if d.duration < limits.MinExpiration || d.duration > limits.MaxExpiration {
+				continue
+			}
+			durations = append(durations, layout.Rigid(material.RadioButton(&th.Theme, &p.duration, d.label, d.label).Layout))
+		}
+
+		children = append(children,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, durations...)
+			}),
+			layout.Rigid(material.CheckBox(&th.Theme, &p.multiple, "Multiple choice").Layout),
+			layout.Rigid(material.CheckBox(&th.Theme, &p.hideTotals, "Hide totals until poll ends").Layout),
+		)
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"fmt"
+	"gioui.org/app"
+	"gioui.org/font/gofont"
+	"gioui.org/io/system"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/k3a/html2text"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
+	"github.com/mattn/go-mastodon"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+const (
+	// Mastodon limits report comments to 1000 characters.
+	maxReportCommentLength = 1000
+
+	// statuses are shortened to this many characters when choosing which to include in a report.
+	reportStatusPreviewLength = 100
+)
+
+// reportCategories are the categories offered when reporting, in the order they're displayed.
+var reportCategories = []struct {
+	category string
+	label    string
+}{
+	{mastodon2.ReportCategorySpam, "Spam"},
+	{mastodon2.ReportCategoryLegal, "Illegal content"},
+	{mastodon2.ReportCategoryViolation, "Breaks server rules"},
+	{mastodon2.ReportCategoryOther, "Something else"},
+}
+
+// statusPreview is the text of the status, shortened to fit on one line.
+func statusPreview(status mastodon.Status) string {
+	preview := strings.Join(strings.Fields(html2text.HTML2Text(status.Content)), " ")
+	if status.SpoilerText != "" {
+		preview = "CW: " + status.SpoilerText
+	}
+	if len([]rune(preview)) > reportStatusPreviewLength {
+		preview = string([]rune(preview)[:reportStatusPreviewLength]) + "…"
+	}
+	return preview
+}
+
+// openReportWindow reports the account to the moderators. If status isn't nil it's included in the report,
+// and the author's other recent statuses can be added.
+func openReportWindow(backend mastodon2.Backend, account mastodon.Account, status *mastodon.Status) {
+	rules, err := backend.GetInstanceRules()
+	if err != nil {
+		log.Errorf("unable to get instance rules %v", err)
+	}
+	statuses, err := backend.GetAccountStatuses(account.ID)
+	if err != nil {
+		log.Errorf("unable to get statuses to report %v", err)
+	}
+
+	// the reported status always comes first, even if it's too old to be in the recent statuses.
+	if status != nil {
+		statuses = slices.DeleteFunc(statuses, func(s mastodon.Status) bool { return s.ID == status.ID })
+		statuses = append([]mastodon.Status{*status}, statuses...)
+	}
+
+	w := new(app.Window)
+	w.Option(
+		app.Title("Report @"+account.Acct),
+		app.Size(unit.Dp(600), unit.Dp(700)))
+	var ops op.Ops
+
+	th := material.NewTheme()
+	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
+
+	category := widget.Enum{Value: mastodon2.ReportCategorySpam}
+	brokenRules := make([]widget.Bool, len(rules))
+	includeStatus := make([]widget.Bool, len(statuses))
+	if status != nil {
+		includeStatus[0].Value = true
+	}
+	comment := widget.Editor{MaxLen: maxReportCommentLength}
+	var forward widget.Bool
+	remote := mastodon2.AccountDomain(account)
+
+	var submitButton widget.Clickable
+	var cancelButton widget.Clickable
+	var scroll widget.List
+	scroll.Axis = layout.Vertical
+
+	for {
+		switch event := w.Event().(type) {
+		case app.DestroyEvent:
+			return
+		case app.FrameEvent:
+			gtx := app.NewContext(&ops, event)
+
+			if cancelButton.Clicked(gtx) {
+				w.Perform(system.ActionClose)
+			}
+
+			if submitButton.Clicked(gtx) {
+				report := mastodon2.Report{
+					AccountID: account.ID,
+					Category:  category.Value,
+					Comment:   comment.Text(),
+					Forward:   remote != "" && forward.Value,
+				}
+				for i, s := range statuses {
+					if includeStatus[i].Value {
+						report.StatusIDs = append(report.StatusIDs, s.ID)
+					}
+				}
+				for i, r := range rules {
+					if brokenRules[i].Value {
+						report.RuleIDs = append(report.RuleIDs, r.ID)
+					}
+				}
+				// left open on failure, so the report can be tried again.
+				if err := backend.Report(report); err != nil {
+					log.Errorf("error reporting account %+v", err)
+					alert("Report", "Unable to report @"+account.Acct+": "+err.Error())
+				} else {
+					w.Perform(system.ActionClose)
+				}
+			}
+
+			var rows []layout.Widget
+			rows = append(rows, material.H6(th, "Why are you reporting @"+account.Acct+"?").Layout)
+			for _, c := range reportCategories {
+				rows = append(rows, material.RadioButton(th, &category, c.category, c.label).Layout)
+			}
+
+			// rules are only relevant when reporting a rule violation.
+			if category.Value == mastodon2.ReportCategoryViolation {
+				rows = append(rows, material.Body2(th, "Which rules were broken?").Layout)
+				for i, r := range rules {
+					rows = append(rows, material.CheckBox(th, &brokenRules[i], r.Text).Layout)
+				}
+			}
+
+			rows = append(rows, layout.Spacer{Height: unit.Dp(10)}.Layout)
+			rows = append(rows, material.Body2(th, "Statuses to include").Layout)
+			if len(statuses) == 0 {
+				rows = append(rows, material.Caption(th, "No statuses").Layout)
+			}
+			for i, s := range statuses {
+				rows = append(rows, material.CheckBox(th, &includeStatus[i], statusPreview(s)).Layout)
+			}
+
+			rows = append(rows, layout.Spacer{Height: unit.Dp(10)}.Layout)
+			rows = append(rows, material.Editor(th, &comment, fmt.Sprintf("Additional comments (max %d characters)", maxReportCommentLength)).Layout)
+			if remote != "" {
+				rows = append(rows, material.CheckBox(th, &forward, "Forward an anonymised copy to "+remote).Layout)
+			}
+
+			layout.UniformInset(unit.Dp(10)).Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Flexed(1, func(gtx C) D {
+						return material.List(th, &scroll).Layout(gtx, len(rows), func(gtx C, index int) D {
+							return rows[index](gtx)
+						})
+					}),
+					layout.Rigid(layout.Spacer{Height: unit.Dp(10)}.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+							layout.Rigid(material.Button(th, &submitButton, "Submit report").Layout),
+							layout.Rigid(layout.Spacer{Width: unit.Dp(5)}.Layout),
+							layout.Rigid(material.Button(th, &cancelButton, "Cancel").Layout),
+						)
+					}),
+				)
+			})
+
+			event.Frame(gtx.Ops)
+		}
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"gioui.org/widget/material"
 	"gioui.org/x/component"
 	"github.com/kpfaulkner/shipdon/config"
+	mastodon2 "github.com/kpfaulkner/shipdon/mastodon"
 	log "github.com/sirupsen/logrus"
 	"os"
+	"slices"
 )
 
 const (
@@ -21,7 +23,9 @@ const (
 	LightMode = "LightMode"
 )
 
-func openSettingsWindow(cfg *config.Config) {
+// openSettingsWindow displays the settings. lists are each account's lists (keyed by account name) so
+// they can be hidden. Returns true if the user asked to add another account.
+func openSettingsWindow(cfg *config.Config, lists map[string][]*mastodon2.List) bool {
 	w := new(app.Window)
 	w.Option(
 		app.Title("Settings"),
@@ -39,13 +43,25 @@ func openSettingsWindow(cfg *config.Config) {
 
 	th := material.NewTheme()
 	th.Shaper = text.NewShaper(text.WithCollection(gofont.Collection()))
-	var instanceURLEditor component.TextField
 	var saveButton widget.Clickable
+	var addAccountButton widget.Clickable
 
 	radioButtonsGroup := new(widget.Enum)
+	expandContentWarnings := widget.Bool{Value: cfg.ExpandContentWarnings}
+
+	// accounts to remove when saved.
+	removeAccount := make([]widget.Bool, len(cfg.Accounts))
+
+	// lists to hide, per account.
+	hideList := make([][]widget.Bool, len(cfg.Accounts))
+	for i, a := range cfg.Accounts {
+		hideList[i] = make([]widget.Bool, len(lists[a.Name]))
+		for j, l := range lists[a.Name] {
+			hideList[i][j].Value = slices.Contains(a.ListsToNotDisplay, string(l.ID))
+		}
+	}
 
 	// set up existing values.
-	instanceURLEditor.SetText(cfg.InstanceURL)
 	if cfg.DarkMode {
 		radioButtonsGroup.Value = DarkMode
 	} else {
@@ -56,28 +72,62 @@ func openSettingsWindow(cfg *config.Config) {
 		switch event := w.Event().(type) {
 		case app.DestroyEvent:
 			w.Perform(system.ActionClose)
-			return
+			return false
 		case app.FrameEvent:
 			gtx := app.NewContext(&ops, event)
-			if saveButton.Clicked(gtx) {
+			saveClicked := saveButton.Clicked(gtx)
+			addAccountClicked := addAccountButton.Clicked(gtx)
+			if saveClicked || addAccountClicked {
+
+				for i, a := range cfg.Accounts {
+					for j, l := range lists[a.Name] {
+						hidden := slices.Contains(a.ListsToNotDisplay, string(l.ID))
+						if hideList[i][j].Value && !hidden {
+							a.ListsToNotDisplay = append(a.ListsToNotDisplay, string(l.ID))
+						}
+						if !hideList[i][j].Value && hidden {
+							a.ListsToNotDisplay = slices.DeleteFunc(a.ListsToNotDisplay, func(id string) bool { return id == string(l.ID) })
+						}
+					}
+				}
 
-				if txt := instanceURLEditor.Text(); txt != "" {
-					cfg.InstanceURL = txt
+				var names []string
+				for i, a := range cfg.Accounts {
+					if removeAccount[i].Value {
+						names = append(names, a.Name)
+					}
+				}
+				for _, name := range names {
+					cfg.RemoveAccount(name)
 				}
 
 				cfg.DarkMode = radioButtonsGroup.Value == DarkMode
+				cfg.ExpandContentWarnings = expandContentWarnings.Value
 				cfg.Save()
 				w.Perform(system.ActionClose)
-				return
-
+				return addAccountClicked
 			}
+
 			layout.NW.Layout(gtx, func(gtx C) D {
 				gtx.Constraints.Max.X = gtx.Dp(unit.Dp(300))
-				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+
+				children := []layout.FlexChild{
+					layout.Rigid(material.Body1(th, "Accounts").Layout),
+				}
+				for i, a := range cfg.Accounts {
+					children = append(children, layout.Rigid(material.CheckBox(th, &removeAccount[i], "Remove "+a.Name).Layout))
+					for j, l := range lists[a.Name] {
+						i, j, l := i, j, l
+						children = append(children, layout.Rigid(func(gtx C) D {
+							return layout.Inset{Left: unit.Dp(20)}.Layout(gtx, material.CheckBox(th, &hideList[i][j], "Hide list "+l.Title).Layout)
+						}))
+					}
+				}
+
+				children = append(children,
 					layout.Rigid(func(gtx C) D {
-						return instanceURLEditor.Layout(gtx, th, "Instance URL")
+						return material.Button(th, &addAccountButton, "Add Account").Layout(gtx)
 					}),
-
 					layout.Rigid(func(gtx C) D {
 						return layout.Spacer{Height: unit.Dp(10)}.Layout(gtx)
 					}),
@@ -88,10 +138,15 @@ func openSettingsWindow(cfg *config.Config) {
 					layout.Rigid(func(gtx C) D {
 						return layout.Spacer{Height: unit.Dp(10)}.Layout(gtx)
 					}),
+					layout.Rigid(material.CheckBox(th, &expandContentWarnings, "Always expand content warnings").Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Spacer{Height: unit.Dp(10)}.Layout(gtx)
+					}),
 					layout.Rigid(func(gtx C) D {
 						return material.Button(th, &saveButton, "Save").Layout(gtx)
 					}),
 				)
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
 			})
 
 			log.Debugf("RADIO BUTTON %s", radioButtonsGroup.Value)
@@ -49,10 +49,45 @@ type StatusState struct {
 	ReplyButton      widget.Clickable
 	BoostButton      widget.Clickable
 	FavouriteButton  widget.Clickable
+	BookmarkButton   widget.Clickable
 	ViewThreadButton widget.Clickable
 
+	// overflow menu with mute/block actions. Only for other people's statuses.
+	MoreButton        widget.Clickable
+	showActions       bool
+	MuteButton        widget.Clickable
+	BlockButton       widget.Clickable
+	BlockDomainButton widget.Clickable
+	ReportButton      widget.Clickable
+
+	// only for our own statuses.
+	EditButton    widget.Clickable
+	DeleteButton  widget.Clickable
+	RedraftButton widget.Clickable
+
 	Avatar widget.Image
 
+	// content warning. If set, details and media are hidden until expanded.
+	spoilerText    string
+	ShowMoreButton widget.Clickable
+	expanded       bool
+
+	// expand regardless of ShowMoreButton. Set by the column.
+	alwaysExpand bool
+
+	// voting and results if the status has a poll.
+	poll pollState
+
+	// titles of the warn filters the status matches. Collapsed until ShowFilteredButton is clicked.
+	filterWarnings     []string
+	ShowFilteredButton widget.Clickable
+	filterRevealed     bool
+
+	// sensitive media is hidden until ShowMediaButton is clicked.
+	sensitive       bool
+	ShowMediaButton widget.Clickable
+	mediaRevealed   bool
+
 	// first image (if exists) to show.
 	img        *widget.Image
 	imgOrigURL string
@@ -60,8 +95,12 @@ type StatusState struct {
 
 	//Media widget.Clickable
 
+	// status a thread column was opened from, highlighted in the thread.
 	targetStatus bool
 
+	// how deeply nested in a thread this status is.
+	threadDepth int
+
 	// actual status from mastodon... a copy.
 	status mastodon.Status
 
@@ -91,6 +130,8 @@ func (ss *StatusState) syncStatusToUI(status mastodon.Status, gtx C) {
 	detailsSpans := generateDetailsSpanStyles(status, ss.th)
 	ss.DetailStyle = richtext.Text(&ss.Details, ss.th.Shaper, detailsSpans...)
 
+	ss.spoilerText, ss.sensitive = contentWarning(status)
+
 	var secondaryAccount *mastodon.Account
 	if status.Reblog != nil {
 		secondaryAccount = &status.Reblog.Account
@@ -111,7 +152,7 @@ func (ss *StatusState) syncStatusToUI(status mastodon.Status, gtx C) {
 	ss.status = status
 
 	// if favourited then list name of favourite.
-	if status.FavouritesCount > 0 && mastodon2.AccountID == status.Account.ID {
+	if status.FavouritesCount > 0 && ss.backend.AccountID() == status.Account.ID {
 
 		// if we're generating our own content... then just use acct.
 		if status.Content != "" {
@@ -240,6 +281,58 @@ func generateMedia(status mastodon.Status) (*widget.Image, string) {
 	return nil, ""
 }
 
+// contentWarning returns the spoiler text and if the media is sensitive. If a boost, then the
+// details come from the original status.
+func contentWarning(status mastodon.Status) (string, bool) {
+	if status.Reblog != nil {
+		return status.Reblog.SpoilerText, status.Reblog.Sensitive
+	}
+	return status.SpoilerText, status.Sensitive
+}
+
+// threadStatus is the status a thread should be opened for. If a boost, then the original status.
+func threadStatus(status mastodon.Status) mastodon.Status {
+	if status.Reblog != nil {
+		return *status.Reblog
+	}
+	return status
+}
+
+// hasThread returns true if the status is part of a conversation, either as a reply or by having replies.
+func hasThread(status mastodon.Status) bool {
+	s := threadStatus(status)
+	return s.InReplyToID != nil || s.RepliesCount > 0
+}
+
+// isOwnStatus returns true if the status was written by the account we're logged in as.
+func (ss *StatusState) isOwnStatus() bool {
+	return ss.status.Reblog == nil && ss.status.Account.ID == ss.backend.AccountID()
+}
+
+// statusAuthor is the account that wrote the content of the status (the original author for boosts).
+func statusAuthor(status mastodon.Status) mastodon.Account {
+	if status.Reblog != nil {
+		return status.Reblog.Account
+	}
+	return status.Account
+}
+
+// canModerate returns true if the author of the status can be muted or blocked (ie. isn't us).
+func (ss *StatusState) canModerate() bool {
+	return statusAuthor(ss.status).ID != ss.backend.AccountID()
+}
+
+// isFiltered returns true if the status matched a warn filter and hasn't been revealed.
+func (ss *StatusState) isFiltered() bool {
+	return len(ss.filterWarnings) > 0 && !ss.filterRevealed
+}
+
+// showDetails returns true if the details and media should be displayed. False if they're
+// hidden behind a content warning.
+func (ss *StatusState) showDetails() bool {
+	return ss.spoilerText == "" || ss.expanded || ss.alwaysExpand
+}
+
 func loadAvatar(username string, avatarURL string) widget.Image {
 	imgEntry := imageCache.Get(username)
 	if imgEntry.status == Processed {
@@ -374,49 +467,6 @@ func generateDetailsSpanStyles(status mastodon.Status, th *ShipdonTheme) []richt
 	return spans
 }
 
-func generatePollSpanStyles(status mastodon.Status, th *ShipdonTheme) []richtext.SpanStyle {
-
-	var content string
-
-	if status.Content != "" {
-		content = status.Content
-	} else {
-		if status.Reblog != nil {
-			content = status.Reblog.Content
-		}
-	}
-
-	spans := []richtext.SpanStyle{}
-
-	t := html2text.HTML2TextWithOptions(content, html2text.WithLinksInnerText())
-
-	span := richtext.SpanStyle{
-		Content: "Poll: " + t,
-		Color:   th.Fg,
-		Size:    unit.Sp(15),
-		Font:    fonts[0].Font,
-	}
-	spans = append(spans, span)
-
-	span2 := richtext.SpanStyle{
-		Content: "line1",
-		Color:   th.Fg,
-		Size:    unit.Sp(15),
-		Font:    fonts[0].Font,
-	}
-	spans = append(spans, span2)
-
-	span3 := richtext.SpanStyle{
-		Content: "line2",
-		Color:   th.Fg,
-		Size:    unit.Sp(15),
-		Font:    fonts[0].Font,
-	}
-	spans = append(spans, span3)
-
-	return spans
-}
-
 func (ss *StatusState) generateNameSpanStyles(status mastodon.Status) []richtext.SpanStyle {
 
 	var spans []richtext.SpanStyle
@@ -526,6 +576,14 @@ func (i StatusStyle) Layout(gtx C) D {
 
 			// Here is background of status areas (currently white). Need to modify
 			paint.FillShape(gtx.Ops, i.state.th.Bg, rrect.Op(gtx.Ops))
+
+			// highlight the status the thread was opened from.
+			if i.state.targetStatus {
+				border := widget.Border{Color: i.state.th.ContrastBg, CornerRadius: unit.Dp(10), Width: unit.Dp(2)}
+				border.Layout(gtx, func(gtx C) D {
+					return D{Size: gtx.Constraints.Min}
+				})
+			}
 			return D{Size: gtx.Constraints.Min}
 		}),
 
@@ -549,6 +607,9 @@ func (i StatusStyle) Layout(gtx C) D {
 				// other flexed children, this means it gets all of the
 				// space not occupied by rigid children.
 				layout.Flexed(1, func(gtx C) D {
+					if i.state.isFiltered() {
+						return i.layoutFiltered(gtx)
+					}
 					return i.layoutStatus(gtx)
 				}),
 			)
@@ -556,6 +617,22 @@ func (i StatusStyle) Layout(gtx C) D {
 	)
 }
 
+// layoutFiltered displays the titles of the filters the status matched, instead of the status.
+func (i StatusStyle) layoutFiltered(gtx C) D {
+	const spacing = unit.Dp(4)
+	return layout.UniformInset(spacing).Layout(gtx, func(gtx C) D {
+		gtx.Constraints.Min.X = gtx.Constraints.Max.X
+		return layout.Flex{
+			Axis:      layout.Horizontal,
+			Alignment: layout.Middle,
+		}.Layout(gtx,
+			layout.Flexed(1, material.Body1(&i.state.th.Theme, "Filtered: "+strings.Join(i.state.filterWarnings, ", ")).Layout),
+			layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+			layout.Rigid(material.Button(&i.state.th.Theme, &i.state.ShowFilteredButton, "show anyway").Layout),
+		)
+	})
+}
+
 // layoutStatus displays the text editors used to manipulate the task.
 func (i StatusStyle) layoutStatus(gtx C) D {
 	const spacing = unit.Dp(4)
@@ -598,17 +675,58 @@ func (i StatusStyle) layoutStatus(gtx C) D {
 
 			layout.Rigid(layout.Spacer{Height: spacing}.Layout),
 
+			// content warning
+			layout.Rigid(func(gtx C) D {
+				if i.state.spoilerText == "" {
+					return D{}
+				}
+
+				label := "show more"
+				if i.state.showDetails() {
+					label = "show less"
+				}
+				return layout.Flex{
+					Axis:      layout.Horizontal,
+					Alignment: layout.Middle,
+				}.Layout(gtx,
+					layout.Flexed(1, material.Body1(&i.state.th.Theme, i.state.spoilerText).Layout),
+					layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+					layout.Rigid(func(gtx C) D {
+						if i.state.alwaysExpand {
+							return D{}
+						}
+						return material.Button(&i.state.th.Theme, &i.state.ShowMoreButton, label).Layout(gtx)
+					}),
+				)
+			}),
+
 			// DETAILS
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !i.state.showDetails() {
+					return D{}
+				}
 				return i.state.DetailStyle.Layout(gtx)
 			}),
 
 			layout.Rigid(layout.Spacer{Height: spacing}.Layout),
 
+			// poll?
+			layout.Rigid(func(gtx C) D {
+				poll := statusPoll(i.state.status)
+				if poll == nil || !i.state.showDetails() {
+					return D{}
+				}
+				return i.state.poll.Layout(gtx, i.state.th, poll)
+			}),
+
 			// image/media?
 			layout.Rigid(func(gtx C) D {
 
-				if i.state.img != nil {
+				if i.state.img != nil && i.state.showDetails() {
+					if i.state.sensitive && !i.state.mediaRevealed {
+						return material.Button(&i.state.th.Theme, &i.state.ShowMediaButton, "Sensitive media, click to show").Layout(gtx)
+					}
+
 					ib := newImageButton(&i.state.th.Theme, &i.state.imgButton, i.state.img)
 					return ib.Layout(gtx)
 				}
@@ -659,6 +777,27 @@ func (i StatusStyle) layoutStatus(gtx C) D {
 
 						return favButton.Layout(gtx)
 					}),
+					layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+					layout.Rigid(func(gtx C) D {
+						ic, _ := widget.NewIcon(icons.ActionBookmark)
+						bookmarkButton := newIconButton(i.state.th, &i.state.BookmarkButton, ic, i.state.th.IconBackgroundColour)
+						if bookmarked, _ := i.state.status.Bookmarked.(bool); bookmarked {
+							bookmarkButton.iconColour = i.state.th.IconActiveColour
+						} else {
+							bookmarkButton.iconColour = i.state.th.IconInactiveColour
+						}
+						return bookmarkButton.Layout(gtx)
+					}),
+					layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+					layout.Rigid(func(gtx C) D {
+						if !i.state.canModerate() {
+							return D{}
+						}
+						ic, _ := widget.NewIcon(icons.NavigationMoreVert)
+						moreButton := newIconButton(i.state.th, &i.state.MoreButton, ic, i.state.th.IconBackgroundColour)
+						moreButton.iconColour = i.state.th.IconInactiveColour
+						return moreButton.Layout(gtx)
+					}),
 
 					layout.Rigid(layout.Spacer{Width: spacing}.Layout),
 
@@ -671,7 +810,7 @@ func (i StatusStyle) layoutStatus(gtx C) D {
 
 					layout.Rigid(func(gtx C) D {
 						var dim layout.Dimensions
-						if i.state.status.InReplyToID != nil {
+						if hasThread(i.state.status) {
 							ic, _ := widget.NewIcon(icons.ActionList)
 							threadButton := newIconButton(i.state.th, &i.state.ViewThreadButton, ic, i.state.th.IconBackgroundColour)
 							threadButton.iconColour = i.state.th.IconInactiveColour
@@ -696,6 +835,64 @@ func (i StatusStyle) layoutStatus(gtx C) D {
 					}),
 				)
 			}),
+
+			// mute, block etc for the author of someone else's status.
+			layout.Rigid(func(gtx C) D {
+				if !i.state.showActions || !i.state.canModerate() {
+					return D{}
+				}
+
+				author := statusAuthor(i.state.status)
+				return layout.Inset{Top: spacing}.Layout(gtx, func(gtx C) D {
+					children := []layout.FlexChild{
+						layout.Rigid(material.Button(&i.state.th.Theme, &i.state.MuteButton, "Mute @"+author.Acct).Layout),
+						layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+						layout.Rigid(material.Button(&i.state.th.Theme, &i.state.BlockButton, "Block @"+author.Acct).Layout),
+					}
+					if domain := mastodon2.AccountDomain(author); domain != "" {
+						children = append(children,
+							layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+							layout.Rigid(material.Button(&i.state.th.Theme, &i.state.BlockDomainButton, "Block "+domain).Layout),
+						)
+					}
+					children = append(children,
+						layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+						layout.Rigid(material.Button(&i.state.th.Theme, &i.state.ReportButton, "Report").Layout),
+					)
+					return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+				})
+			}),
+
+			// edit, delete etc for our own statuses.
+			layout.Rigid(func(gtx C) D {
+				if !i.state.isOwnStatus() {
+					return D{}
+				}
+
+				return layout.Inset{Top: spacing}.Layout(gtx, func(gtx C) D {
+					return layout.Flex{
+						Axis: layout.Horizontal,
+					}.Layout(gtx,
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.EditorModeEdit)
+							editButton := newIconButton(i.state.th, &i.state.EditButton, ic, i.state.th.IconInactiveColour)
+							return editButton.Layout(gtx)
+						}),
+						layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.ActionAutorenew)
+							redraftButton := newIconButton(i.state.th, &i.state.RedraftButton, ic, i.state.th.IconInactiveColour)
+							return redraftButton.Layout(gtx)
+						}),
+						layout.Rigid(layout.Spacer{Width: spacing}.Layout),
+						layout.Rigid(func(gtx C) D {
+							ic, _ := widget.NewIcon(icons.ActionDelete)
+							deleteButton := newIconButton(i.state.th, &i.state.DeleteButton, ic, i.state.th.IconInactiveColour)
+							return deleteButton.Layout(gtx)
+						}),
+					)
+				})
+			}),
 		)
 	})
 }
@@ -54,7 +54,7 @@ func (sc *StatusStateCache) FlushOldEntries() {
 	fmt.Printf("StatusStateCache had %d entries.\n", len(sc.cache))
 	for k, v := range sc.cache {
 		if time.Since(v.lastUsed) > 10*time.Minute {
-			log.Debugf("Deleting from imagecache %s\n", k)
+			log.Debugf("Deleting from imagecache %d\n", k)
 			delete(sc.cache, k)
 		}
 	}